@@ -0,0 +1,383 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Most files a single multipart upload may carry
+const maxUploadFiles = 10000
+
+type uploadedFile struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	ETag string `json:"etag"`
+}
+
+type uploadError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// partPath returns the path a multipart file part was sent under.
+// mime/multipart strips directories from file names, but a dropped folder
+// relies on them, so the Content-Disposition header is parsed here.
+func partPath(h map[string][]string) string {
+	var disposition string
+	if v := h["Content-Disposition"]; len(v) > 0 {
+		disposition = v[0]
+	}
+	_, params, err := mime.ParseMediaType(disposition)
+	if err != nil {
+		return ""
+	}
+	return strings.ReplaceAll(params["filename"], `\`, "/")
+}
+
+// fileMultiUploadHandler writes every file part of a multipart/form-data
+// body under the directory ?path= (the workspace root if empty), keeping the
+// relative path each part was named with, so a dropped folder keeps its
+// layout. Existing files are left alone unless overwrite=true. Files that
+// can't be written are reported per path; a broken body aborts the upload.
+func fileMultiUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	dir := "/workspace"
+	if p := r.URL.Query().Get("path"); p != "" {
+		var err error
+		if dir, err = resolveWorkspacePath(p); err != nil {
+			pathError(w, r, p, err)
+			return
+		}
+	}
+	overwrite := r.URL.Query().Get("overwrite") == "true"
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "multipart/form-data body required", 400)
+		return
+	}
+	noDeadlines(w)
+
+	files := []uploadedFile{}
+	failed := []uploadError{}
+	for count := 0; ; {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			multiUploadAborted(w, r, err)
+			return
+		}
+		name := partPath(part.Header)
+		if name == "" {
+			// Not a file, e.g. a plain form field
+			part.Close()
+			continue
+		}
+		if count++; count > maxUploadFiles {
+			http.Error(w, fmt.Sprintf("too many files (max %d)", maxUploadFiles), 413)
+			return
+		}
+
+		rel := strings.TrimPrefix(filepath.Join(strings.TrimPrefix(dir, "/workspace"), name), "/")
+		fullPath, err := resolveWorkspacePath(rel)
+		if err == nil && !strings.HasPrefix(fullPath, dir+"/") {
+			err = errInvalidPath
+		}
+		if err != nil {
+			failed = append(failed, uploadError{Path: name, Error: err.Error()})
+			part.Close()
+			continue
+		}
+		if info, err := os.Lstat(fullPath); err == nil && (!overwrite || info.IsDir()) {
+			failed = append(failed, uploadError{Path: rel, Error: "destination already exists"})
+			part.Close()
+			continue
+		}
+
+		info, err := saveUploadedPart(part, fullPath)
+		part.Close()
+		if err != nil {
+			var readErr *partReadError
+			if errors.As(err, &readErr) {
+				multiUploadAborted(w, r, readErr.err)
+				return
+			}
+			logWithRequestID(r, "Failed to save uploaded %s: %v", rel, err)
+			failed = append(failed, uploadError{Path: rel, Error: err.Error()})
+			continue
+		}
+		files = append(files, uploadedFile{Path: rel, Size: info.Size(), ETag: rawETag(info)})
+	}
+
+	logWithRequestID(r, "Uploaded %d files (%d failed)", len(files), len(failed))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"files":  files,
+		"errors": failed,
+	})
+}
+
+// partReadError is a failure reading the request body, as opposed to
+// writing the file.
+type partReadError struct{ err error }
+
+func (e *partReadError) Error() string { return e.err.Error() }
+
+// saveUploadedPart streams a part to a temp file and renames it into place.
+func saveUploadedPart(part io.Reader, fullPath string) (os.FileInfo, error) {
+	tmp, err := tempFileFor(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	buf := make([]byte, 32<<10)
+	for {
+		n, readErr := part.Read(buf)
+		if n > 0 {
+			if _, err := tmp.Write(buf[:n]); err != nil {
+				tmp.Close()
+				return nil, err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			tmp.Close()
+			return nil, &partReadError{readErr}
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	saveMu.Lock()
+	defer saveMu.Unlock()
+	return replaceFile(tmp.Name(), fullPath)
+}
+
+func multiUploadAborted(w http.ResponseWriter, r *http.Request, err error) {
+	logWithRequestID(r, "Multipart upload aborted: %v", err)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "upload too large", 413)
+		return
+	}
+	http.Error(w, "upload interrupted: "+err.Error(), 400)
+}
+
+// archiveWriter adds workspace entries to a tar or zip stream under their
+// archive name.
+type archiveWriter interface {
+	add(name, fullPath string, info os.FileInfo) error
+	Close() error
+}
+
+type tarArchive struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func newTarArchive(w io.Writer) *tarArchive {
+	gz := gzip.NewWriter(w)
+	return &tarArchive{gz: gz, tw: tar.NewWriter(gz)}
+}
+
+func (a *tarArchive) add(name, fullPath string, info os.FileInfo) error {
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		var err error
+		if link, err = os.Readlink(fullPath); err != nil {
+			return err
+		}
+	}
+	hdr, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	}
+	// Uid/gid names mean nothing outside the workspace
+	hdr.Uname, hdr.Gname = "", ""
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+	return copyFileTo(a.tw, fullPath)
+}
+
+func (a *tarArchive) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+type zipArchive struct{ zw *zip.Writer }
+
+func (a *zipArchive) add(name, fullPath string, info os.FileInfo) error {
+	hdr, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+	} else {
+		hdr.Method = zip.Deflate
+	}
+	fw, err := a.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		// Zip stores a symlink's target as its content
+		link, err := os.Readlink(fullPath)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(fw, link)
+		return err
+	case info.Mode().IsRegular():
+		return copyFileTo(fw, fullPath)
+	}
+	return nil
+}
+
+func (a *zipArchive) Close() error { return a.zw.Close() }
+
+func copyFileTo(w io.Writer, fullPath string) error {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// addArchiveDir adds the contents of dir, skipping .git and ignored files
+// the same way the file tree does. Symlinks are stored, never followed, so
+// an archive can't pull in anything outside the workspace.
+func addArchiveDir(a archiveWriter, dir, name string, opts listOptions, ignores *ignoreChecker) error {
+	entries, err := readListEntries(dir, opts, ignores)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		fullPath := filepath.Join(dir, entry.Name())
+		entryName := path.Join(name, entry.Name())
+		info, err := os.Lstat(fullPath)
+		if err != nil {
+			// Deleted while we were walking
+			continue
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+			// Sockets, pipes and devices
+			continue
+		}
+		if err := a.add(entryName, fullPath, info); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := addArchiveDir(a, fullPath, entryName, opts, ignores); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fileArchiveHandler streams the directory ?path= (the whole workspace if
+// empty) as a tar.gz or, with format=zip, a zip archive. Gitignored files
+// are left out unless showIgnored=true.
+func fileArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	dir, name := "/workspace", "workspace"
+	if p := r.URL.Query().Get("path"); p != "" {
+		var err error
+		if dir, err = resolveWorkspacePath(p); err != nil {
+			pathError(w, r, p, err)
+			return
+		}
+		// Named as requested, not after a symlink's target
+		name = path.Base(strings.Trim(p, "/"))
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		fileOpError(w, r, "archive", r.URL.Query().Get("path"), err)
+		return
+	}
+	if !info.IsDir() {
+		http.Error(w, "path is not a directory", 400)
+		return
+	}
+
+	var a archiveWriter
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "tar.gz", "tgz":
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".tar.gz"))
+		a = newTarArchive(w)
+	case "zip":
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".zip"))
+		a = &zipArchive{zw: zip.NewWriter(w)}
+	default:
+		http.Error(w, "unknown format "+format, 400)
+		return
+	}
+	noDeadlines(w)
+
+	opts := listOptions{showIgnored: r.URL.Query().Get("showIgnored") == "true"}
+	ignores := newIgnoreChecker()
+	defer ignores.close()
+	err = a.add(name, dir, info)
+	if err == nil {
+		err = addArchiveDir(a, dir, name, opts, ignores)
+	}
+	if err == nil {
+		err = a.Close()
+	}
+	if err != nil {
+		// The status went out with the first bytes; dropping the connection
+		// is the only way left to tell the client the archive is incomplete
+		logWithRequestID(r, "Archive of %s failed: %v", name, err)
+		panic(http.ErrAbortHandler)
+	}
+	logWithRequestID(r, "Served archive of %s", name)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileArchive(t *testing.T) {
+	if _, err := os.Stat("/workspace/.git"); err != nil {
+		t.Skip("/workspace is not a git repository")
+	}
+	setTestReady(t)
+	saved := cfg.FileDenylist
+	defer func() { cfg.FileDenylist = saved }()
+	cfg.FileDenylist = parseDenylist(defaultFileDenylist)
+
+	dir := testWorkspaceDir(t)
+	rel := strings.TrimPrefix(dir, "/workspace/")
+	name := path.Base(rel)
+	files := map[string]string{
+		".gitignore": "*.log\n",
+		"a.txt":      "a",
+		"sub/b.txt":  "b",
+		"debug.log":  "log",
+		".env":       "SECRET=1",
+	}
+	for f, content := range files {
+		mustMkdir(t, filepath.Dir(filepath.Join(dir, f)))
+		if err := os.WriteFile(filepath.Join(dir, f), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustSymlink(t, "a.txt", filepath.Join(dir, "link"))
+	// Stored as a link, never followed out of the workspace
+	mustSymlink(t, "/etc/passwd", filepath.Join(dir, "escape"))
+
+	want := map[string]string{
+		name + "/":           "",
+		name + "/.gitignore": "*.log\n",
+		name + "/a.txt":      "a",
+		name + "/sub/":       "",
+		name + "/sub/b.txt":  "b",
+		name + "/link":       "-> a.txt",
+		name + "/escape":     "-> /etc/passwd",
+	}
+	withIgnored := map[string]string{name + "/debug.log": "log"}
+	for k, v := range want {
+		withIgnored[k] = v
+	}
+
+	tests := []struct {
+		query string
+		want  map[string]string
+	}{
+		{"", want},
+		{"&format=zip", want},
+		{"&showIgnored=true", withIgnored},
+		{"&format=zip&showIgnored=true", withIgnored},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		fileArchiveHandler(rec, httptest.NewRequest(http.MethodGet, "/files/archive?path="+rel+tt.query, nil))
+		if rec.Code != 200 {
+			t.Fatalf("%q: status %d: %s", tt.query, rec.Code, rec.Body)
+		}
+		var got map[string]string
+		if strings.Contains(tt.query, "zip") {
+			got = readZipEntries(t, rec.Body.Bytes())
+		} else {
+			got = readTarEntries(t, rec.Body.Bytes())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: archive holds %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFileArchiveErrors(t *testing.T) {
+	setTestReady(t)
+	dir := testWorkspaceDir(t)
+	rel := strings.TrimPrefix(dir, "/workspace/")
+	if err := os.WriteFile(filepath.Join(dir, "f.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"path=" + rel + "&format=rar", 400},
+		{"path=" + rel + "/f.txt", 400},
+		{"path=" + rel + "/missing", 404},
+		{"path=../etc", 400},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		fileArchiveHandler(rec, httptest.NewRequest(http.MethodGet, "/files/archive?"+tt.query, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.query, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestFileMultiUpload(t *testing.T) {
+	setTestReady(t)
+	dir := testWorkspaceDir(t)
+	rel := strings.TrimPrefix(dir, "/workspace/")
+	if err := os.WriteFile(filepath.Join(dir, "exists.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	parts := []struct{ name, content string }{
+		{"folder/a.txt", "a"},
+		{`folder\sub\b.txt`, "b"},
+		{"exists.txt", "new"},
+		{"../escape.txt", "x"},
+	}
+	for _, p := range parts {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="file"; filename="`+strings.ReplaceAll(p.name, `\`, `\\`)+`"`)
+		fw, err := mw.CreatePart(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, p.content)
+	}
+	mw.WriteField("note", "not a file")
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/files/upload-multi?path="+rel, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	fileMultiUploadHandler(rec, req)
+	if rec.Code != 200 {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Files  []uploadedFile `json:"files"`
+		Errors []uploadError  `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	var uploaded, failed []string
+	for _, f := range resp.Files {
+		uploaded = append(uploaded, strings.TrimPrefix(f.Path, rel+"/"))
+	}
+	for _, e := range resp.Errors {
+		failed = append(failed, strings.TrimPrefix(e.Path, rel+"/"))
+	}
+	if want := []string{"folder/a.txt", "folder/sub/b.txt"}; !reflect.DeepEqual(uploaded, want) {
+		t.Errorf("uploaded %v, want %v", uploaded, want)
+	}
+	if want := []string{"exists.txt", "../escape.txt"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("failed %v, want %v", failed, want)
+	}
+	assertFile(t, filepath.Join(dir, "folder/sub/b.txt"), "b", 0644)
+	assertFile(t, filepath.Join(dir, "exists.txt"), "old", 0644)
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape.txt")); !os.IsNotExist(err) {
+		t.Error("a part escaped the target directory")
+	}
+}
+
+// readTarEntries maps each entry of a tar.gz to its content, or to
+// "-> target" for symlinks.
+func readTarEntries(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	entries := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag == tar.TypeSymlink {
+			entries[hdr.Name] = "-> " + hdr.Linkname
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[hdr.Name] = string(content)
+	}
+}
+
+func readZipEntries(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			entries[f.Name] = "-> " + string(content)
+			continue
+		}
+		entries[f.Name] = string(content)
+	}
+	return entries
+}
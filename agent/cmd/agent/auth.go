@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Audience the auth service puts in workspace tokens
+	workspaceAudience = "workspace"
+	// Signing keys are refetched this often, and at most this often when a
+	// token names a key we don't know
+	jwksMaxAge          = time.Hour
+	jwksRefreshInterval = 30 * time.Second
+)
+
+// Routes reachable without a token. Atlas probes /health before the first
+// user request.
+var publicRoutes = map[string]bool{
+	"/health": true,
+}
+
+var jwks = struct {
+	sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}{}
+
+// authMiddleware requires a workspace token issued for this workspace on
+// every request, so the agent doesn't depend on the proxy in front of it
+// being the only way in.
+func authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.AuthDisabled || publicRoutes[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if cfg.JWKSURL == "" {
+			logWithRequestID(r, "Rejecting request: AGENT_JWKS_URL not configured")
+			http.Error(w, "authentication not configured", http.StatusServiceUnavailable)
+			return
+		}
+		token := tokenFromRequest(r)
+		if token == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if _, err := verifyWorkspaceToken(token); err != nil {
+			logWithRequestID(r, "Rejected workspace token: %v", err)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// tokenFromRequest takes the token the proxy forwards, else one the client
+// sent itself. Browsers can't set headers on WebSocket connections, so those
+// may pass it as ?access_token=. Other requests can't, since URLs end up in
+// logs and browser history.
+func tokenFromRequest(r *http.Request) string {
+	if token := r.Header.Get("X-Workspace-Token"); token != "" {
+		return token
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if websocket.IsWebSocketUpgrade(r) {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
+
+// verifyWorkspaceToken checks the token's signature against the auth
+// service's keys and that it was issued for this workspace. It returns the
+// user the token was issued to.
+func verifyWorkspaceToken(token string) (string, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return signingKey(kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithAudience(workspaceAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return "", err
+	}
+	if ws, _ := claims["ws"].(string); ws == "" || ws != cfg.AtlasID {
+		return "", errors.New("token is for another workspace")
+	}
+	sub, _ := claims.GetSubject()
+	return sub, nil
+}
+
+// signingKey returns the public key with the given ID, refetching the key
+// set when it is stale or doesn't have it, e.g. after a key rotation.
+func signingKey(kid string) (*rsa.PublicKey, error) {
+	jwks.Lock()
+	defer jwks.Unlock()
+
+	key, ok := jwks.keys[kid]
+	age := time.Since(jwks.fetchedAt)
+	if ok && age < jwksMaxAge {
+		return key, nil
+	}
+	if age >= jwksRefreshInterval {
+		keys, err := fetchJWKS(cfg.JWKSURL)
+		if err == nil {
+			jwks.keys, jwks.fetchedAt = keys, time.Now()
+			key, ok = keys[kid]
+		} else if !ok {
+			return nil, fmt.Errorf("fetch signing keys: %v", err)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func fetchJWKS(url string) (map[string]*rsa.PublicKey, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode key set: %v", err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA signing keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenFromRequest(t *testing.T) {
+	upgrade := http.Header{"Connection": {"Upgrade"}, "Upgrade": {"websocket"}}
+	tests := []struct {
+		name   string
+		url    string
+		header http.Header
+		want   string
+	}{
+		{"proxy header", "/files", http.Header{"X-Workspace-Token": {"a"}, "Authorization": {"Bearer b"}}, "a"},
+		{"bearer", "/files", http.Header{"Authorization": {"Bearer b"}}, "b"},
+		{"bearer over query", "/terminal?access_token=q", http.Header{"Authorization": {"Bearer b"}, "Connection": {"Upgrade"}, "Upgrade": {"websocket"}}, "b"},
+		{"query on WebSocket upgrade", "/terminal?access_token=q", upgrade, "q"},
+		{"query on plain request", "/files?access_token=q", nil, ""},
+		{"other scheme", "/files", http.Header{"Authorization": {"Basic x"}}, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		for k, v := range tt.header {
+			r.Header[k] = v
+		}
+		if got := tokenFromRequest(r); got != tt.want {
+			t.Errorf("%s: token = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
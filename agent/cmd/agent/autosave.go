@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auto-save policies, chosen per project and passed in AUTOSAVE_POLICY.
+const (
+	// Never save automatically
+	autosaveOff = "off"
+	// Commit working tree snapshots to the codenest/autosave branch without
+	// touching HEAD, the index or the checked-out branch
+	autosaveShadow = "shadow"
+	// Record stash entries (tracked files only) like `git stash` without
+	// resetting the working tree
+	autosaveSnapshot = "snapshot"
+	// Commit WIP to the checked-out branch and squash them at session end
+	autosaveWIP = "wip"
+)
+
+const (
+	autosaveRef         = "refs/heads/codenest/autosave"
+	autosaveSnapshotRef = "refs/heads/codenest/snapshot"
+	autosaveWIPMessage  = "WIP: auto-save"
+	autosaveFinalMsg    = "Session end sync"
+	defaultAutosaveFreq = 5 * time.Minute
+)
+
+var autosavePolicies = map[string]bool{
+	autosaveOff:      true,
+	autosaveShadow:   true,
+	autosaveSnapshot: true,
+	autosaveWIP:      true,
+}
+
+// parseAutosavePolicy normalises the configured policy, falling back to
+// shadow for unknown values so a typo never commits to the user's branch.
+func parseAutosavePolicy(v string) string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if v == "" {
+		return autosaveShadow
+	}
+	if !autosavePolicies[v] {
+		log.Printf("Unknown AUTOSAVE_POLICY %q, using %s", v, autosaveShadow)
+		return autosaveShadow
+	}
+	return v
+}
+
+func parseAutosaveInterval(v string) time.Duration {
+	if v == "" {
+		return defaultAutosaveFreq
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 10*time.Second {
+		log.Printf("Invalid AUTOSAVE_INTERVAL %q, using %s", v, defaultAutosaveFreq)
+		return defaultAutosaveFreq
+	}
+	return d
+}
+
+// wipState tracks the run of WIP commits made this session so they can be
+// squashed at shutdown.
+var wipState struct {
+	sync.Mutex
+	base string // HEAD before the first WIP commit of the current run
+	last string // the most recent WIP commit
+}
+
+func autosaveLoop() {
+	if cfg.AutosavePolicy == autosaveOff {
+		log.Println("Auto-save disabled")
+		return
+	}
+	log.Printf("Auto-save policy %s every %s", cfg.AutosavePolicy, cfg.AutosaveInterval)
+	for {
+		time.Sleep(cfg.AutosaveInterval)
+		if !isReady() {
+			continue
+		}
+		if err := autosave(false); err != nil {
+			log.Printf("Auto-save failed: %v", err)
+		}
+	}
+}
+
+// autosave saves the working tree according to the configured policy.
+// final is set for the last save before shutdown.
+func autosave(final bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	gitMu.Lock()
+	defer gitMu.Unlock()
+
+	switch cfg.AutosavePolicy {
+	case autosaveShadow:
+		return shadowSave(ctx)
+	case autosaveSnapshot:
+		return snapshotSave(ctx)
+	case autosaveWIP:
+		if err := wipSave(ctx); err != nil {
+			return err
+		}
+		if final {
+			return wipSquash(ctx)
+		}
+	}
+	return nil
+}
+
+// gitOutput runs git and returns trimmed stdout, folding stderr into the
+// error. Callers hold gitMu.
+func gitOutput(ctx context.Context, env []string, args ...string) (string, error) {
+	stdout, stderr, err := runGitEnv(ctx, env, args...)
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr))
+	}
+	return strings.TrimSpace(stdout), nil
+}
+
+// shadowSave writes the whole working tree, untracked files included, as a
+// commit on codenest/autosave. It stages into a throwaway index so the
+// user's staging area is left exactly as it was.
+func shadowSave(ctx context.Context) error {
+	head, err := gitOutput(ctx, nil, "rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		// Nothing to hang a snapshot off in an empty repository
+		return nil
+	}
+
+	indexFile := "/workspace/.git/codenest-autosave-index"
+	defer os.Remove(indexFile)
+	// Start from the real index so unchanged files aren't rehashed
+	if err := copyIndex("/workspace/.git/index", indexFile); err != nil {
+		return err
+	}
+	env := []string{"GIT_INDEX_FILE=" + indexFile}
+	if _, err := gitOutput(ctx, env, "add", "-A"); err != nil {
+		return err
+	}
+	tree, err := gitOutput(ctx, env, "write-tree")
+	if err != nil {
+		return err
+	}
+
+	var parents []string
+	prev, _ := gitOutput(ctx, nil, "rev-parse", "--verify", "-q", autosaveRef)
+	if prev != "" {
+		parents = append(parents, prev)
+	}
+	// Link the checked-out commit in whenever it moved since the last save
+	// so the shadow branch can be merged back cleanly
+	if prev == "" || !isAncestor(ctx, head, prev) {
+		parents = append(parents, head)
+	} else if prevTree, _ := gitOutput(ctx, nil, "rev-parse", prev+"^{tree}"); prevTree == tree {
+		return nil
+	}
+
+	args := []string{"commit-tree", tree, "-m", "Auto-save " + time.Now().UTC().Format(time.RFC3339)}
+	for _, p := range parents {
+		args = append(args, "-p", p)
+	}
+	commit, err := gitOutput(ctx, nil, args...)
+	if err != nil {
+		return err
+	}
+	if _, err := gitOutput(ctx, nil, "update-ref", "-m", "codenest auto-save", autosaveRef, commit); err != nil {
+		return err
+	}
+	log.Printf("Auto-saved working tree to %s (%s)", autosaveRef, commit[:min(12, len(commit))])
+	return nil
+}
+
+func copyIndex(src, dst string) error {
+	in, err := os.Open(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func isAncestor(ctx context.Context, ancestor, descendant string) bool {
+	_, _, err := runGit(ctx, "merge-base", "--is-ancestor", ancestor, descendant)
+	return err == nil
+}
+
+// snapshotSave records tracked changes as a stash entry. `git stash create`
+// builds the entry without touching the working tree; it prints nothing
+// when there is nothing to save.
+func snapshotSave(ctx context.Context) error {
+	if _, err := gitOutput(ctx, nil, "rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		return nil
+	}
+	msg := "codenest auto-save " + time.Now().UTC().Format(time.RFC3339)
+	sha, err := gitOutput(ctx, nil, "stash", "create", msg)
+	if err != nil || sha == "" {
+		return err
+	}
+	if _, err := gitOutput(ctx, nil, "stash", "store", "-m", msg, sha); err != nil {
+		return err
+	}
+	log.Printf("Auto-saved snapshot %s", sha[:min(12, len(sha))])
+	return nil
+}
+
+// wipSave commits all changes to the checked-out branch as a WIP commit.
+func wipSave(ctx context.Context) error {
+	status, err := gitOutput(ctx, nil, "status", "--porcelain")
+	if err != nil || status == "" {
+		return err
+	}
+	head, _ := gitOutput(ctx, nil, "rev-parse", "--verify", "-q", "HEAD")
+	if _, err := gitOutput(ctx, nil, "add", "-A"); err != nil {
+		return err
+	}
+	if _, err := gitOutput(ctx, nil, "commit", "--no-verify", "-m", autosaveWIPMessage); err != nil {
+		return err
+	}
+	commit, err := gitOutput(ctx, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+
+	wipState.Lock()
+	defer wipState.Unlock()
+	// A new run starts whenever someone committed or switched branches
+	// since our last WIP commit
+	if wipState.last == "" || wipState.last != head {
+		wipState.base = head
+	}
+	wipState.last = commit
+	log.Printf("Auto-saved WIP commit %s", commit[:min(12, len(commit))])
+	return nil
+}
+
+// wipSquash folds the trailing run of WIP commits into one. It only acts
+// when HEAD is still our last WIP commit; anything else means the user has
+// built on top of it and rewriting would lose their work. WIP commits that
+// already reached a remote are kept, and only the ones after them are
+// squashed, so the branch never diverges from what was pushed.
+func wipSquash(ctx context.Context) error {
+	wipState.Lock()
+	defer wipState.Unlock()
+	if wipState.last == "" || wipState.base == "" {
+		return nil
+	}
+	head, err := gitOutput(ctx, nil, "rev-parse", "HEAD")
+	if err != nil || head != wipState.last {
+		return err
+	}
+	// Newest first; the run is linear, so pushed commits all come before these
+	unpushed, err := gitOutput(ctx, nil, "rev-list", wipState.base+"..HEAD", "--not", "--remotes")
+	if err != nil {
+		return err
+	}
+	if unpushed == "" {
+		log.Println("WIP auto-save commits are already pushed, not squashing")
+		wipState.base, wipState.last = "", ""
+		return nil
+	}
+	commits := strings.Fields(unpushed)
+	if _, err := gitOutput(ctx, nil, "reset", "--soft", commits[len(commits)-1]+"^"); err != nil {
+		return err
+	}
+	if _, err := gitOutput(ctx, nil, "commit", "--no-verify", "-m", autosaveFinalMsg); err != nil {
+		return err
+	}
+	wipState.base, wipState.last = "", ""
+	log.Println("Squashed WIP auto-save commits")
+	return nil
+}
+
+// pushAutosave publishes the shadow branch or latest snapshot so the saved
+// work outlives the workspace. Both refs belong to the agent, so they are
+// force-pushed.
+func pushAutosave() {
+	var refspec string
+	switch cfg.AutosavePolicy {
+	case autosaveShadow:
+		refspec = "+" + autosaveRef + ":" + autosaveRef
+	case autosaveSnapshot:
+		refspec = "+refs/stash:" + autosaveSnapshotRef
+	default:
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitRemoteTimeout)
+	defer cancel()
+	gitMu.Lock()
+	defer gitMu.Unlock()
+	src := strings.TrimPrefix(strings.SplitN(refspec, ":", 2)[0], "+")
+	if _, _, err := runGit(ctx, "rev-parse", "--verify", "-q", src); err != nil {
+		return
+	}
+	if _, stderr, err := runGit(ctx, "push", "origin", refspec); err != nil {
+		log.Printf("auto-save push failed: %v, out: %s", err, stderr)
+		return
+	}
+	log.Printf("pushed auto-save ref %s", src)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Longest the initial clone, or the fetch and fast-forward of a reused
+// workspace, may take before it is abandoned
+const cloneTimeout = 30 * time.Minute
+
+// trackRemoteBranch adds branch to origin's fetch refspecs unless they
+// already cover every branch, so later fetches and upstream tracking work
+// in single-branch clones.
+func trackRemoteBranch(ctx context.Context, branch string) {
+	refspecs, _, _ := runGit(ctx, "config", "--get-all", "remote.origin.fetch")
+	if strings.Contains(refspecs, "refs/heads/*") {
+		return
+	}
+	runGit(ctx, "remote", "set-branches", "--add", "origin", branch)
+}
+
+// parseCloneDepth reads GIT_CLONE_DEPTH; 0 means full history.
+func parseCloneDepth(v string) int {
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("Invalid GIT_CLONE_DEPTH %q, cloning full history", v)
+		return 0
+	}
+	return n
+}
+
+// parseSparsePaths reads the comma separated GIT_SPARSE_PATHS, dropping
+// anything that could point outside the repository.
+func parseSparsePaths(v string) []string {
+	var paths []string
+	for _, p := range strings.Split(v, ",") {
+		p = strings.Trim(strings.TrimSpace(p), "/")
+		if p == "" {
+			continue
+		}
+		if strings.HasPrefix(p, "-") || !validGitPath(p) {
+			log.Printf("Ignoring invalid sparse path %q", p)
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// depthArgs keeps follow-up fetches as shallow as the initial clone.
+func depthArgs() []string {
+	if cfg.CloneDepth > 0 {
+		return []string{"--depth", strconv.Itoa(cfg.CloneDepth)}
+	}
+	return nil
+}
+
+// workspaceHasRepo reports whether /workspace already holds a clone, e.g.
+// from a persisted volume.
+func workspaceHasRepo() bool {
+	info, err := os.Stat("/workspace/.git")
+	return err == nil && info.IsDir()
+}
+
+// clearWorkspace empties /workspace so a fresh clone can go there. Without
+// persistWorkspace every start clones again, even if the sandbox kept an
+// earlier clone. The directory itself stays, as it may be a mount point.
+func clearWorkspace() error {
+	entries, err := os.ReadDir("/workspace")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if len(entries) > 0 {
+		log.Printf("Discarding existing /workspace contents, the project does not persist its workspace")
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join("/workspace", e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runGitProgress runs a long git command in dir with its output, progress
+// meter included, going to w. It is killed when ctx ends.
+func runGitProgress(ctx context.Context, w io.Writer, dir string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdout = w
+	cmd.Stderr = w
+	// git-remote-https can outlive a killed git and hold the output open
+	cmd.WaitDelay = 5 * time.Second
+	return cmd.Run()
+}
+
+// cloneWorkspace clones the repository into /workspace using the project's
+// clone strategy: a depth limit, a partial clone filter such as blob:none,
+// and sparse-checkout paths.
+func cloneWorkspace(ctx context.Context, w io.Writer, cloneURL string) error {
+	args := []string{"clone", "--progress"}
+	args = append(args, depthArgs()...)
+	if cfg.CloneFilter != "" {
+		args = append(args, "--filter="+cfg.CloneFilter)
+	}
+	if len(cfg.SparsePaths) > 0 {
+		args = append(args, "--sparse")
+	}
+	args = append(args, "--", cloneURL, "/workspace")
+	log.Printf("Cloning repository from: %s (depth=%d filter=%q sparse=%v)", maskToken(cloneURL), cfg.CloneDepth, cfg.CloneFilter, cfg.SparsePaths)
+	if err := runGitProgress(ctx, w, "/", args...); err != nil || len(cfg.SparsePaths) == 0 {
+		return err
+	}
+	return runGitProgress(ctx, w, "/workspace", append([]string{"sparse-checkout", "set", "--"}, cfg.SparsePaths...)...)
+}
+
+// updateWorkspace refreshes an existing clone instead of cloning again: it
+// updates the remote URL (the token changes every start), fetches, and
+// fast-forwards the checked-out branch when it can. Local commits that
+// diverged from upstream are left alone.
+func updateWorkspace(ctx context.Context, w io.Writer, cloneURL string) error {
+	current, _, err := runGit(ctx, "remote", "get-url", "origin")
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("existing workspace has no origin remote")
+	}
+	if !sameRepo(strings.TrimSpace(current), cfg.RepoURL) {
+		return fmt.Errorf("existing workspace is a clone of a different repository")
+	}
+	if _, err := gitOutput(ctx, nil, "remote", "set-url", "origin", cloneURL); err != nil {
+		return err
+	}
+
+	log.Printf("Reusing existing workspace, fetching from: %s", maskToken(cloneURL))
+	// No --depth here: on a shallow clone that would cut history at the new
+	// tip and the fast-forward below would see unrelated histories
+	if err := runGitProgress(ctx, w, "/workspace", "fetch", "--progress", "--prune", "origin"); err != nil {
+		return err
+	}
+	if _, _, err := runGit(ctx, "rev-parse", "--verify", "-q", "@{upstream}"); err == nil {
+		if err := runGitProgress(ctx, w, "/workspace", "merge", "--ff-only", "@{upstream}"); err != nil {
+			log.Printf("Workspace could not be fast-forwarded, keeping local state: %v", err)
+		}
+	}
+	return nil
+}
+
+// sameRepo compares two remote URLs ignoring credentials and a .git suffix.
+func sameRepo(a, b string) bool {
+	normalize := func(s string) string {
+		if u, err := url.Parse(s); err == nil && u.Host != "" {
+			u.User = nil
+			s = u.Host + u.Path
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(s, "/"), ".git")
+	}
+	return normalize(a) == normalize(b)
+}
+
+// checkoutStartRef checks out the ref or pull request the workspace was
+// started for, after the default branch has been cloned. Branches become
+// local tracking branches, tags and commits a detached HEAD, and pull
+// requests a local pr/<n> branch.
+func checkoutStartRef(ctx context.Context) error {
+	if cfg.GitPR != "" {
+		n, err := strconv.Atoi(cfg.GitPR)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid pull request number %q", cfg.GitPR)
+		}
+		branch := fmt.Sprintf("pr/%d", n)
+		args := append([]string{"fetch"}, depthArgs()...)
+		// The remote-tracking copy lets the shutdown sync tell the pull
+		// request's own commits from new ones
+		if _, err := gitOutput(ctx, nil, append(args, "origin",
+			fmt.Sprintf("+pull/%d/head:refs/heads/%s", n, branch),
+			fmt.Sprintf("+pull/%d/head:refs/remotes/pull/%d", n, n))...); err != nil {
+			return err
+		}
+		_, err = gitOutput(ctx, nil, "checkout", branch, "--")
+		return err
+	}
+
+	ref := cfg.GitRef
+	if ref == "" {
+		return nil
+	}
+	if !validRefName(ctx, ref) {
+		return fmt.Errorf("invalid ref %q", ref)
+	}
+	if current, _, _ := runGit(ctx, "symbolic-ref", "--short", "-q", "HEAD"); strings.TrimSpace(current) == ref {
+		return nil
+	}
+
+	// Branches
+	if _, _, err := runGit(ctx, "rev-parse", "--verify", "-q", "refs/heads/"+ref); err == nil {
+		_, err = gitOutput(ctx, nil, "checkout", ref, "--")
+		return err
+	}
+	remoteRef := "refs/remotes/origin/" + ref
+	if _, _, err := runGit(ctx, "rev-parse", "--verify", "-q", remoteRef); err != nil {
+		// Single-branch and shallow clones only know the default branch
+		args := append([]string{"fetch"}, depthArgs()...)
+		if _, _, err := runGit(ctx, append(args, "origin", "+refs/heads/"+ref+":"+remoteRef)...); err == nil {
+			trackRemoteBranch(ctx, ref)
+		}
+	}
+	if _, _, err := runGit(ctx, "rev-parse", "--verify", "-q", remoteRef); err == nil {
+		_, err = gitOutput(ctx, nil, "checkout", "--track", "origin/"+ref, "--")
+		return err
+	}
+
+	// Tags and commits. Commits the clone didn't bring along are fetched
+	// directly, which GitHub allows for any reachable SHA.
+	target := ref
+	if _, _, err := runGit(ctx, "rev-parse", "--verify", "-q", ref+"^{commit}"); err != nil {
+		args := append([]string{"fetch"}, depthArgs()...)
+		if _, err := gitOutput(ctx, nil, append(args, "origin", ref)...); err != nil {
+			return fmt.Errorf("ref %q not found: %v", ref, err)
+		}
+		target = "FETCH_HEAD"
+	}
+	_, err := gitOutput(ctx, nil, "checkout", "--detach", target, "--")
+	return err
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Replaced with a free port in adapter commands that listen on TCP
+	debugPortPlaceholder = "{port}"
+	// How long a TCP adapter gets to start listening
+	debugConnectTimeout = 10 * time.Second
+)
+
+// Debug adapters used when the workspace config doesn't name one. They have
+// to be installed in the workspace image.
+var defaultDebugAdapters = map[string]string{
+	"go":     "dlv dap --listen 127.0.0.1:{port}",
+	"python": "python3 -m debugpy.adapter",
+	"node":   "js-debug-adapter {port} 127.0.0.1",
+}
+
+// debugPorts holds the ports TCP adapters listen on; they are the agent's
+// business, so the port watcher doesn't report them.
+var debugPorts = struct {
+	sync.Mutex
+	ports map[int]bool
+}{ports: map[int]bool{}}
+
+func isDebugPort(port int) bool {
+	debugPorts.Lock()
+	defer debugPorts.Unlock()
+	return debugPorts.ports[port]
+}
+
+// debugAdapterCommand returns the command for an adapter, from the
+// workspace config first. builtin reports whether it is a default one.
+func debugAdapterCommand(name string) (command string, builtin, ok bool) {
+	if command, ok := currentWorkspaceConfig().DebugAdapters[name]; ok {
+		return command, false, true
+	}
+	command, ok = defaultDebugAdapters[name]
+	return command, true, ok
+}
+
+// commandInstalled reports whether the program a command line starts is on
+// the PATH.
+func commandInstalled(command string) bool {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return false
+	}
+	_, err := exec.LookPath(fields[0])
+	return err == nil
+}
+
+type debugAdapterInfo struct {
+	Name      string `json:"name"`
+	Command   string `json:"command"`
+	Transport string `json:"transport"` // stdio or tcp
+	Available bool   `json:"available"`
+}
+
+func listDebugAdapters() []debugAdapterInfo {
+	names := map[string]bool{}
+	for name := range defaultDebugAdapters {
+		names[name] = true
+	}
+	for name := range currentWorkspaceConfig().DebugAdapters {
+		names[name] = true
+	}
+	list := make([]debugAdapterInfo, 0, len(names))
+	for name := range names {
+		command, builtin, _ := debugAdapterCommand(name)
+		transport := "stdio"
+		if strings.Contains(command, debugPortPlaceholder) {
+			transport = "tcp"
+		}
+		list = append(list, debugAdapterInfo{
+			Name:      name,
+			Command:   command,
+			Transport: transport,
+			Available: !builtin || commandInstalled(command),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// startDebugAdapter launches an adapter as a task run, so it shows up in
+// /tasks with its output and can be canceled like any task, and returns the
+// DAP channel to it: its stdio, or a connection to the port it listens on.
+func startDebugAdapter(name, command string) (*taskRun, *bufio.Reader, io.WriteCloser, error) {
+	port := 0
+	if strings.Contains(command, debugPortPlaceholder) {
+		var err error
+		if port, err = freePort(); err != nil {
+			return nil, nil, nil, err
+		}
+		command = strings.ReplaceAll(command, debugPortPlaceholder, strconv.Itoa(port))
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Dir = "/workspace"
+	cmd.Env = workspaceEnviron()
+	t := newTaskRun("", command, "", cmd)
+	t.Adapter = name
+	cmd.Stderr = taskStreamWriter{t, "stderr"}
+
+	if port == 0 {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		// Not StdoutPipe: Wait would close it while the bridge is still
+		// reading the adapter's last messages
+		stdout, stdoutW, err := os.Pipe()
+		if err != nil {
+			stdin.Close()
+			return nil, nil, nil, err
+		}
+		cmd.Stdout = stdoutW
+		err = launchTask(t)
+		stdoutW.Close()
+		if err != nil {
+			stdin.Close()
+			stdout.Close()
+			return nil, nil, nil, err
+		}
+		return t, bufio.NewReader(stdout), adapterStdio{stdin, stdout}, nil
+	}
+
+	cmd.Stdout = taskStreamWriter{t, "stdout"}
+	debugPorts.Lock()
+	debugPorts.ports[port] = true
+	debugPorts.Unlock()
+	releasePort := func() {
+		debugPorts.Lock()
+		delete(debugPorts.ports, port)
+		debugPorts.Unlock()
+	}
+	if err := launchTask(t); err != nil {
+		releasePort()
+		return nil, nil, nil, err
+	}
+	go func() {
+		<-t.exited
+		releasePort()
+	}()
+	conn, err := dialDebugAdapter(t, port)
+	if err != nil {
+		t.cancel()
+		return nil, nil, nil, err
+	}
+	return t, bufio.NewReader(conn), conn, nil
+}
+
+// adapterStdio is the write side of a stdio adapter. Closing it closes the
+// adapter's output too, which the bridge owns since Wait doesn't.
+type adapterStdio struct {
+	io.WriteCloser
+	stdout *os.File
+}
+
+func (a adapterStdio) Close() error {
+	err := a.WriteCloser.Close()
+	a.stdout.Close()
+	return err
+}
+
+// dialDebugAdapter connects to an adapter once it listens, giving up if it
+// exits or takes too long.
+func dialDebugAdapter(t *taskRun, port int) (net.Conn, error) {
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	deadline := time.Now().Add(debugConnectTimeout)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			return conn, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("debug adapter did not listen on %s: %v", addr, err)
+		}
+		select {
+		case <-t.exited:
+			return nil, errors.New("debug adapter exited before accepting connections")
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// debugAdaptersHandler lists the available adapters and the debug sessions.
+func debugAdaptersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	sessions := []taskRunInfo{}
+	for _, run := range listTaskRuns() {
+		if run.Adapter != "" && run.Status == taskRunning {
+			sessions = append(sessions, run)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"adapters": listDebugAdapters(),
+		"sessions": sessions,
+	})
+}
+
+// debugHandler starts the debug adapter ?adapter= and bridges DAP over the
+// WebSocket, one message per text frame. The adapter runs as a task whose
+// id is sent in the X-Task-Run-Id handshake header; its own output can be
+// followed on /tasks/stream. Disconnecting stops the adapter and the
+// program being debugged.
+func debugHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	name := r.URL.Query().Get("adapter")
+	if !validLanguage(name) {
+		http.Error(w, "invalid adapter", 400)
+		return
+	}
+	command, builtin, ok := debugAdapterCommand(name)
+	if !ok {
+		http.Error(w, "unknown debug adapter "+name, 404)
+		return
+	}
+	if builtin && !commandInstalled(command) {
+		http.Error(w, "debug adapter not installed: "+strings.Fields(command)[0], http.StatusNotImplemented)
+		return
+	}
+
+	t, fromAdapter, toAdapter, err := startDebugAdapter(name, command)
+	if err != nil {
+		if errors.Is(err, errTooManyTasks) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		logWithRequestID(r, "Failed to start %s debug adapter: %v", name, err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	defer t.cancel()
+	defer toAdapter.Close()
+
+	conn, err := upgrader.Upgrade(w, r, http.Header{"X-Task-Run-Id": {t.ID}})
+	if err != nil {
+		logWithRequestID(r, "WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(lspMaxMessage)
+	logWithRequestID(r, "Bridging %s debug adapter (task %s)", name, t.ID)
+
+	// DAP uses the same Content-Length framing as LSP
+	adapterDone := make(chan struct{})
+	go func() {
+		defer close(adapterDone)
+		for {
+			msg, err := readLSPMessage(fromAdapter)
+			if err != nil {
+				if err != io.EOF && !errors.Is(err, net.ErrClosed) && !errors.Is(err, os.ErrClosed) {
+					logWithRequestID(r, "Debug adapter %s read error: %v", t.ID, err)
+				}
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	clientDone := make(chan struct{})
+	go func() {
+		defer close(clientDone)
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := writeLSPMessage(toAdapter, msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-clientDone:
+		logWithRequestID(r, "Debug client for task %s disconnected", t.ID)
+	case <-adapterDone:
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "debug adapter exited"))
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestStdioAdapterOutputAfterExit(t *testing.T) {
+	// The adapter writes its last message and exits straight away; the
+	// message must still be readable after the run has been waited on
+	run, fromAdapter, toAdapter, err := startDebugAdapter("test", `printf 'Content-Length: 13\r\n\r\n{"seq":1}    '`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer forgetTaskRun(t, run)
+	defer toAdapter.Close()
+	waitExited(t, run)
+
+	msg, err := readLSPMessage(fromAdapter)
+	if err != nil || string(msg) != `{"seq":1}    ` {
+		t.Fatalf("read %q, %v", msg, err)
+	}
+	if _, err := readLSPMessage(fromAdapter); err != io.EOF {
+		t.Errorf("after the last message: err = %v, want EOF", err)
+	}
+	if run.Adapter != "test" {
+		t.Errorf("run adapter = %q", run.Adapter)
+	}
+}
+
+func TestTCPAdapterExitsBeforeListening(t *testing.T) {
+	run, _, _, err := startDebugAdapter("test", "echo no listener on {port} >&2; exit 1")
+	if err == nil || !strings.Contains(err.Error(), "exited before accepting connections") {
+		t.Fatalf("err = %v", err)
+	}
+	if run != nil {
+		t.Error("a run was returned with the error")
+	}
+	tasksMu.Lock()
+	for id, r := range taskRuns {
+		if r.Adapter == "test" {
+			delete(taskRuns, id)
+		}
+	}
+	tasksMu.Unlock()
+
+	// The port is handed back once the adapter is gone
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		debugPorts.Lock()
+		n := len(debugPorts.ports)
+		debugPorts.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d debug ports still reserved", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDebugHandlerBridge(t *testing.T) {
+	setTestReady(t)
+	workspaceState.Lock()
+	saved := workspaceState.config
+	workspaceState.config.DebugAdapters = map[string]string{"echo": "cat"}
+	workspaceState.Unlock()
+	defer func() {
+		workspaceState.Lock()
+		workspaceState.config = saved
+		workspaceState.Unlock()
+	}()
+
+	srv := httptest.NewServer(http.HandlerFunc(debugHandler))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/debug?adapter=echo"
+	conn, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"http://localhost:5173"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := resp.Header.Get("X-Task-Run-Id")
+	tasksMu.Lock()
+	run := taskRuns[id]
+	tasksMu.Unlock()
+	if run == nil {
+		t.Fatalf("no task run %q for the adapter", id)
+	}
+	defer forgetTaskRun(t, run)
+
+	// Messages go through the adapter in DAP framing and come back as
+	// single frames
+	for _, msg := range []string{`{"seq":1,"type":"request"}`, `{"seq":2}`} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, got, err := conn.ReadMessage()
+		if err != nil || string(got) != msg {
+			t.Fatalf("echoed %q, %v, want %q", got, err, msg)
+		}
+	}
+
+	// Disconnecting stops the adapter
+	conn.Close()
+	waitExited(t, run)
+	if info := run.info(); info.Status != taskCanceled && info.Status != taskSucceeded {
+		t.Errorf("adapter status after disconnect = %s", info.Status)
+	}
+}
+
+func waitExited(t *testing.T, run *taskRun) {
+	t.Helper()
+	select {
+	case <-run.exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not exit")
+	}
+}
+
+// forgetTaskRun drops a finished run from the history so tests don't see
+// each other's runs.
+func forgetTaskRun(t *testing.T, run *taskRun) {
+	t.Helper()
+	run.cancel()
+	tasksMu.Lock()
+	delete(taskRuns, run.ID)
+	tasksMu.Unlock()
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+func writeFileOpResult(w http.ResponseWriter, result map[string]interface{}) {
+	result["ok"] = true
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// fileOpError maps filesystem errors onto HTTP statuses.
+func fileOpError(w http.ResponseWriter, r *http.Request, op, path string, err error) {
+	logWithRequestID(r, "File %s failed for %s: %v", op, path, err)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		http.Error(w, "file not found", 404)
+	case errors.Is(err, os.ErrExist):
+		http.Error(w, "destination already exists", http.StatusConflict)
+	case errors.Is(err, os.ErrPermission):
+		http.Error(w, "permission denied", http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+// fileDeleteHandler removes a file, or a directory when recursive=true.
+func fileDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "path required", 400)
+		return
+	}
+	fullPath, err := resolveWorkspaceEntry(path)
+	if err != nil {
+		pathError(w, r, path, err)
+		return
+	}
+
+	info, err := os.Lstat(fullPath)
+	if err != nil {
+		fileOpError(w, r, "delete", path, err)
+		return
+	}
+	if info.IsDir() {
+		if r.URL.Query().Get("recursive") == "true" {
+			err = os.RemoveAll(fullPath)
+		} else {
+			err = os.Remove(fullPath)
+			if errors.Is(err, syscall.ENOTEMPTY) || errors.Is(err, syscall.EEXIST) {
+				http.Error(w, "directory not empty; pass recursive=true", http.StatusConflict)
+				return
+			}
+		}
+	} else {
+		err = os.Remove(fullPath)
+	}
+	if err != nil {
+		fileOpError(w, r, "delete", path, err)
+		return
+	}
+
+	logWithRequestID(r, "Deleted %s", path)
+	writeFileOpResult(w, map[string]interface{}{"path": path})
+}
+
+// fileMkdirHandler creates a directory and any missing parents.
+func fileMkdirHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "path required", 400)
+		return
+	}
+	fullPath, err := resolveWorkspacePath(path)
+	if err != nil {
+		pathError(w, r, path, err)
+		return
+	}
+
+	if info, err := os.Stat(fullPath); err == nil && !info.IsDir() {
+		http.Error(w, "a file with that name already exists", http.StatusConflict)
+		return
+	}
+	if err := os.MkdirAll(fullPath, 0755); err != nil {
+		fileOpError(w, r, "mkdir", path, err)
+		return
+	}
+
+	logWithRequestID(r, "Created directory %s", path)
+	writeFileOpResult(w, map[string]interface{}{"path": path})
+}
+
+// parseFromTo validates the from/to pair shared by rename and copy.
+func parseFromTo(w http.ResponseWriter, r *http.Request) (from, to, fullFrom, fullTo string, ok bool) {
+	from = r.URL.Query().Get("from")
+	to = r.URL.Query().Get("to")
+	if from == "" || to == "" {
+		http.Error(w, "from and to required", 400)
+		return
+	}
+	var err error
+	if fullFrom, err = resolveWorkspaceEntry(from); err != nil {
+		pathError(w, r, from, err)
+		return
+	}
+	if fullTo, err = resolveWorkspaceEntry(to); err != nil {
+		pathError(w, r, to, err)
+		return
+	}
+	if fullFrom == fullTo {
+		ok = false
+		http.Error(w, "from and to are the same", 400)
+		return
+	}
+	if strings.HasPrefix(fullTo, fullFrom+"/") {
+		ok = false
+		http.Error(w, "cannot move or copy a directory into itself", 400)
+		return
+	}
+	return from, to, fullFrom, fullTo, true
+}
+
+// prepareDestination enforces the overwrite flag and creates parent dirs.
+// It returns what is at the destination now, if anything; that is only
+// replaced once the new content is complete.
+func prepareDestination(fullTo string, overwrite bool) (os.FileInfo, error) {
+	existing, err := os.Lstat(fullTo)
+	if err == nil && !overwrite {
+		return nil, os.ErrExist
+	}
+	if err != nil {
+		existing = nil
+	}
+	return existing, os.MkdirAll(filepath.Dir(fullTo), 0755)
+}
+
+// stagingName reserves a hidden name next to path, in the watcher's temp
+// file shape, to build new content under before it replaces path.
+func stagingName(path string) (string, error) {
+	tmp, err := tempFileFor(path)
+	if err != nil {
+		return "", err
+	}
+	tmp.Close()
+	return tmp.Name(), os.Remove(tmp.Name())
+}
+
+// replaceEntry moves src to dst. A file or symlink at dst is replaced
+// atomically by the rename. A directory at dst, or a file where a directory
+// goes, is moved aside first and removed only once src is in place, so a
+// failed move leaves dst as it was.
+func replaceEntry(src, dst string, existing os.FileInfo) error {
+	srcInfo, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if existing == nil || (!existing.IsDir() && !srcInfo.IsDir()) {
+		return os.Rename(src, dst)
+	}
+	aside, err := stagingName(dst)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(dst, aside); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err != nil {
+		if restoreErr := os.Rename(aside, dst); restoreErr != nil {
+			log.Printf("Failed to restore %s from %s: %v", dst, aside, restoreErr)
+		}
+		return err
+	}
+	if err := os.RemoveAll(aside); err != nil {
+		log.Printf("Failed to remove replaced %s: %v", aside, err)
+	}
+	return nil
+}
+
+// fileRenameHandler renames or moves a file or directory.
+func fileRenameHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	from, to, fullFrom, fullTo, ok := parseFromTo(w, r)
+	if !ok {
+		return
+	}
+	if _, err := os.Lstat(fullFrom); err != nil {
+		fileOpError(w, r, "rename", from, err)
+		return
+	}
+	existing, err := prepareDestination(fullTo, r.URL.Query().Get("overwrite") == "true")
+	if err != nil {
+		fileOpError(w, r, "rename", to, err)
+		return
+	}
+	if err := replaceEntry(fullFrom, fullTo, existing); err != nil {
+		fileOpError(w, r, "rename", from, err)
+		return
+	}
+
+	logWithRequestID(r, "Renamed %s to %s", from, to)
+	writeFileOpResult(w, map[string]interface{}{"from": from, "to": to})
+}
+
+// fileCopyHandler copies a file or directory tree.
+func fileCopyHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	from, to, fullFrom, fullTo, ok := parseFromTo(w, r)
+	if !ok {
+		return
+	}
+	if _, err := os.Lstat(fullFrom); err != nil {
+		fileOpError(w, r, "copy", from, err)
+		return
+	}
+	existing, err := prepareDestination(fullTo, r.URL.Query().Get("overwrite") == "true")
+	if err != nil {
+		fileOpError(w, r, "copy", to, err)
+		return
+	}
+	// Copy under a hidden name first, so a failed copy never touches what
+	// is at the destination
+	staged, err := stagingName(fullTo)
+	if err != nil {
+		fileOpError(w, r, "copy", to, err)
+		return
+	}
+	if err := copyTree(fullFrom, staged); err != nil {
+		os.RemoveAll(staged)
+		fileOpError(w, r, "copy", from, err)
+		return
+	}
+	noteAtomicSave(staged, fullTo)
+	if err := replaceEntry(staged, fullTo, existing); err != nil {
+		os.RemoveAll(staged)
+		fileOpError(w, r, "copy", to, err)
+		return
+	}
+
+	logWithRequestID(r, "Copied %s to %s", from, to)
+	writeFileOpResult(w, map[string]interface{}{"from": from, "to": to})
+}
+
+// copyTree copies src to dst, recreating symlinks rather than following them.
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// Sockets, devices and fifos are skipped
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplaceEntry(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string // "file" or "dir"
+		dst  string // "", "file" or "dir"
+	}{
+		{"file to new name", "file", ""},
+		{"file over file", "file", "file"},
+		{"file over directory", "file", "dir"},
+		{"directory to new name", "dir", ""},
+		{"directory over directory", "dir", "dir"},
+		{"directory over file", "dir", "file"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		src := filepath.Join(dir, "src")
+		dst := filepath.Join(dir, "dst")
+		makeEntry(t, src, tt.src, "new")
+		makeEntry(t, dst, tt.dst, "old")
+
+		existing, _ := os.Lstat(dst)
+		if err := replaceEntry(src, dst, existing); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if _, err := os.Lstat(src); !os.IsNotExist(err) {
+			t.Errorf("%s: source still exists", tt.name)
+		}
+		if got := entryContent(t, dst); got != "new" {
+			t.Errorf("%s: destination holds %q", tt.name, got)
+		}
+		assertOnlyEntries(t, dir, "dst")
+	}
+}
+
+func TestReplaceEntryRollback(t *testing.T) {
+	// Moving a directory into itself fails after the existing destination
+	// has been moved aside; it must be put back.
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	makeEntry(t, src, "dir", "new")
+	dst := filepath.Join(src, "child")
+	makeEntry(t, dst, "dir", "old")
+
+	existing, _ := os.Lstat(dst)
+	if err := replaceEntry(src, dst, existing); err == nil {
+		t.Fatal("moving a directory into itself succeeded")
+	}
+	if got := entryContent(t, dst); got != "old" {
+		t.Errorf("destination holds %q after rollback", got)
+	}
+	if got := entryContent(t, src); got != "new" {
+		t.Errorf("source holds %q after rollback", got)
+	}
+	assertOnlyEntries(t, src, "child", "content")
+}
+
+func TestPrepareDestination(t *testing.T) {
+	dir := t.TempDir()
+	existingPath := filepath.Join(dir, "exists.txt")
+	makeEntry(t, existingPath, "file", "x")
+
+	if _, err := prepareDestination(existingPath, false); !os.IsExist(err) {
+		t.Errorf("existing destination without overwrite: err = %v, want ErrExist", err)
+	}
+	info, err := prepareDestination(existingPath, true)
+	if err != nil || info == nil {
+		t.Errorf("existing destination with overwrite = %v, %v", info, err)
+	}
+	nested := filepath.Join(dir, "a", "b", "c.txt")
+	info, err = prepareDestination(nested, false)
+	if err != nil || info != nil {
+		t.Errorf("new destination = %v, %v", info, err)
+	}
+	if fi, err := os.Stat(filepath.Dir(nested)); err != nil || !fi.IsDir() {
+		t.Error("parent directories were not created")
+	}
+}
+
+// makeEntry creates a file, or a directory holding a file named content,
+// with the given content. An empty kind creates nothing.
+func makeEntry(t *testing.T, path, kind, content string) {
+	t.Helper()
+	switch kind {
+	case "file":
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	case "dir":
+		mustMkdir(t, path)
+		if err := os.WriteFile(filepath.Join(path, "content"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func entryContent(t *testing.T, path string) string {
+	t.Helper()
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.IsDir() {
+		path = filepath.Join(path, "content")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// assertOnlyEntries checks dir holds exactly the named entries, so nothing
+// moved aside was left behind.
+func assertOnlyEntries(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{}
+	for _, n := range names {
+		want[n] = true
+	}
+	for _, e := range entries {
+		if !want[e.Name()] {
+			t.Errorf("unexpected entry %s in %s", e.Name(), filepath.Base(dir))
+		}
+		delete(want, e.Name())
+	}
+	for n := range want {
+		t.Errorf("missing entry %s in %s", n, filepath.Base(dir))
+	}
+}
@@ -0,0 +1,635 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Local git operations
+	gitTimeout = 30 * time.Second
+	// fetch / pull / push talk to the remote
+	gitRemoteTimeout = 2 * time.Minute
+)
+
+// gitMu serializes git commands that touch the index or refs so API calls,
+// the auto-save loop and shutdown sync don't trip over index.lock.
+var gitMu sync.Mutex
+
+// gitResult is the structured outcome of a git command.
+type gitResult struct {
+	OK       bool   `json:"ok"`
+	ExitCode int    `json:"exitCode"`
+	Output   string `json:"output,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// runGit runs git in /workspace and captures stdout and stderr separately.
+// The repository token is masked in the returned output.
+func runGit(ctx context.Context, args ...string) (string, string, error) {
+	return runGitEnv(ctx, nil, args...)
+}
+
+// runGitEnv is runGit with extra environment variables, e.g. GIT_INDEX_FILE.
+func runGitEnv(ctx context.Context, env []string, args ...string) (string, string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = "/workspace"
+	cmd.Env = append(append(cmd.Environ(), "GIT_TERMINAL_PROMPT=0"), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	return maskGitToken(stdout.String()), maskGitToken(stderr.String()), err
+}
+
+// maskGitToken hides the repository token wherever git echoes it back.
+func maskGitToken(s string) string {
+	if cfg.GitToken == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, cfg.GitToken, "***")
+}
+
+// gitCommandResult runs a git command under gitMu and folds the outcome
+// into a gitResult.
+func gitCommandResult(ctx context.Context, timeout time.Duration, args ...string) gitResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	gitMu.Lock()
+	stdout, stderr, err := runGit(ctx, args...)
+	gitMu.Unlock()
+
+	res := gitResult{OK: err == nil, Output: strings.TrimSpace(stdout + stderr)}
+	if err != nil {
+		res.Error = err.Error()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			res.ExitCode = exitErr.ExitCode()
+		} else {
+			res.ExitCode = -1
+		}
+	}
+	return res
+}
+
+// gitArgs joins a command's arguments, extra flags and a pathspec.
+func gitArgs(args, flags, pathspec []string) []string {
+	out := append(append([]string{}, args...), flags...)
+	return append(append(out, "--"), pathspec...)
+}
+
+func writeGitResult(w http.ResponseWriter, res gitResult) {
+	w.Header().Set("Content-Type", "application/json")
+	if !res.OK {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+// validGitPath keeps pathspecs inside the workspace.
+func validGitPath(p string) bool {
+	return p != "" && !strings.HasPrefix(p, "-") && !strings.ContainsRune(p, 0) && filepath.IsLocal(p)
+}
+
+// validRefName rejects names git would refuse or that look like options.
+func validRefName(ctx context.Context, name string) bool {
+	if name == "" || strings.HasPrefix(name, "-") {
+		return false
+	}
+	_, _, err := runGit(ctx, "check-ref-format", "--branch", name)
+	return err == nil
+}
+
+// gitPreamble performs the checks shared by every /git endpoint.
+func gitPreamble(w http.ResponseWriter, r *http.Request, method string) bool {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return false
+	}
+	if r.Method != method {
+		http.Error(w, "method not allowed", 405)
+		return false
+	}
+	return true
+}
+
+type gitFileStatus struct {
+	Path      string `json:"path"`
+	OrigPath  string `json:"origPath,omitempty"`
+	Index     string `json:"index"`    // status in the index, "." when unchanged
+	Worktree  string `json:"worktree"` // status in the work tree, "." when unchanged
+	Staged    bool   `json:"staged"`
+	Untracked bool   `json:"untracked,omitempty"`
+	Conflict  bool   `json:"conflict,omitempty"`
+}
+
+type gitStatus struct {
+	Branch   string          `json:"branch"`
+	Commit   string          `json:"commit,omitempty"`
+	Upstream string          `json:"upstream,omitempty"`
+	Ahead    int             `json:"ahead"`
+	Behind   int             `json:"behind"`
+	Detached bool            `json:"detached,omitempty"`
+	Files    []gitFileStatus `json:"files"`
+}
+
+// parseGitStatus parses `git status --porcelain=v2 --branch -z`.
+func parseGitStatus(out string) gitStatus {
+	st := gitStatus{Files: []gitFileStatus{}}
+	records := strings.Split(out, "\x00")
+	for i := 0; i < len(records); i++ {
+		rec := records[i]
+		switch {
+		case strings.HasPrefix(rec, "# branch.oid "):
+			st.Commit = strings.TrimPrefix(rec, "# branch.oid ")
+		case strings.HasPrefix(rec, "# branch.head "):
+			st.Branch = strings.TrimPrefix(rec, "# branch.head ")
+			st.Detached = st.Branch == "(detached)"
+		case strings.HasPrefix(rec, "# branch.upstream "):
+			st.Upstream = strings.TrimPrefix(rec, "# branch.upstream ")
+		case strings.HasPrefix(rec, "# branch.ab "):
+			fields := strings.Fields(strings.TrimPrefix(rec, "# branch.ab "))
+			if len(fields) == 2 {
+				st.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[0], "+"))
+				st.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "-"))
+			}
+		case strings.HasPrefix(rec, "1 "):
+			// 1 XY sub mH mI mW hH hI path
+			fields := strings.SplitN(rec, " ", 9)
+			if len(fields) == 9 {
+				st.Files = append(st.Files, newGitFileStatus(fields[1], fields[8], ""))
+			}
+		case strings.HasPrefix(rec, "2 "):
+			// 2 XY sub mH mI mW hH hI Xscore path, followed by origPath record
+			fields := strings.SplitN(rec, " ", 10)
+			if len(fields) == 10 {
+				orig := ""
+				if i+1 < len(records) {
+					orig = records[i+1]
+					i++
+				}
+				st.Files = append(st.Files, newGitFileStatus(fields[1], fields[9], orig))
+			}
+		case strings.HasPrefix(rec, "u "):
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			fields := strings.SplitN(rec, " ", 11)
+			if len(fields) == 11 {
+				fs := newGitFileStatus(fields[1], fields[10], "")
+				fs.Conflict = true
+				st.Files = append(st.Files, fs)
+			}
+		case strings.HasPrefix(rec, "? "):
+			st.Files = append(st.Files, gitFileStatus{
+				Path:      strings.TrimPrefix(rec, "? "),
+				Index:     "?",
+				Worktree:  "?",
+				Untracked: true,
+			})
+		}
+	}
+	return st
+}
+
+func newGitFileStatus(xy, path, orig string) gitFileStatus {
+	return gitFileStatus{
+		Path:     path,
+		OrigPath: orig,
+		Index:    xy[:1],
+		Worktree: xy[1:],
+		Staged:   xy[0] != '.',
+	}
+}
+
+// gitStatusHandler reports branch tracking info and changed files.
+func gitStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if !gitPreamble(w, r, http.MethodGet) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), gitTimeout)
+	defer cancel()
+	stdout, stderr, err := runGit(ctx, "status", "--porcelain=v2", "--branch", "-z")
+	if err != nil {
+		logWithRequestID(r, "git status failed: %v: %s", err, stderr)
+		http.Error(w, strings.TrimSpace(stderr), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(parseGitStatus(stdout))
+}
+
+// gitDiffHandler returns the unified diff of one file (or everything when
+// path is omitted), against the index or, with staged=true, against HEAD.
+func gitDiffHandler(w http.ResponseWriter, r *http.Request) {
+	if !gitPreamble(w, r, http.MethodGet) {
+		return
+	}
+	path := r.URL.Query().Get("path")
+	if path != "" && !validGitPath(path) {
+		http.Error(w, "invalid path", 400)
+		return
+	}
+	if path != "" && pathDenied(path) {
+		pathError(w, r, path, errPathDenied)
+		return
+	}
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if r.URL.Query().Get("staged") == "true" {
+		args = append(args, "--cached")
+	}
+	var pathspec []string
+	if path != "" {
+		pathspec = append(pathspec, path)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), gitTimeout)
+	defer cancel()
+	// Leave out changed files the policy denies. Exclude pathspecs can't
+	// re-allow what a glob denied, so the files are checked one by one.
+	changed, stderr, err := runGit(ctx, gitArgs(args, []string{"--name-only", "-z", "--no-renames"}, pathspec)...)
+	if err != nil {
+		logWithRequestID(r, "git diff failed: %v: %s", err, stderr)
+		http.Error(w, strings.TrimSpace(stderr), 500)
+		return
+	}
+	for _, name := range strings.Split(changed, "\x00") {
+		if name != "" && pathDenied(name) {
+			pathspec = append(pathspec, ":(exclude,literal)"+name)
+		}
+	}
+	stdout, stderr, err := runGit(ctx, gitArgs(args, nil, pathspec)...)
+	if err != nil {
+		logWithRequestID(r, "git diff failed: %v: %s", err, stderr)
+		http.Error(w, strings.TrimSpace(stderr), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"path":   path,
+		"staged": r.URL.Query().Get("staged") == "true",
+		"diff":   stdout,
+	})
+}
+
+type gitPathsRequest struct {
+	Paths []string `json:"paths"`
+	All   bool     `json:"all"`
+}
+
+func decodeGitPaths(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var body gitPathsRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", 400)
+		return nil, false
+	}
+	if body.All {
+		return []string{"."}, true
+	}
+	if len(body.Paths) == 0 {
+		http.Error(w, "paths required", 400)
+		return nil, false
+	}
+	for _, p := range body.Paths {
+		if !validGitPath(p) {
+			http.Error(w, "invalid path: "+p, 400)
+			return nil, false
+		}
+	}
+	return body.Paths, true
+}
+
+// gitStageHandler adds paths (or everything with all=true) to the index.
+func gitStageHandler(w http.ResponseWriter, r *http.Request) {
+	if !gitPreamble(w, r, http.MethodPost) {
+		return
+	}
+	paths, ok := decodeGitPaths(w, r)
+	if !ok {
+		return
+	}
+	for _, p := range paths {
+		if pathDenied(p) {
+			pathError(w, r, p, errPathDenied)
+			return
+		}
+	}
+	res, ok := stageChanges(w, r, []string{"--all"}, paths)
+	if !ok {
+		return
+	}
+	logWithRequestID(r, "git stage %v: ok=%t", paths, res.OK)
+	writeGitResult(w, res)
+}
+
+// stageChanges runs git add with flags over paths, leaving out the changed
+// files the policy denies so they can't be committed by staging a directory
+// or everything. Exclude pathspecs can't re-allow what a glob denied, so
+// the files are checked one by one.
+func stageChanges(w http.ResponseWriter, r *http.Request, flags, paths []string) (gitResult, bool) {
+	ctx, cancel := context.WithTimeout(r.Context(), gitTimeout)
+	defer cancel()
+	// The files add would pick up: changed tracked files and, unless only
+	// tracked files are being updated, untracked ones that aren't ignored
+	lsFlags := []string{"-z", "--modified", "--deleted"}
+	if !slices.Contains(flags, "--update") {
+		lsFlags = append(lsFlags, "--others", "--exclude-standard")
+	}
+	changed, stderr, err := runGit(ctx, gitArgs([]string{"ls-files"}, lsFlags, paths)...)
+	if err != nil {
+		logWithRequestID(r, "git ls-files failed: %v: %s", err, stderr)
+		http.Error(w, strings.TrimSpace(stderr), 500)
+		return gitResult{}, false
+	}
+	pathspec := append([]string{}, paths...)
+	for _, name := range strings.Split(changed, "\x00") {
+		if name != "" && pathDenied(name) {
+			pathspec = append(pathspec, ":(exclude,literal)"+name)
+		}
+	}
+	return gitCommandResult(r.Context(), gitTimeout, gitArgs([]string{"add"}, flags, pathspec)...), true
+}
+
+// gitUnstageHandler removes paths from the index, keeping work tree changes.
+func gitUnstageHandler(w http.ResponseWriter, r *http.Request) {
+	if !gitPreamble(w, r, http.MethodPost) {
+		return
+	}
+	paths, ok := decodeGitPaths(w, r)
+	if !ok {
+		return
+	}
+	res := gitCommandResult(r.Context(), gitTimeout, append([]string{"reset", "-q", "HEAD", "--"}, paths...)...)
+	if !res.OK && strings.Contains(res.Output, "ambiguous argument 'HEAD'") {
+		// No commits yet: unstage by dropping from the index
+		res = gitCommandResult(r.Context(), gitTimeout, append([]string{"rm", "-r", "-q", "--cached", "--"}, paths...)...)
+	}
+	logWithRequestID(r, "git unstage %v: ok=%t", paths, res.OK)
+	writeGitResult(w, res)
+}
+
+// gitCommitHandler commits the index with the user's message.
+func gitCommitHandler(w http.ResponseWriter, r *http.Request) {
+	if !gitPreamble(w, r, http.MethodPost) {
+		return
+	}
+	var body struct {
+		Message string `json:"message"`
+		All     bool   `json:"all"`   // stage tracked modifications first, like commit -a
+		Amend   bool   `json:"amend"` // rewrite the previous commit
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", 400)
+		return
+	}
+	if strings.TrimSpace(body.Message) == "" && !body.Amend {
+		http.Error(w, "message required", 400)
+		return
+	}
+
+	args := []string{"commit"}
+	if body.All {
+		// Stage tracked modifications here rather than with commit --all,
+		// which would include denied files
+		res, ok := stageChanges(w, r, []string{"--update"}, []string{"."})
+		if !ok {
+			return
+		}
+		if !res.OK {
+			writeGitResult(w, res)
+			return
+		}
+	}
+	if body.Amend {
+		args = append(args, "--amend")
+		if body.Message == "" {
+			args = append(args, "--no-edit")
+		}
+	}
+	if body.Message != "" {
+		args = append(args, "-m", body.Message)
+	}
+	res := gitCommandResult(r.Context(), gitTimeout, args...)
+	if !res.OK {
+		writeGitResult(w, res)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), gitTimeout)
+	defer cancel()
+	sha, _, _ := runGit(ctx, "rev-parse", "HEAD")
+	sha = strings.TrimSpace(sha)
+	logWithRequestID(r, "git commit created %s", sha)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ok":     true,
+		"output": res.Output,
+		"commit": sha,
+	})
+}
+
+type gitBranch struct {
+	Name     string `json:"name"`
+	Commit   string `json:"commit"`
+	Upstream string `json:"upstream,omitempty"`
+	Current  bool   `json:"current"`
+	Remote   bool   `json:"remote"`
+}
+
+// gitBranchesHandler lists branches (GET) or creates one (POST).
+func gitBranchesHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		if !gitPreamble(w, r, http.MethodGet) {
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), gitTimeout)
+		defer cancel()
+		stdout, stderr, err := runGit(ctx, "for-each-ref",
+			"--format=%(HEAD)%00%(refname)%00%(refname:short)%00%(objectname:short)%00%(upstream:short)",
+			"refs/heads", "refs/remotes")
+		if err != nil {
+			logWithRequestID(r, "git for-each-ref failed: %v: %s", err, stderr)
+			http.Error(w, strings.TrimSpace(stderr), 500)
+			return
+		}
+		branches := []gitBranch{}
+		for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+			fields := strings.Split(line, "\x00")
+			if len(fields) != 5 || strings.HasSuffix(fields[1], "/HEAD") {
+				continue
+			}
+			branches = append(branches, gitBranch{
+				Name:     fields[2],
+				Commit:   fields[3],
+				Upstream: fields[4],
+				Current:  fields[0] == "*",
+				Remote:   strings.HasPrefix(fields[1], "refs/remotes/"),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(branches)
+	case http.MethodPost:
+		if !gitPreamble(w, r, http.MethodPost) {
+			return
+		}
+		var body struct {
+			Name       string `json:"name"`
+			StartPoint string `json:"startPoint"`
+			Checkout   bool   `json:"checkout"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", 400)
+			return
+		}
+		if !validRefName(r.Context(), body.Name) {
+			http.Error(w, "invalid branch name", 400)
+			return
+		}
+		if strings.HasPrefix(body.StartPoint, "-") {
+			http.Error(w, "invalid start point", 400)
+			return
+		}
+		args := []string{"branch", body.Name}
+		if body.Checkout {
+			args = []string{"checkout", "-b", body.Name}
+		}
+		if body.StartPoint != "" {
+			args = append(args, body.StartPoint)
+		}
+		res := gitCommandResult(r.Context(), gitTimeout, args...)
+		logWithRequestID(r, "git create branch %s: ok=%t", body.Name, res.OK)
+		writeGitResult(w, res)
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// gitCheckoutHandler switches to an existing branch, tag or commit.
+func gitCheckoutHandler(w http.ResponseWriter, r *http.Request) {
+	if !gitPreamble(w, r, http.MethodPost) {
+		return
+	}
+	var body struct {
+		Ref string `json:"ref"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", 400)
+		return
+	}
+	if body.Ref == "" || strings.HasPrefix(body.Ref, "-") {
+		http.Error(w, "invalid ref", 400)
+		return
+	}
+	res := gitCommandResult(r.Context(), gitTimeout, "checkout", body.Ref, "--")
+	logWithRequestID(r, "git checkout %s: ok=%t", body.Ref, res.OK)
+	writeGitResult(w, res)
+}
+
+type gitRemoteRequest struct {
+	Remote      string `json:"remote"`
+	Branch      string `json:"branch"`
+	SetUpstream bool   `json:"setUpstream"`
+	Rebase      bool   `json:"rebase"`
+	Force       bool   `json:"force"` // push only; uses --force-with-lease
+}
+
+func decodeGitRemote(w http.ResponseWriter, r *http.Request) (gitRemoteRequest, bool) {
+	var body gitRemoteRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", 400)
+			return body, false
+		}
+	}
+	if body.Remote == "" {
+		body.Remote = "origin"
+	}
+	if strings.HasPrefix(body.Remote, "-") || strings.HasPrefix(body.Branch, "-") {
+		http.Error(w, "invalid remote or branch", 400)
+		return body, false
+	}
+	return body, true
+}
+
+// gitRemoteResult runs a fetch, pull or push for a request. Those can
+// outlive the server's default write timeout, so the deadline is moved past
+// gitRemoteTimeout first.
+func gitRemoteResult(w http.ResponseWriter, r *http.Request, args ...string) gitResult {
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(gitRemoteTimeout + 5*time.Second))
+	return gitCommandResult(r.Context(), gitRemoteTimeout, args...)
+}
+
+// gitFetchHandler fetches from a remote.
+func gitFetchHandler(w http.ResponseWriter, r *http.Request) {
+	if !gitPreamble(w, r, http.MethodPost) {
+		return
+	}
+	body, ok := decodeGitRemote(w, r)
+	if !ok {
+		return
+	}
+	res := gitRemoteResult(w, r, "fetch", "--prune", body.Remote)
+	logWithRequestID(r, "git fetch %s: ok=%t", body.Remote, res.OK)
+	writeGitResult(w, res)
+}
+
+// gitPullHandler pulls (merge by default, rebase=true to rebase).
+func gitPullHandler(w http.ResponseWriter, r *http.Request) {
+	if !gitPreamble(w, r, http.MethodPost) {
+		return
+	}
+	body, ok := decodeGitRemote(w, r)
+	if !ok {
+		return
+	}
+	args := []string{"pull", "--no-edit"}
+	if body.Rebase {
+		args = append(args, "--rebase")
+	} else {
+		args = append(args, "--no-rebase")
+	}
+	args = append(args, body.Remote)
+	if body.Branch != "" {
+		args = append(args, body.Branch)
+	}
+	res := gitRemoteResult(w, r, args...)
+	logWithRequestID(r, "git pull %s %s: ok=%t", body.Remote, body.Branch, res.OK)
+	writeGitResult(w, res)
+}
+
+// gitPushHandler pushes the current (or given) branch.
+func gitPushHandler(w http.ResponseWriter, r *http.Request) {
+	if !gitPreamble(w, r, http.MethodPost) {
+		return
+	}
+	body, ok := decodeGitRemote(w, r)
+	if !ok {
+		return
+	}
+	args := []string{"push"}
+	if body.SetUpstream {
+		args = append(args, "--set-upstream")
+	}
+	if body.Force {
+		args = append(args, "--force-with-lease")
+	}
+	branch := body.Branch
+	if branch == "" {
+		branch = "HEAD"
+	}
+	args = append(args, body.Remote, branch)
+	res := gitRemoteResult(w, r, args...)
+	logWithRequestID(r, "git push %s %s: ok=%t", body.Remote, branch, res.OK)
+	writeGitResult(w, res)
+}
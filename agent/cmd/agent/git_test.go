@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	const h = "61780798228d17af2d34fce4cfbdf35556832472"
+	tests := []struct {
+		name    string
+		records []string
+		want    gitStatus
+	}{
+		{
+			name: "clean branch with upstream",
+			records: []string{
+				"# branch.oid 094dba03",
+				"# branch.head main",
+				"# branch.upstream origin/main",
+				"# branch.ab +2 -1",
+			},
+			want: gitStatus{Branch: "main", Commit: "094dba03", Upstream: "origin/main", Ahead: 2, Behind: 1, Files: []gitFileStatus{}},
+		},
+		{
+			name: "changed files",
+			records: []string{
+				"# branch.oid 094dba03",
+				"# branch.head main",
+				"1 .M N... 100644 100644 100644 " + h + " " + h + " m.go",
+				"1 A. N... 000000 100644 100644 0000000000000000000000000000000000000000 " + h + " new file.txt",
+				"1 MD N... 100644 100644 000000 " + h + " " + h + " gone.go",
+				"2 R. N... 100644 100644 100644 " + h + " " + h + " R100 new.go",
+				"old.go",
+				"u UU N... 100644 100644 100644 100644 " + h + " " + h + " " + h + " conflict.go",
+				"? untracked.txt",
+			},
+			want: gitStatus{Branch: "main", Commit: "094dba03", Files: []gitFileStatus{
+				{Path: "m.go", Index: ".", Worktree: "M"},
+				{Path: "new file.txt", Index: "A", Worktree: ".", Staged: true},
+				{Path: "gone.go", Index: "M", Worktree: "D", Staged: true},
+				{Path: "new.go", OrigPath: "old.go", Index: "R", Worktree: ".", Staged: true},
+				{Path: "conflict.go", Index: "U", Worktree: "U", Staged: true, Conflict: true},
+				{Path: "untracked.txt", Index: "?", Worktree: "?", Untracked: true},
+			}},
+		},
+		{
+			name: "detached head before first commit",
+			records: []string{
+				"# branch.oid (initial)",
+				"# branch.head (detached)",
+			},
+			want: gitStatus{Branch: "(detached)", Commit: "(initial)", Detached: true, Files: []gitFileStatus{}},
+		},
+		{
+			name: "paths with spaces and a rename at the end",
+			records: []string{
+				"# branch.head dev",
+				"2 C. N... 100644 100644 100644 " + h + " " + h + " C75 docs/a copy.md",
+				"docs/a.md",
+			},
+			want: gitStatus{Branch: "dev", Files: []gitFileStatus{
+				{Path: "docs/a copy.md", OrigPath: "docs/a.md", Index: "C", Worktree: ".", Staged: true},
+			}},
+		},
+		{
+			name:    "truncated records are skipped",
+			records: []string{"# branch.head main", "1 .M N... 100644", "2 R. N... 100644 100644"},
+			want:    gitStatus{Branch: "main", Files: []gitFileStatus{}},
+		},
+	}
+	for _, tt := range tests {
+		got := parseGitStatus(strings.Join(tt.records, "\x00") + "\x00")
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGitStageSkipsDeniedFiles(t *testing.T) {
+	if _, err := os.Stat("/workspace/.git"); err != nil {
+		t.Skip("/workspace is not a git repository")
+	}
+	setTestReady(t)
+	saved := cfg.FileDenylist
+	defer func() { cfg.FileDenylist = saved }()
+	cfg.FileDenylist = parseDenylist(defaultFileDenylist)
+
+	dir := testWorkspaceDir(t)
+	rel := strings.TrimPrefix(dir, "/workspace/")
+	t.Cleanup(func() { runGit(context.Background(), "rm", "-r", "-q", "--cached", "--ignore-unmatch", "--", rel) })
+	for _, name := range []string{"main.go", ".env", "certs/server.pem", "certs/README"} {
+		mustMkdir(t, filepath.Dir(filepath.Join(dir, name)))
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"paths": ["` + rel + `/.env"]}`, 403},
+		{`{"paths": ["` + rel + `"]}`, 200},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		gitStageHandler(rec, httptest.NewRequest(http.MethodPost, "/git/stage", strings.NewReader(tt.body)))
+		if rec.Code != tt.want {
+			t.Errorf("stage %s: status = %d, want %d (%s)", tt.body, rec.Code, tt.want, rec.Body)
+		}
+	}
+
+	staged, _, err := runGit(context.Background(), "diff", "--cached", "--name-only", "--", rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Fields(strings.ReplaceAll(staged, rel+"/", ""))
+	want := []string{"certs/README", "main.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("staged %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultListLimit = 200
+	maxListLimit     = 1000
+	maxListDepth     = 5
+)
+
+type pagedListing struct {
+	Path       string      `json:"path"`
+	Nodes      []*FileNode `json:"nodes"`
+	Total      int         `json:"total"`
+	NextCursor string      `json:"nextCursor,omitempty"`
+}
+
+type listOptions struct {
+	depth       int
+	showIgnored bool
+	sniffMime   bool
+}
+
+// pagedListHandler lists one directory (optionally a few levels deep) with
+// cursor pagination over its direct children. Ignored files are hidden unless
+// showIgnored=true and MIME types come from the extension unless mime=sniff.
+func pagedListHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	rel := strings.Trim(q.Get("path"), "/")
+	dir := "/workspace"
+	if rel != "" {
+		fullPath, err := resolveWorkspacePath(rel)
+		if err != nil {
+			pathError(w, r, rel, err)
+			return
+		}
+		dir = fullPath
+	}
+
+	opts := listOptions{
+		depth:       1,
+		showIgnored: q.Get("showIgnored") == "true",
+		sniffMime:   q.Get("mime") == "sniff",
+	}
+	if v := q.Get("depth"); v != "" {
+		d, err := strconv.Atoi(v)
+		if err != nil || d < 1 {
+			http.Error(w, "invalid depth", 400)
+			return
+		}
+		opts.depth = min(d, maxListDepth)
+	}
+	limit := defaultListLimit
+	if v := q.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 {
+			http.Error(w, "invalid limit", 400)
+			return
+		}
+		limit = min(l, maxListLimit)
+	}
+	after := ""
+	if v := q.Get("cursor"); v != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil {
+			http.Error(w, "invalid cursor", 400)
+			return
+		}
+		after = string(decoded)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "directory not found", 404)
+		} else {
+			http.Error(w, err.Error(), 500)
+		}
+		return
+	}
+	if !info.IsDir() {
+		http.Error(w, "path is not a directory", 400)
+		return
+	}
+
+	ignores := newIgnoreChecker()
+	defer ignores.close()
+	entries, err := listEntries(dir, info.ModTime(), opts, ignores)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	start := 0
+	if after != "" {
+		start = sort.Search(len(entries), func(i int) bool { return listSortKey(entries[i]) > after })
+	}
+	end := min(start+limit, len(entries))
+	page := entries[start:end]
+
+	result := pagedListing{
+		Path:  strings.TrimPrefix(dir, "/workspace"),
+		Nodes: make([]*FileNode, 0, len(page)),
+		Total: len(entries),
+	}
+	if result.Path == "" {
+		result.Path = "/"
+	}
+	for _, entry := range page {
+		result.Nodes = append(result.Nodes, buildListNode(dir, entry, opts, ignores, 1))
+	}
+	if end < len(entries) {
+		result.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(listSortKey(entries[end-1])))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// listEntry pairs a directory entry with its gitignore status.
+type listEntry struct {
+	os.DirEntry
+	ignored bool
+}
+
+// listSortKey orders directories before files, then by name.
+func listSortKey(e listEntry) string {
+	if e.IsDir() {
+		return "0" + e.Name()
+	}
+	return "1" + e.Name()
+}
+
+// listSnapshot is a directory's filtered, sorted entries, kept briefly so
+// paging through a large directory doesn't re-read it for every page.
+type listSnapshot struct {
+	entries []listEntry
+	modTime time.Time
+	expires time.Time
+}
+
+type listCacheKey struct {
+	dir         string
+	showIgnored bool
+}
+
+const (
+	listCacheTTL  = 30 * time.Second
+	maxListCached = 64
+)
+
+var (
+	listCacheMu sync.Mutex
+	listCache   = map[listCacheKey]*listSnapshot{}
+)
+
+// listEntries returns readListEntries for dir, reusing a recent snapshot
+// while the directory's modification time is unchanged.
+func listEntries(dir string, modTime time.Time, opts listOptions, ignores *ignoreChecker) ([]listEntry, error) {
+	key := listCacheKey{dir: dir, showIgnored: opts.showIgnored}
+	now := time.Now()
+	listCacheMu.Lock()
+	snap := listCache[key]
+	listCacheMu.Unlock()
+	if snap != nil && snap.modTime.Equal(modTime) && now.Before(snap.expires) {
+		return snap.entries, nil
+	}
+
+	entries, err := readListEntries(dir, opts, ignores)
+	if err != nil {
+		return nil, err
+	}
+	listCacheMu.Lock()
+	defer listCacheMu.Unlock()
+	if len(listCache) >= maxListCached {
+		for k, s := range listCache {
+			if now.After(s.expires) {
+				delete(listCache, k)
+			}
+		}
+		// Still full: drop an arbitrary entry
+		for k := range listCache {
+			if len(listCache) < maxListCached {
+				break
+			}
+			delete(listCache, k)
+		}
+	}
+	listCache[key] = &listSnapshot{entries: entries, modTime: modTime, expires: now.Add(listCacheTTL)}
+	return entries, nil
+}
+
+// readListEntries reads dir, drops .git, denied and (unless requested)
+// ignored entries, and returns the rest in listing order.
+func readListEntries(dir string, opts listOptions, ignores *ignoreChecker) ([]listEntry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []os.DirEntry
+	var relPaths []string
+	for _, entry := range dirEntries {
+		rel := strings.TrimPrefix(filepath.Join(dir, entry.Name()), "/workspace/")
+		if entry.Name() == ".git" || pathDenied(rel) {
+			continue
+		}
+		candidates = append(candidates, entry)
+		if entry.IsDir() {
+			rel += "/"
+		}
+		relPaths = append(relPaths, rel)
+	}
+	ignored := ignores.check(relPaths)
+
+	entries := make([]listEntry, 0, len(candidates))
+	for i, entry := range candidates {
+		isIgnored := ignored[relPaths[i]]
+		if isIgnored && !opts.showIgnored {
+			continue
+		}
+		entries = append(entries, listEntry{DirEntry: entry, ignored: isIgnored})
+	}
+	sort.Slice(entries, func(i, j int) bool { return listSortKey(entries[i]) < listSortKey(entries[j]) })
+	return entries, nil
+}
+
+func buildListNode(dir string, entry listEntry, opts listOptions, ignores *ignoreChecker, level int) *FileNode {
+	fullPath := filepath.Join(dir, entry.Name())
+	node := &FileNode{
+		Name:      entry.Name(),
+		IsDir:     entry.IsDir(),
+		Path:      strings.TrimPrefix(fullPath, "/workspace"),
+		Extension: filepath.Ext(entry.Name()),
+		Ignored:   entry.ignored,
+	}
+	if info, err := entry.Info(); err == nil {
+		node.Size = info.Size()
+		node.ModTime = info.ModTime()
+		node.Permissions = info.Mode().String()
+	}
+
+	if !node.IsDir {
+		if opts.sniffMime {
+			node.MimeType = getMimeType(fullPath)
+		} else {
+			node.MimeType = mime.TypeByExtension(node.Extension)
+		}
+		return node
+	}
+
+	if level < opts.depth {
+		children, err := listEntries(fullPath, node.ModTime, opts, ignores)
+		if err == nil {
+			for _, child := range children {
+				node.Nodes = append(node.Nodes, buildListNode(fullPath, child, opts, ignores, level+1))
+			}
+			node.HasChildren = len(children) > 0
+		}
+		return node
+	}
+	node.HasChildren = dirHasEntries(fullPath)
+	return node
+}
+
+// dirHasEntries reports whether dir contains anything, reading one entry.
+func dirHasEntries(dir string) bool {
+	f, err := os.Open(dir)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	_, err = f.Readdirnames(1)
+	return err == nil
+}
+
+// gitCheckIgnore asks git which of the workspace-relative paths are ignored
+// by .gitignore, .git/info/exclude or the global excludes file. Directory
+// paths should carry a trailing slash. Outside a git repo nothing is ignored.
+func gitCheckIgnore(paths []string) map[string]bool {
+	ignores := newIgnoreChecker()
+	defer ignores.close()
+	return ignores.check(paths)
+}
+
+// ignoreChecker answers gitCheckIgnore queries from one git check-ignore
+// process, started on first use, so a request that lists several levels
+// spawns git once rather than once per directory.
+type ignoreChecker struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	out    *bufio.Reader
+	failed bool
+}
+
+func newIgnoreChecker() *ignoreChecker {
+	return &ignoreChecker{}
+}
+
+func (ic *ignoreChecker) start() error {
+	// Verbose, non-matching output answers every path, so each batch can be
+	// read back without closing stdin; GIT_FLUSH stops git buffering it.
+	cmd := exec.Command("git", "check-ignore", "--stdin", "-z", "--verbose", "--non-matching")
+	cmd.Dir = "/workspace"
+	cmd.Env = append(os.Environ(), "GIT_FLUSH=1")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	ic.cmd, ic.stdin, ic.out = cmd, stdin, bufio.NewReader(stdout)
+	return nil
+}
+
+// check reports which of paths are ignored. After any failure, such as
+// /workspace not being a repository, nothing is.
+func (ic *ignoreChecker) check(paths []string) map[string]bool {
+	ignored := map[string]bool{}
+	if len(paths) == 0 || ic.failed {
+		return ignored
+	}
+	if ic.cmd == nil {
+		if err := ic.start(); err != nil {
+			ic.failed = true
+			return ignored
+		}
+	}
+
+	// Write from a goroutine so a large batch can't fill both pipes
+	go func() {
+		for _, p := range paths {
+			if _, err := io.WriteString(ic.stdin, p+"\x00"); err != nil {
+				return
+			}
+		}
+	}()
+	// Each answer is source, line number, pattern and path. Paths matched
+	// by nothing have an empty source; a "!" pattern re-includes the path.
+	for range paths {
+		var fields [4]string
+		for i := range fields {
+			field, err := ic.out.ReadString(0)
+			if err != nil {
+				ic.failed = true
+				ic.close()
+				return map[string]bool{}
+			}
+			fields[i] = strings.TrimSuffix(field, "\x00")
+		}
+		if fields[0] != "" && !strings.HasPrefix(fields[2], "!") {
+			ignored[fields[3]] = true
+		}
+	}
+	return ignored
+}
+
+func (ic *ignoreChecker) close() {
+	if ic.cmd == nil {
+		return
+	}
+	// Exit status 1 just means nothing matched, and outside a repo git
+	// fails; neither is worth reporting
+	ic.stdin.Close()
+	_ = ic.cmd.Wait()
+	ic.cmd = nil
+}
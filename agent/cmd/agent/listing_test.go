@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPagedListCursor(t *testing.T) {
+	setTestReady(t)
+	dir := testWorkspaceDir(t)
+	rel := strings.TrimPrefix(dir, "/workspace/")
+	for _, name := range []string{"b", "a", "c"} {
+		mustMkdir(t, filepath.Join(dir, name))
+	}
+	for _, name := range []string{"z.go", "a.go", "m.go", "b.go", "y.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		page := getListing(t, "path="+rel+"&limit=3&cursor="+cursor)
+		wantTotal := 8
+		if pages > 0 {
+			wantTotal = 9
+		}
+		if page.Total != wantTotal {
+			t.Errorf("page %d: total = %d, want %d", pages+1, page.Total, wantTotal)
+		}
+		if len(page.Nodes) > 3 {
+			t.Errorf("page has %d nodes, limit is 3", len(page.Nodes))
+		}
+		for _, n := range page.Nodes {
+			names = append(names, n.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+		if pages == 0 {
+			// An entry added before the cursor doesn't shift later pages
+			mustMkdir(t, filepath.Join(dir, "0"))
+		}
+	}
+	want := []string{"a", "b", "c", "a.go", "b.go", "m.go", "y.go", "z.go"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("pages listed %v, want %v", names, want)
+	}
+}
+
+func TestPagedListFiltering(t *testing.T) {
+	if _, err := os.Stat("/workspace/.git"); err != nil {
+		t.Skip("/workspace is not a git repository")
+	}
+	setTestReady(t)
+	saved := cfg.FileDenylist
+	defer func() { cfg.FileDenylist = saved }()
+	cfg.FileDenylist = parseDenylist(defaultFileDenylist)
+
+	dir := testWorkspaceDir(t)
+	rel := strings.TrimPrefix(dir, "/workspace/")
+	files := map[string]string{
+		".gitignore":          "*.log\nnode_modules/\n!keep.log\n",
+		"app.log":             "",
+		"keep.log":            "",
+		".env":                "SECRET=1",
+		"main.go":             "",
+		"src/util.go":         "",
+		"src/debug.log":       "",
+		"node_modules/x/a.js": "",
+	}
+	for name, content := range files {
+		mustMkdir(t, filepath.Dir(filepath.Join(dir, name)))
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"/src", "/.gitignore", "/keep.log", "/main.go"}},
+		{"&depth=2", []string{"/src", "/src/util.go", "/.gitignore", "/keep.log", "/main.go"}},
+		{"&showIgnored=true", []string{"/node_modules*", "/src", "/.gitignore", "/app.log*", "/keep.log", "/main.go"}},
+		{"&showIgnored=true&depth=3", []string{
+			"/node_modules*", "/node_modules/x*", "/node_modules/x/a.js*",
+			"/src", "/src/debug.log*", "/src/util.go",
+			"/.gitignore", "/app.log*", "/keep.log", "/main.go",
+		}},
+	}
+	for _, tt := range tests {
+		page := getListing(t, "path="+rel+tt.query)
+		var got []string
+		var walk func(nodes []*FileNode)
+		walk = func(nodes []*FileNode) {
+			for _, n := range nodes {
+				p := strings.TrimPrefix(n.Path, "/"+rel)
+				if n.Ignored {
+					p += "*"
+				}
+				got = append(got, p)
+				walk(n.Nodes)
+			}
+		}
+		walk(page.Nodes)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("query %q listed %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPagedListErrors(t *testing.T) {
+	setTestReady(t)
+	dir := testWorkspaceDir(t)
+	rel := strings.TrimPrefix(dir, "/workspace/")
+	if err := os.WriteFile(filepath.Join(dir, "f.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"path=" + rel + "&cursor=***", 400},
+		{"path=" + rel + "&limit=0", 400},
+		{"path=" + rel + "&depth=x", 400},
+		{"path=" + rel + "/f.txt", 400},
+		{"path=" + rel + "/missing", 404},
+		{"path=../etc", 400},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		pagedListHandler(rec, httptest.NewRequest(http.MethodGet, "/files?"+tt.query, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.query, rec.Code, tt.want, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestIgnoreChecker(t *testing.T) {
+	if _, err := os.Stat("/workspace/.git"); err != nil {
+		t.Skip("/workspace is not a git repository")
+	}
+	dir := testWorkspaceDir(t)
+	rel := strings.TrimPrefix(dir, "/workspace/")
+	if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.log\n!keep.log\nbuild/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ic := newIgnoreChecker()
+	defer ic.close()
+	// Several batches are answered by the same process
+	batches := []struct {
+		paths []string
+		want  []string
+	}{
+		{[]string{"a.log", "a.go", "keep.log"}, []string{"a.log"}},
+		{[]string{"build/", "build/out/", "src/"}, []string{"build/", "build/out/"}},
+		{nil, nil},
+	}
+	for _, b := range batches {
+		var paths []string
+		for _, p := range b.paths {
+			paths = append(paths, rel+"/"+p)
+		}
+		got := ic.check(paths)
+		want := map[string]bool{}
+		for _, p := range b.want {
+			want[rel+"/"+p] = true
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("check(%v) = %v, want %v", b.paths, got, want)
+		}
+	}
+	if ic.failed {
+		t.Error("checker failed")
+	}
+}
+
+func TestListEntriesSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	list := func() []listEntry {
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ignores := newIgnoreChecker()
+		defer ignores.close()
+		entries, err := listEntries(dir, info.ModTime(), listOptions{}, ignores)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+
+	first := list()
+	if second := list(); &second[0] != &first[0] {
+		t.Error("unchanged directory was read again")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if third := list(); len(third) != 2 {
+		t.Errorf("changed directory lists %d entries, want 2", len(third))
+	}
+}
+
+func getListing(t *testing.T, query string) pagedListing {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	pagedListHandler(rec, httptest.NewRequest(http.MethodGet, "/files?"+query, nil))
+	if rec.Code != 200 {
+		t.Fatalf("list %s: status %d: %s", query, rec.Code, rec.Body)
+	}
+	var page pagedListing
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
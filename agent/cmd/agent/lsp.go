@@ -0,0 +1,496 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Upper bound on concurrently running language servers
+	maxLanguageServers = 8
+	// Largest JSON-RPC message accepted from a server or a client
+	lspMaxMessage = 32 << 20 // 32MB
+	// Time a server gets to exit after its input closes, then after SIGTERM
+	lspExitGrace  = 2 * time.Second
+	lspKillGrace  = 5 * time.Second
+	workspaceRoot = "file:///workspace"
+)
+
+// Language servers used when the workspace config doesn't name one. They
+// have to be installed in the workspace image.
+var defaultLanguageServers = map[string][]string{
+	"go":         {"gopls"},
+	"typescript": {"typescript-language-server", "--stdio"},
+	"javascript": {"typescript-language-server", "--stdio"},
+	"python":     {"pyright-langserver", "--stdio"},
+	"rust":       {"rust-analyzer"},
+	"c":          {"clangd"},
+	"cpp":        {"clangd"},
+}
+
+var (
+	errTooManyLanguageServers = errors.New("too many language servers")
+
+	lspMu      sync.Mutex
+	lspServers = map[string]*languageServer{}
+)
+
+// languageServer is a server process bridged to one WebSocket client. LSP
+// sessions are stateful, so every connection gets its own process.
+type languageServer struct {
+	ID        string    `json:"id"`
+	Language  string    `json:"language"`
+	Command   string    `json:"command"`
+	Pid       int       `json:"pid"`
+	StartedAt time.Time `json:"startedAt"`
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdoutR *os.File
+	stdout  *bufio.Reader
+	exited  chan struct{}
+}
+
+func validLanguage(lang string) bool {
+	if lang == "" || len(lang) > 32 {
+		return false
+	}
+	for _, c := range lang {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '+') {
+			return false
+		}
+	}
+	return true
+}
+
+// languageServerCommand returns the command line for a language, from the
+// workspace config first. ok is false for languages with no server.
+func languageServerCommand(lang string) ([]string, bool) {
+	if command, ok := currentWorkspaceConfig().LanguageServers[lang]; ok {
+		return []string{"/bin/sh", "-c", command}, true
+	}
+	args, ok := defaultLanguageServers[lang]
+	return args, ok
+}
+
+type languageInfo struct {
+	Language  string `json:"language"`
+	Command   string `json:"command"`
+	Available bool   `json:"available"`
+}
+
+func listLanguages() []languageInfo {
+	langs := map[string]bool{}
+	for lang := range defaultLanguageServers {
+		langs[lang] = true
+	}
+	for lang := range currentWorkspaceConfig().LanguageServers {
+		langs[lang] = true
+	}
+	list := make([]languageInfo, 0, len(langs))
+	for lang := range langs {
+		args, _ := languageServerCommand(lang)
+		_, err := exec.LookPath(args[0])
+		list = append(list, languageInfo{
+			Language:  lang,
+			Command:   commandString(args),
+			Available: err == nil,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Language < list[j].Language })
+	return list
+}
+
+func commandString(args []string) string {
+	if len(args) == 3 && args[0] == "/bin/sh" && args[1] == "-c" {
+		return args[2]
+	}
+	return strings.Join(args, " ")
+}
+
+func startLanguageServer(lang string, args []string) (*languageServer, error) {
+	lspMu.Lock()
+	defer lspMu.Unlock()
+	if len(lspServers) >= maxLanguageServers {
+		return nil, errTooManyLanguageServers
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = "/workspace"
+	cmd.Env = workspaceEnviron()
+	// Own process group so stopping reaches helpers the server spawned
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Stderr = lspLogWriter{lang}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	// Not StdoutPipe: Wait would close it while the bridge is still reading
+	// the server's last messages. This pipe is read to EOF and closed by stop.
+	stdout, stdoutW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = stdoutW
+	err = cmd.Start()
+	stdoutW.Close()
+	if err != nil {
+		stdout.Close()
+		return nil, err
+	}
+
+	ls := &languageServer{
+		ID:        generateRequestID(),
+		Language:  lang,
+		Command:   commandString(args),
+		Pid:       cmd.Process.Pid,
+		StartedAt: time.Now(),
+		cmd:       cmd,
+		stdin:     stdin,
+		stdoutR:   stdout,
+		stdout:    bufio.NewReader(stdout),
+		exited:    make(chan struct{}),
+	}
+	lspServers[ls.ID] = ls
+	go func() {
+		err := cmd.Wait()
+		lspMu.Lock()
+		delete(lspServers, ls.ID)
+		lspMu.Unlock()
+		close(ls.exited)
+		log.Printf("Language server %s (%s) exited: %v", ls.ID, lang, err)
+	}()
+	log.Printf("Language server %s (%s) started: %s", ls.ID, lang, ls.Command)
+	return ls, nil
+}
+
+// stop closes the server's input, which well-behaved servers treat as exit,
+// then signals its process group. Its output is closed last, which also
+// unblocks a reader if a leftover child still holds the pipe open.
+func (ls *languageServer) stop() {
+	defer ls.stdoutR.Close()
+	_ = ls.stdin.Close()
+	select {
+	case <-ls.exited:
+		return
+	case <-time.After(lspExitGrace):
+	}
+	pgid := -ls.cmd.Process.Pid
+	_ = syscall.Kill(pgid, syscall.SIGTERM)
+	select {
+	case <-ls.exited:
+	case <-time.After(lspKillGrace):
+		_ = syscall.Kill(pgid, syscall.SIGKILL)
+		<-ls.exited
+	}
+}
+
+func listLanguageServers() []languageServer {
+	lspMu.Lock()
+	defer lspMu.Unlock()
+	list := make([]languageServer, 0, len(lspServers))
+	for _, ls := range lspServers {
+		list = append(list, languageServer{
+			ID:        ls.ID,
+			Language:  ls.Language,
+			Command:   ls.Command,
+			Pid:       ls.Pid,
+			StartedAt: ls.StartedAt,
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	return list
+}
+
+func stopAllLanguageServers() {
+	lspMu.Lock()
+	list := make([]*languageServer, 0, len(lspServers))
+	for _, ls := range lspServers {
+		list = append(list, ls)
+	}
+	lspMu.Unlock()
+	var wg sync.WaitGroup
+	for _, ls := range list {
+		wg.Add(1)
+		go func(ls *languageServer) {
+			defer wg.Done()
+			ls.stop()
+		}(ls)
+	}
+	wg.Wait()
+}
+
+// lspLogWriter sends a server's stderr to the agent log.
+type lspLogWriter struct{ lang string }
+
+func (w lspLogWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		log.Printf("[lsp:%s] %s", w.lang, line)
+	}
+	return len(p), nil
+}
+
+// readLSPMessage reads one base-protocol message: headers, a blank line and
+// a Content-Length body.
+func readLSPMessage(r *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 || length > lspMaxMessage {
+		return nil, fmt.Errorf("invalid message length %d", length)
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(r, body)
+	return body, err
+}
+
+func writeLSPMessage(w io.Writer, body []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// uriMapper translates between the URIs the client uses for the project
+// and file:///workspace, where the server sees it. The client's root comes
+// from its initialize request.
+type uriMapper struct {
+	mu         sync.RWMutex
+	clientRoot string
+}
+
+func (m *uriMapper) root() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.clientRoot
+}
+
+// fromClient rewrites a client message for the server. The initialize
+// request's roots are pointed at /workspace whatever the client sent.
+func (m *uriMapper) fromClient(msg []byte) ([]byte, error) {
+	if !bytes.Contains(msg, []byte(`"initialize"`)) {
+		return rewriteURIs(msg, m.root(), workspaceRoot)
+	}
+	v, err := decodeJSON(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, ok := v.(map[string]interface{})
+	if !ok || req["method"] != "initialize" {
+		return rewriteURIs(msg, m.root(), workspaceRoot)
+	}
+	params, _ := req["params"].(map[string]interface{})
+	if params == nil {
+		params = map[string]interface{}{}
+		req["params"] = params
+	}
+	if root, _ := params["rootUri"].(string); root != "" && root != workspaceRoot {
+		m.mu.Lock()
+		m.clientRoot = strings.TrimSuffix(root, "/")
+		m.mu.Unlock()
+	}
+	params["rootUri"] = workspaceRoot
+	params["rootPath"] = "/workspace"
+	params["workspaceFolders"] = []map[string]string{{"uri": workspaceRoot, "name": "workspace"}}
+	return encodeJSON(mapURIs(req, m.root(), workspaceRoot))
+}
+
+func (m *uriMapper) fromServer(msg []byte) ([]byte, error) {
+	return rewriteURIs(msg, workspaceRoot, m.root())
+}
+
+// rewriteURIs replaces the from prefix of every string in a JSON message
+// that is from itself or a path under it.
+func rewriteURIs(msg []byte, from, to string) ([]byte, error) {
+	if from == "" || to == "" || from == to || !bytes.Contains(msg, []byte(from)) {
+		return msg, nil
+	}
+	v, err := decodeJSON(msg)
+	if err != nil {
+		return nil, err
+	}
+	return encodeJSON(mapURIs(v, from, to))
+}
+
+func mapURIs(v interface{}, from, to string) interface{} {
+	if from == "" || to == "" || from == to {
+		return v
+	}
+	switch v := v.(type) {
+	case string:
+		if rest, ok := strings.CutPrefix(v, from); ok && (rest == "" || rest[0] == '/') {
+			return to + rest
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = mapURIs(item, from, to)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = mapURIs(item, from, to)
+		}
+	}
+	return v
+}
+
+// decodeJSON keeps numbers as written, so request IDs and positions
+// survive the round trip exactly.
+func decodeJSON(msg []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(msg))
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+func encodeJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// lspServersHandler lists the languages with a server and the running
+// servers.
+func lspServersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"languages": listLanguages(),
+		"running":   listLanguageServers(),
+	})
+}
+
+// lspHandler starts a language server for ?language= and bridges it to the
+// WebSocket, one JSON-RPC message per text frame. The server stops when the
+// client disconnects.
+func lspHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	lang := r.URL.Query().Get("language")
+	if !validLanguage(lang) {
+		http.Error(w, "invalid language", 400)
+		return
+	}
+	args, ok := languageServerCommand(lang)
+	if !ok {
+		http.Error(w, "no language server for "+lang, 404)
+		return
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		http.Error(w, "language server not installed: "+args[0], http.StatusNotImplemented)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logWithRequestID(r, "WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(lspMaxMessage)
+
+	ls, err := startLanguageServer(lang, args)
+	if err != nil {
+		logWithRequestID(r, "Failed to start %s language server: %v", lang, err)
+		code := websocket.CloseInternalServerErr
+		if errors.Is(err, errTooManyLanguageServers) {
+			code = websocket.CloseTryAgainLater
+		}
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, err.Error()))
+		return
+	}
+	defer ls.stop()
+	logWithRequestID(r, "Bridging %s language server %s", lang, ls.ID)
+
+	mapper := &uriMapper{}
+
+	// Server to client; the only writer of data frames
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		for {
+			msg, err := readLSPMessage(ls.stdout)
+			if err != nil {
+				if err != io.EOF {
+					logWithRequestID(r, "Language server %s read error: %v", ls.ID, err)
+				}
+				return
+			}
+			if msg, err = mapper.fromServer(msg); err != nil {
+				logWithRequestID(r, "Language server %s sent invalid JSON: %v", ls.ID, err)
+				continue
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Client to server
+	clientDone := make(chan struct{})
+	go func() {
+		defer close(clientDone)
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if msg, err = mapper.fromClient(msg); err != nil {
+				logWithRequestID(r, "Dropping invalid LSP message from client: %v", err)
+				continue
+			}
+			if err := writeLSPMessage(ls.stdin, msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-clientDone:
+		logWithRequestID(r, "LSP client for %s disconnected", ls.ID)
+	case <-serverDone:
+		// Let the client know the server is gone rather than leave it hanging
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "language server exited"))
+	}
+}
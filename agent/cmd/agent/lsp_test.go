@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadLSPMessage(t *testing.T) {
+	input := "Content-Length: 2\r\n\r\n{}" +
+		"content-length:  7\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n{\"a\":1}" +
+		"Content-Length: 3\n\n[1]"
+	r := bufio.NewReader(strings.NewReader(input))
+	for _, want := range []string{"{}", `{"a":1}`, "[1]"} {
+		got, err := readLSPMessage(r)
+		if err != nil {
+			t.Fatalf("readLSPMessage: %v", err)
+		}
+		if string(got) != want {
+			t.Errorf("readLSPMessage = %q, want %q", got, want)
+		}
+	}
+	if _, err := readLSPMessage(r); err != io.EOF {
+		t.Errorf("readLSPMessage at end = %v, want EOF", err)
+	}
+}
+
+func TestReadLSPMessageErrors(t *testing.T) {
+	tests := map[string]string{
+		"no length":        "Content-Type: x\r\n\r\n{}",
+		"bad length":       "Content-Length: two\r\n\r\n{}",
+		"negative length":  "Content-Length: -1\r\n\r\n",
+		"too long":         "Content-Length: 999999999999\r\n\r\n",
+		"truncated body":   "Content-Length: 10\r\n\r\n{}",
+		"truncated header": "Content-Length: 2",
+	}
+	for name, input := range tests {
+		if _, err := readLSPMessage(bufio.NewReader(strings.NewReader(input))); err == nil {
+			t.Errorf("%s: readLSPMessage succeeded", name)
+		}
+	}
+}
+
+func TestWriteLSPMessageRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	body := []byte(`{"jsonrpc":"2.0","method":"initialized","params":{}}`)
+	if err := writeLSPMessage(&buf, body); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readLSPMessage(bufio.NewReader(&buf))
+	if err != nil || !bytes.Equal(got, body) {
+		t.Errorf("round trip = %q, %v", got, err)
+	}
+}
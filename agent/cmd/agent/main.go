@@ -1,29 +1,28 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"mime"
-	"net"
 	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/creack/pty"
 	"github.com/gorilla/websocket"
 )
 
@@ -35,49 +34,45 @@ type AppConfig struct {
 	GitToken      string
 	GitUser       string
 	GitEmail      string
+	// Branch, tag or commit, or pull request number, to check out after cloning
+	GitRef string
+	GitPR  string
+	// Clone strategy: history depth (0 = full), partial clone filter,
+	// sparse-checkout paths, and whether an existing clone in /workspace is
+	// reused rather than replaced
+	CloneDepth       int
+	CloneFilter      string
+	SparsePaths      []string
+	PersistWorkspace bool
+	// Auto-save policy and how often it runs
+	AutosavePolicy   string
+	AutosaveInterval time.Duration
+	// Where the shutdown push outcome, clone progress and listening ports
+	// are reported
+	SyncURL     string
+	ProgressURL string
+	PortsURL    string
+	// Where the keys that sign workspace tokens are published. AuthDisabled
+	// turns token checks off for local development.
+	JWKSURL      string
+	AuthDisabled bool
+	// Origins the IDE may be served from, shared with the gateway
+	AllowedOrigins []string
+	FileDenylist   []denyRule
 }
 
 var (
 	cfg      AppConfig
 	ready    bool
 	mu       sync.RWMutex
-	cloneLog bytes.Buffer
 	upgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			origin := r.Header.Get("Origin")
-			if origin == "" {
-				return false // No origin header, reject
-			}
-
-			// Allow localhost origins for development
-			if strings.HasPrefix(origin, "http://localhost:") ||
-				strings.HasPrefix(origin, "https://localhost:") ||
-				strings.HasPrefix(origin, "http://127.0.0.1:") ||
-				strings.HasPrefix(origin, "https://127.0.0.1:") {
-				return true
-			}
-
-			// In production, check against allowed origins
-			allowedOrigins := []string{
-				"http://localhost:3000", // Gateway default
-				"https://localhost:3000",
-			}
-
-			for _, allowed := range allowedOrigins {
-				if origin == allowed {
-					return true
-				}
-			}
-
-			return false
-		},
+		CheckOrigin: checkOrigin,
 	}
 	// Rate limiting: 100 requests per minute per IP
 	rateLimiter = make(map[string]*rateLimitInfo)
 	rateLimitMu sync.Mutex
-	// Port watcher synchronization
-	portWatcherMu sync.Mutex
-	knownPorts    = map[int]bool{}
+	// Serializes precondition checks and writes in fileSaveHandler
+	saveMu sync.Mutex
 )
 
 type rateLimitInfo struct {
@@ -85,11 +80,22 @@ type rateLimitInfo struct {
 	lastReset int64
 }
 
+// Routes that take larger bodies than the default
+var routeBodyLimits = map[string]int64{
+	"/files/raw":              maxUploadSize,
+	"/files/upload":           maxUploadChunk,
+	"/files/upload/multipart": maxUploadSize,
+}
+
 // Request size limiting middleware
 func limitBodySizeMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Limit request body to 10MB
-		r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // 10MB
+		// Limit request body to 10MB unless the route allows more
+		limit := int64(10 << 20) // 10MB
+		if l, ok := routeBodyLimits[r.URL.Path]; ok {
+			limit = l
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -176,7 +182,10 @@ func logWithRequestID(r *http.Request, format string, args ...interface{}) {
 
 func main() {
 	cfg = loadConfig()
-	log.Printf("Starting agent with config: AtlasID=%s, User=%s, Email=%s", cfg.AtlasID, cfg.GitUser, cfg.GitEmail)
+	log.Printf("Starting agent with config: AtlasID=%s, User=%s, Email=%s, Autosave=%s", cfg.AtlasID, cfg.GitUser, cfg.GitEmail, cfg.AutosavePolicy)
+	if cfg.AuthDisabled {
+		log.Printf("WARNING: AGENT_AUTH_DISABLED is set, requests are not authenticated")
+	}
 
 	go backgroundClone()
 	go portWatcher()
@@ -184,15 +193,50 @@ func main() {
 	// Apply middleware chain
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/clone/progress", cloneProgressHandler)
+	mux.HandleFunc("/workspace/config", workspaceConfigHandler)
+	mux.HandleFunc("/ports", portsHandler)
 	mux.HandleFunc("/terminal", terminalHandler)
+	mux.HandleFunc("/terminal/sessions", sessionsHandler)
+	mux.HandleFunc("/terminal/sessions/detach", sessionDetachHandler)
+	mux.HandleFunc("/terminal/sessions/kill", sessionKillHandler)
+	mux.HandleFunc("/tasks", tasksHandler)
+	mux.HandleFunc("/tasks/run", taskRunHandler)
+	mux.HandleFunc("/tasks/output", taskOutputHandler)
+	mux.HandleFunc("/tasks/stream", taskStreamHandler)
+	mux.HandleFunc("/tasks/cancel", taskCancelHandler)
+	mux.HandleFunc("/lsp", lspHandler)
+	mux.HandleFunc("/lsp/servers", lspServersHandler)
+	mux.HandleFunc("/debug", debugHandler)
+	mux.HandleFunc("/debug/adapters", debugAdaptersHandler)
 	mux.HandleFunc("/files", fileListHandler)
 	mux.HandleFunc("/files/content", fileContentHandler)
 	mux.HandleFunc("/files/save", fileSaveHandler)
+	mux.HandleFunc("/files/watch", fileWatchHandler)
+	mux.HandleFunc("/files/delete", fileDeleteHandler)
+	mux.HandleFunc("/files/rename", fileRenameHandler)
+	mux.HandleFunc("/files/copy", fileCopyHandler)
+	mux.HandleFunc("/files/mkdir", fileMkdirHandler)
+	mux.HandleFunc("/files/raw", fileRawHandler)
+	mux.HandleFunc("/files/upload", fileUploadHandler)
+	mux.HandleFunc("/files/upload/multipart", fileMultiUploadHandler)
+	mux.HandleFunc("/files/archive", fileArchiveHandler)
 	mux.HandleFunc("/files/", fileHandler)
-
-	handler := securityHeadersMiddleware(requestIDMiddleware(rateLimitMiddleware(limitBodySizeMiddleware(mux))))
-
-	go autoCommitLoop()
+	mux.HandleFunc("/search", searchHandler)
+	mux.HandleFunc("/git/status", gitStatusHandler)
+	mux.HandleFunc("/git/diff", gitDiffHandler)
+	mux.HandleFunc("/git/stage", gitStageHandler)
+	mux.HandleFunc("/git/unstage", gitUnstageHandler)
+	mux.HandleFunc("/git/commit", gitCommitHandler)
+	mux.HandleFunc("/git/branches", gitBranchesHandler)
+	mux.HandleFunc("/git/checkout", gitCheckoutHandler)
+	mux.HandleFunc("/git/fetch", gitFetchHandler)
+	mux.HandleFunc("/git/pull", gitPullHandler)
+	mux.HandleFunc("/git/push", gitPushHandler)
+
+	handler := securityHeadersMiddleware(requestIDMiddleware(corsMiddleware(rateLimitMiddleware(authMiddleware(limitBodySizeMiddleware(mux))))))
+
+	go autosaveLoop()
 
 	server := &http.Server{
 		Addr:           ":9000",
@@ -217,12 +261,33 @@ func main() {
 	gracefulShutdown()
 }
 
+// healthHandler answers "cloning", "setup", "ready", "setup_failed" or
+// "error" as plain text. Clients asking for JSON (Accept: application/json
+// or ?format=json) also get the clone progress and setup status.
 func healthHandler(w http.ResponseWriter, r *http.Request) {
-	if ready {
-		fmt.Fprint(w, "ready")
+	status := "cloning"
+	progress := currentCloneProgress()
+	setup := currentSetupStatus()
+	switch {
+	case progress.Phase == phaseError:
+		status = "error"
+	case setup.State == setupFailed:
+		status = "setup_failed"
+	case isReady():
+		status = "ready"
+	case progress.Phase == phaseDone:
+		status = "setup"
+	}
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":   status,
+			"progress": progress,
+			"setup":    setup,
+		})
 		return
 	}
-	fmt.Fprint(w, "cloning")
+	fmt.Fprint(w, status)
 }
 
 func loadConfig() AppConfig {
@@ -234,6 +299,25 @@ func loadConfig() AppConfig {
 		GitToken:      getenv("GIT_TOKEN", ""),
 		GitUser:       getenv("GIT_USER_NAME", "workspace"),
 		GitEmail:      getenv("GIT_USER_EMAIL", "workspace@example.com"),
+		GitRef:        getenv("GIT_REF", ""),
+		GitPR:         getenv("GIT_PR", ""),
+		CloneDepth:    parseCloneDepth(os.Getenv("GIT_CLONE_DEPTH")),
+		CloneFilter:   getenv("GIT_CLONE_FILTER", ""),
+		SparsePaths:   parseSparsePaths(os.Getenv("GIT_SPARSE_PATHS")),
+
+		PersistWorkspace: os.Getenv("GIT_PERSIST_WORKSPACE") == "true",
+
+		AutosavePolicy:   parseAutosavePolicy(os.Getenv("AUTOSAVE_POLICY")),
+		AutosaveInterval: parseAutosaveInterval(os.Getenv("AUTOSAVE_INTERVAL")),
+		SyncURL:          getenv("AGENT_SYNC_URL", ""),
+		ProgressURL:      getenv("AGENT_PROGRESS_URL", ""),
+		PortsURL:         getenv("AGENT_PORTS_URL", ""),
+
+		JWKSURL:      getenv("AGENT_JWKS_URL", ""),
+		AuthDisabled: os.Getenv("AGENT_AUTH_DISABLED") == "true",
+
+		AllowedOrigins: parseAllowedOrigins(getenv("ALLOWED_ORIGINS", defaultAllowedOrigins)),
+		FileDenylist:   parseDenylist(getenv("FILE_DENYLIST", defaultFileDenylist)),
 	}
 }
 
@@ -258,17 +342,41 @@ func backgroundClone() {
 	if strings.HasPrefix(cfg.RepoURL, "https://") && cfg.GitToken != "" {
 		cloneURL = strings.Replace(cfg.RepoURL, "https://", fmt.Sprintf("https://x-access-token:%s@", cfg.GitToken), 1)
 	}
-	log.Printf("Cloning repository from: %s", maskToken(cloneURL))
-	cmd := exec.Command("git", "clone", cloneURL, "/workspace")
-	out, err := cmd.CombinedOutput()
-	cloneLog.Write(out)
+	pw := &progressWriter{}
+	ctx, cancel := context.WithTimeout(context.Background(), cloneTimeout)
+	var err error
+	if cfg.PersistWorkspace && workspaceHasRepo() {
+		err = updateWorkspace(ctx, pw, cloneURL)
+	} else if err = clearWorkspace(); err == nil {
+		err = cloneWorkspace(ctx, pw, cloneURL)
+	}
+	cancel()
+	pw.Flush()
 	if err != nil {
-		log.Printf("Git clone failed: %v, output: %s", err, string(out))
-		notifyCallback("ERROR")
+		log.Printf("Git clone failed: %v", err)
+		cloneFailed(fmt.Sprintf("clone failed: %v", err))
 		return
 	}
-	log.Printf("Repository cloned successfully")
+	log.Printf("Repository ready")
+
+	ctx, cancel = context.WithTimeout(context.Background(), gitRemoteTimeout)
+	err = checkoutStartRef(ctx)
+	cancel()
+	if err != nil {
+		log.Printf("Checkout of requested ref failed: %v", err)
+		cloneFailed(fmt.Sprintf("checkout failed: %v", err))
+		return
+	}
+	setCloneProgress(cloneProgress{Phase: phaseDone, Percent: 100})
+
+	// A failed setup still leaves a usable workspace so the user can fix it
+	setupOK := runWorkspaceSetup()
 	setReady()
+	go startFileWatcher()
+	if !setupOK {
+		notifyCallback("SETUP_FAILED")
+		return
+	}
 	notifyCallback("READY")
 }
 
@@ -283,9 +391,11 @@ func notifyCallback(status string) {
 	if cfg.CallbackURL == "" || cfg.CallbackToken == "" {
 		return
 	}
+	// The gateway's /api/internal/webhook binds the atlas id from "id",
+	// like the sync, progress and port callbacks
 	body, err := json.Marshal(map[string]string{
-		"atlas_id": cfg.AtlasID,
-		"status":   status,
+		"id":     cfg.AtlasID,
+		"status": status,
 	})
 	if err != nil {
 		log.Printf("Failed to marshal callback body: %v", err)
@@ -300,61 +410,88 @@ func notifyCallback(status string) {
 func terminalHandler(w http.ResponseWriter, r *http.Request) {
 	logWithRequestID(r, "Terminal connection attempt from %s", r.RemoteAddr)
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	framed := wantsFramedTerminal(r)
+	var respHeader http.Header
+	if framed {
+		respHeader = http.Header{"Sec-WebSocket-Protocol": {terminalSubprotocol}}
+	}
+	conn, err := upgrader.Upgrade(w, r, respHeader)
 	if err != nil {
 		logWithRequestID(r, "WebSocket upgrade failed: %v", err)
 		return
 	}
 	defer conn.Close()
-	logWithRequestID(r, "WebSocket connection established")
+	logWithRequestID(r, "WebSocket connection established (framed=%t)", framed)
+	tc := &terminalConn{conn: conn, framed: framed}
 
+	// Show clone and setup output as it arrives until the workspace is ready
+	offset := 0
 	for {
+		if data := cloneLogFrom(offset); len(data) > 0 {
+			offset += len(data)
+			_ = tc.writeData(data)
+		}
 		if isReady() {
 			logWithRequestID(r, "Workspace ready, starting terminal")
 			break
 		}
-		streamLogs(conn)
-		time.Sleep(time.Second)
+		if p := currentCloneProgress(); p.Phase == phaseError {
+			_ = tc.writeControl(terminalMessage{Type: msgError, Message: p.Message})
+			return
+		}
+		time.Sleep(250 * time.Millisecond)
 	}
 
-	cmd := exec.Command("/bin/bash")
-	ptmx, err := pty.Start(cmd)
+	name := r.URL.Query().Get("session")
+	if name == "" {
+		name = defaultSessionName
+	}
+	session, err := getOrCreateSession(name)
 	if err != nil {
-		logWithRequestID(r, "Failed to start PTY: %v", err)
+		logWithRequestID(r, "Failed to start terminal session: %v", err)
 		_ = conn.WriteMessage(websocket.TextMessage, []byte("failed to start pty"))
 		return
 	}
-	defer ptmx.Close()
-	logWithRequestID(r, "PTY started successfully")
+	viewer, scrollback := session.attach()
+	defer session.detach(viewer)
+	logWithRequestID(r, "Attached to terminal session %s (%s)", session.ID, session.Name)
 
 	go func() {
+		defer viewer.close()
 		for {
-			_, msg, err := conn.ReadMessage()
+			mt, msg, err := conn.ReadMessage()
 			if err != nil {
 				logWithRequestID(r, "WebSocket read error: %v", err)
 				return
 			}
-			_, _ = ptmx.Write(msg)
+			if err := tc.handleInput(session, mt, msg); err != nil {
+				logWithRequestID(r, "Terminal input error: %v", err)
+				return
+			}
 		}
 	}()
 
-	buf := make([]byte, 1024)
-	for {
-		n, err := ptmx.Read(buf)
-		if err != nil {
-			logWithRequestID(r, "PTY read error: %v", err)
+	if len(scrollback) > 0 {
+		if err := tc.writeData(scrollback); err != nil {
 			return
 		}
-		_ = conn.WriteMessage(websocket.TextMessage, buf[:n])
 	}
-}
-
-func streamLogs(conn *websocket.Conn) {
-	mu.RLock()
-	defer mu.RUnlock()
-	scan := bufio.NewScanner(bytes.NewReader(cloneLog.Bytes()))
-	for scan.Scan() {
-		_ = conn.WriteMessage(websocket.TextMessage, scan.Bytes())
+	for {
+		select {
+		case chunk := <-viewer.out:
+			if err := tc.writeData(chunk); err != nil {
+				logWithRequestID(r, "WebSocket write error: %v", err)
+				return
+			}
+		case <-viewer.done:
+			if code, exited := session.exitStatus(); exited {
+				_ = tc.writeControl(terminalMessage{Type: msgExit, Code: &code})
+				logWithRequestID(r, "Terminal session %s exited with code %d", session.ID, code)
+				return
+			}
+			logWithRequestID(r, "Detached from terminal session %s", session.ID)
+			return
+		}
 	}
 }
 
@@ -363,9 +500,19 @@ func fileHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "not ready", http.StatusServiceUnavailable)
 		return
 	}
-	fs := http.FileServer(http.Dir("/workspace"))
-	// strip /files prefix
-	http.StripPrefix("/files", fs).ServeHTTP(w, r)
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	// Same as /files/raw?path=, under the same path policy as every other
+	// file route
+	path := strings.TrimPrefix(r.URL.Path, "/files/")
+	fullPath, err := resolveWorkspacePath(path)
+	if err != nil {
+		pathError(w, r, path, err)
+		return
+	}
+	serveWorkspaceFile(w, r, path, fullPath)
 }
 
 func fileContentHandler(w http.ResponseWriter, r *http.Request) {
@@ -385,16 +532,9 @@ func fileContentHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate and sanitize path
-	if !isValidPath(path) {
-		logWithRequestID(r, "Invalid path attempted: %s", path)
-		http.Error(w, "invalid path", 400)
-		return
-	}
-
-	fullPath := filepath.Join("/workspace", path)
-	if !strings.HasPrefix(fullPath, "/workspace") {
-		logWithRequestID(r, "Path traversal attempt: %s", path)
-		http.Error(w, "invalid path", 400)
+	fullPath, err := resolveWorkspacePath(path)
+	if err != nil {
+		pathError(w, r, path, err)
 		return
 	}
 
@@ -415,6 +555,10 @@ func fileContentHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "path is a directory", 400)
 		return
 	}
+	if info.Size() > maxContentSize {
+		http.Error(w, "file too large, use /files/raw", 413)
+		return
+	}
 
 	data, err := os.ReadFile(fullPath)
 	if err != nil {
@@ -423,15 +567,27 @@ func fileContentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Text comes back as a string in the encoding it was detected in,
+	// binary files (or any file with ?encoding=base64) as base64
+	encoding := detectEncoding(data)
+	if r.URL.Query().Get("encoding") == encodingBase64 {
+		encoding = encodingBase64
+	}
+
 	// Return file content with metadata
+	etag := fileETag(data)
 	response := map[string]interface{}{
-		"content": string(data),
-		"size":    info.Size(),
-		"modTime": info.ModTime().Unix(),
-		"path":    path,
+		"content":  decodeContent(data, encoding),
+		"encoding": encoding,
+		"mimeType": getMimeType(fullPath),
+		"size":     info.Size(),
+		"modTime":  info.ModTime().Unix(),
+		"etag":     etag,
+		"path":     path,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		logWithRequestID(r, "Failed to encode file content response: %v", err)
 		http.Error(w, "failed to encode response", 500)
@@ -465,11 +621,8 @@ func isValidPath(path string) bool {
 		return false
 	}
 
-	// Ensure path doesn't start with a dot (hidden files)
-	if strings.HasPrefix(filepath.Base(cleanPath), ".") {
-		return false
-	}
-
+	// Dotfiles are fine; what is off limits is up to the denylist in
+	// resolveWorkspacePath
 	return true
 }
 
@@ -498,36 +651,49 @@ func getMimeType(filePath string) string {
 	return http.DetectContentType(buffer[:n])
 }
 
+type FileNode struct {
+	Name        string      `json:"name"`
+	IsDir       bool        `json:"isDir"`
+	Path        string      `json:"path"`
+	Size        int64       `json:"size"`
+	ModTime     time.Time   `json:"modTime"`
+	Permissions string      `json:"permissions"`
+	Extension   string      `json:"extension,omitempty"`
+	MimeType    string      `json:"mimeType,omitempty"`
+	Ignored     bool        `json:"ignored,omitempty"`
+	HasChildren bool        `json:"hasChildren,omitempty"`
+	Nodes       []*FileNode `json:"nodes,omitempty"`
+}
+
+// fileListHandler returns the whole workspace tree. Denied and gitignored
+// entries are left out the same way as in the paginated listing, unless
+// showIgnored=true brings back the ignored ones. Requests carrying any of
+// path, depth, cursor or limit get the lazy, paginated listing instead.
 func fileListHandler(w http.ResponseWriter, r *http.Request) {
 	if !isReady() {
 		http.Error(w, "not ready", http.StatusServiceUnavailable)
 		return
 	}
 
-	type FileNode struct {
-		Name        string      `json:"name"`
-		IsDir       bool        `json:"isDir"`
-		Path        string      `json:"path"`
-		Size        int64       `json:"size"`
-		ModTime     time.Time   `json:"modTime"`
-		Permissions string      `json:"permissions"`
-		Extension   string      `json:"extension,omitempty"`
-		MimeType    string      `json:"mimeType,omitempty"`
-		Nodes       []*FileNode `json:"nodes,omitempty"`
+	q := r.URL.Query()
+	if q.Has("path") || q.Has("depth") || q.Has("cursor") || q.Has("limit") {
+		pagedListHandler(w, r)
+		return
 	}
 
+	opts := listOptions{showIgnored: q.Get("showIgnored") == "true"}
+	ignores := newIgnoreChecker()
+	defer ignores.close()
+
 	rootPath := "/workspace"
 	var walk func(string) ([]*FileNode, error)
 	walk = func(curr string) ([]*FileNode, error) {
-		entries, err := os.ReadDir(curr)
+		entries, err := readListEntries(curr, opts, ignores)
 		if err != nil {
 			return nil, err
 		}
 		var nodes []*FileNode
 		for _, entry := range entries {
-			if entry.Name() == ".git" {
-				continue
-			}
 			fullPath := filepath.Join(curr, entry.Name())
 			info, _ := entry.Info()
 
@@ -555,6 +721,7 @@ func fileListHandler(w http.ResponseWriter, r *http.Request) {
 				Permissions: perms,
 				Extension:   ext,
 				MimeType:    mimeType,
+				Ignored:     entry.ignored,
 			}
 			if node.IsDir {
 				node.Nodes, _ = walk(fullPath)
@@ -588,14 +755,9 @@ func fileSaveHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate and sanitize path
-	if !isValidPath(path) {
-		http.Error(w, "invalid path", 400)
-		return
-	}
-
-	fullPath := filepath.Join("/workspace", path)
-	if !strings.HasPrefix(fullPath, "/workspace") {
-		http.Error(w, "invalid path", 400)
+	fullPath, err := resolveWorkspacePath(path)
+	if err != nil {
+		pathError(w, r, path, err)
 		return
 	}
 
@@ -606,140 +768,186 @@ func fileSaveHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+	// Limited reader to prevent excessive data
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxFileSize))
+	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
 
-	f, err := os.Create(fullPath)
+	// Save in the encoding /files/content reported
+	data, err = encodeContent(data, r.URL.Query().Get("encoding"))
 	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	saveMu.Lock()
+	defer saveMu.Unlock()
+
+	if current, ok := checkSavePrecondition(r, fullPath); !ok {
+		logWithRequestID(r, "Save conflict for %s", path)
+		w.Header().Set("Content-Type", "application/json")
+		if current.ETag != "" {
+			w.Header().Set("ETag", current.ETag)
+		}
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(current)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 		http.Error(w, err.Error(), 500)
 		return
 	}
-	defer f.Close()
 
-	// Limited reader to prevent excessive data
-	limitedReader := io.LimitReader(r.Body, maxFileSize)
-	if _, err := io.Copy(f, limitedReader); err != nil {
+	if err := writeFileAtomic(fullPath, data); err != nil {
+		logWithRequestID(r, "Failed to save file %s: %v", path, err)
 		http.Error(w, err.Error(), 500)
 		return
 	}
 
-	logWithRequestID(r, "Successfully saved file: %s (%d bytes)", path, r.ContentLength)
+	logWithRequestID(r, "Successfully saved file: %s (%d bytes)", path, len(data))
+	w.Header().Set("ETag", fileETag(data))
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprint(w, "saved")
 }
 
-func autoCommitLoop() {
-	for {
-		time.Sleep(5 * time.Minute)
-		if !isReady() {
-			continue
-		}
-		_ = commitAll("Auto-save")
-	}
+// fileVersion describes the server copy of a file returned on save conflicts.
+type fileVersion struct {
+	Error   string `json:"error"`
+	Exists  bool   `json:"exists"`
+	Content string `json:"content,omitempty"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"`
+	ETag    string `json:"etag,omitempty"`
+	Path    string `json:"path"`
 }
 
-func commitAll(msg string) error {
-	cmd := exec.Command("git", "add", ".")
-	cmd.Dir = "/workspace"
-	if err := cmd.Run(); err != nil {
-		return err
-	}
-	cmd = exec.Command("git", "commit", "-m", msg)
-	cmd.Dir = "/workspace"
-	return cmd.Run()
+// fileETag is a strong validator derived from file content.
+func fileETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
 }
 
-func gracefulShutdown() {
-	if !isReady() {
-		return
+// checkSavePrecondition evaluates If-Match / If-None-Match and the
+// expectedModTime query parameter against the file on disk. Saves without
+// any precondition always pass, preserving last-writer-wins for old clients.
+func checkSavePrecondition(r *http.Request, fullPath string) (fileVersion, bool) {
+	ifMatch := r.Header.Get("If-Match")
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	expectedModTime := r.URL.Query().Get("expectedModTime")
+	if ifMatch == "" && ifNoneMatch == "" && expectedModTime == "" {
+		return fileVersion{}, true
 	}
-	log.Println("performing final sync...")
-	_ = commitAll("Session end sync")
-	cmd := exec.Command("git", "push", "origin", "main")
-	cmd.Dir = "/workspace"
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Printf("final push failed: %v, out: %s", err, string(out))
-	} else {
-		log.Println("final sync successful")
-	}
-}
 
-func portWatcher() {
-	// Common development ports to watch
-	watchPorts := []int{
-		3000, 3001, 3002, 3003, 3004, 3005, // Common dev servers
-		4000, 4001, 4002, 4003, 4004, 4005, // Alternative dev ports
-		5000, 5001, 5002, 5003, 5004, 5005, // More dev ports
-		6000, 6001, 6002, 6003, 6004, 6005, // Even more dev ports
-		7000, 7001, 7002, 7003, 7004, 7005, // Additional dev ports
-		8000, 8001, 8002, 8003, 8004, 8005, // Common dev ports
-		8080, 8081, 8082, 8083, 8084, 8085, // HTTP alternatives
-		9000, 9001, 9002, 9003, 9004, 9005, // More dev ports
+	current := fileVersion{Error: "file changed on server", Path: strings.TrimPrefix(fullPath, "/workspace/")}
+	data, err := os.ReadFile(fullPath)
+	if err == nil {
+		info, statErr := os.Stat(fullPath)
+		if statErr == nil {
+			current.ModTime = info.ModTime().Unix()
+		}
+		current.Exists = true
+		current.Content = string(data)
+		current.Size = int64(len(data))
+		current.ETag = fileETag(data)
 	}
 
-	for {
-		time.Sleep(5 * time.Second) // Increased from 2s to 5s
-
-		// Batch scan ports for efficiency
-		for _, port := range watchPorts {
-			portWatcherMu.Lock()
-			if knownPorts[port] {
-				portWatcherMu.Unlock()
-				continue
-			}
-			portWatcherMu.Unlock()
-
-			conn, err := net.DialTimeout("tcp", fmt.Sprintf("localhost:%d", port), 100*time.Millisecond)
-			if err != nil {
-				continue
-			}
-			conn.Close()
-
-			portWatcherMu.Lock()
-			knownPorts[port] = true
-			portWatcherMu.Unlock()
-
-			notifyPort(port)
+	if ifNoneMatch == "*" && current.Exists {
+		current.Error = "file already exists"
+		return current, false
+	}
+	if ifMatch != "" {
+		if !current.Exists {
+			current.Error = "file no longer exists"
+			return current, false
 		}
+		if ifMatch != "*" && !etagMatches(ifMatch, current.ETag) {
+			return current, false
+		}
+	}
+	if expectedModTime != "" && current.Exists && expectedModTime != strconv.FormatInt(current.ModTime, 10) {
+		return current, false
 	}
+	return current, true
 }
 
-func notifyPort(port int) {
-	if cfg.AtlasID == "" {
-		log.Printf("AtlasID not configured, skipping port notification")
-		return
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag {
+			return true
+		}
 	}
+	return false
+}
 
-	body := map[string]interface{}{
-		"port":   port,
-		"public": false,
+// writeFileAtomic writes data to a temp file next to path and renames it
+// into place, so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
 	}
-	data, err := json.Marshal(body)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
 	if err != nil {
-		log.Printf("Failed to marshal port notification: %v", err)
-		return
+		return err
+	}
+	tmpName := tmp.Name()
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmpName)
 	}
 
-	// Use Atlas base URL from environment or default to localhost:8080
-	atlasURL := getenv("ATLAS_BASE_URL", "http://localhost:8080")
-	url := fmt.Sprintf("%s/sandboxes/%s/ports", atlasURL, cfg.AtlasID)
-
-	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
-	if err != nil {
-		log.Printf("Failed to notify Atlas about port %d: %v", port, err)
-		return
+	if _, err := tmp.Write(data); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		cleanup()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	noteAtomicSave(tmpName, path)
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return err
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode >= 400 {
-		log.Printf("Atlas returned error status %d for port %d notification", resp.StatusCode, port)
+func gracefulShutdown() {
+	if !isReady() {
 		return
 	}
-
-	log.Printf("Successfully notified Atlas about port %d", port)
+	killAllSessions()
+	cancelAllTasks()
+	stopAllLanguageServers()
+	stopFileWatcher()
+	log.Println("performing final sync...")
+	if err := autosave(true); err != nil {
+		log.Printf("final auto-save failed: %v", err)
+	}
+	pushAutosave()
+	res := pushWorkspace()
+	switch res.Status {
+	case syncPushed:
+		log.Printf("final sync successful: %s pushed to %s", res.Branch, res.RemoteRef)
+	case syncRecovery:
+		log.Printf("final push rejected, work saved to %s", res.RemoteRef)
+	default:
+		log.Printf("final push %s: %s", strings.ToLower(res.Status), res.Message)
+	}
+	notifySync(res)
 }
 
 func setReady() {
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFileSavePreconditions(t *testing.T) {
+	setTestReady(t)
+	dir := testWorkspaceDir(t)
+	rel := strings.TrimPrefix(dir, "/workspace/")
+
+	path := filepath.Join(dir, "doc.txt")
+	if err := os.WriteFile(path, []byte("server copy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := fileETag([]byte("server copy"))
+	modTime := strconv.FormatInt(info.ModTime().Unix(), 10)
+
+	tests := []struct {
+		name        string
+		file        string
+		header      string
+		value       string
+		modTime     string
+		wantStatus  int
+		wantExists  bool
+		wantMessage string
+	}{
+		{"no precondition", "doc.txt", "", "", "", 200, false, ""},
+		{"matching If-Match", "doc.txt", "If-Match", etag, "", 200, false, ""},
+		{"weak If-Match in a list", "doc.txt", "If-Match", `"other", W/` + etag, "", 200, false, ""},
+		{"stale If-Match", "doc.txt", "If-Match", `"0123"`, "", 409, true, "file changed on server"},
+		{"If-Match on a missing file", "gone.txt", "If-Match", "*", "", 409, false, "file no longer exists"},
+		{"If-None-Match on an existing file", "doc.txt", "If-None-Match", "*", "", 409, true, "file already exists"},
+		{"If-None-Match on a new file", "fresh.txt", "If-None-Match", "*", "", 200, false, ""},
+		{"matching expectedModTime", "doc.txt", "", "", modTime, 200, false, ""},
+		{"stale expectedModTime", "doc.txt", "", "", "1", 409, true, "file changed on server"},
+	}
+	for _, tt := range tests {
+		// Each case starts from the same server copy
+		if err := os.WriteFile(path, []byte("server copy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, info.ModTime(), info.ModTime())
+		os.Remove(filepath.Join(dir, "fresh.txt"))
+
+		url := "/files/save?path=" + rel + "/" + tt.file
+		if tt.modTime != "" {
+			url += "&expectedModTime=" + tt.modTime
+		}
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader("client copy"))
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		rec := httptest.NewRecorder()
+		fileSaveHandler(rec, req)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d (%s)", tt.name, rec.Code, tt.wantStatus, rec.Body)
+			continue
+		}
+		if tt.wantStatus == 200 {
+			if got := rec.Header().Get("ETag"); got != fileETag([]byte("client copy")) {
+				t.Errorf("%s: ETag = %q, want the saved content's", tt.name, got)
+			}
+			if data, _ := os.ReadFile(filepath.Join(dir, tt.file)); string(data) != "client copy" {
+				t.Errorf("%s: file = %q after save", tt.name, data)
+			}
+			continue
+		}
+
+		var current fileVersion
+		if err := json.Unmarshal(rec.Body.Bytes(), &current); err != nil {
+			t.Fatalf("%s: bad conflict body %q: %v", tt.name, rec.Body, err)
+		}
+		if current.Error != tt.wantMessage || current.Exists != tt.wantExists {
+			t.Errorf("%s: conflict = %+v", tt.name, current)
+		}
+		if tt.wantExists && (current.Content != "server copy" || current.ETag != etag || rec.Header().Get("ETag") != etag) {
+			t.Errorf("%s: conflict doesn't describe the server copy: %+v", tt.name, current)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "server copy" {
+			t.Errorf("%s: rejected save changed the file to %q", tt.name, data)
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+
+	// New files get 0644
+	path := filepath.Join(dir, "new.txt")
+	if err := writeFileAtomic(path, []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, path, "one", 0644)
+
+	// Existing files keep their mode
+	script := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(script, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(script, []byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, script, "new", 0755)
+
+	// Writes replace the file rather than modifying it in place, so an open
+	// reader keeps the old content
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := writeFileAtomic(path, []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 8)
+	n, _ := f.Read(buf)
+	if string(buf[:n]) != "one" {
+		t.Errorf("open reader saw %q", buf[:n])
+	}
+	assertFile(t, path, "two", 0644)
+
+	// Failed writes leave no temp files behind
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "x.txt"), []byte("x")); err == nil {
+		t.Error("write into a missing directory succeeded")
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if isAtomicTempName(e.Name()) {
+			t.Errorf("temp file %s left behind", e.Name())
+		}
+	}
+}
+
+func TestFileListTreeFiltering(t *testing.T) {
+	if _, err := os.Stat("/workspace/.git"); err != nil {
+		t.Skip("/workspace is not a git repository")
+	}
+	setTestReady(t)
+	saved := cfg.FileDenylist
+	defer func() { cfg.FileDenylist = saved }()
+	cfg.FileDenylist = parseDenylist(defaultFileDenylist)
+
+	dir := testWorkspaceDir(t)
+	rel := strings.TrimPrefix(dir, "/workspace/")
+	for _, name := range []string{".gitignore", "main.go", "app.log", ".env", "src/util.go", "src/id_rsa", "node_modules/x/a.js"} {
+		mustMkdir(t, filepath.Dir(filepath.Join(dir, name)))
+		content := ""
+		if name == ".gitignore" {
+			content = "*.log\nnode_modules/\n"
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"/src", "/src/util.go", "/.gitignore", "/main.go"}},
+		{"?showIgnored=true", []string{
+			"/node_modules*", "/node_modules/x*", "/node_modules/x/a.js*",
+			"/src", "/src/util.go", "/.gitignore", "/app.log*", "/main.go",
+		}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		fileListHandler(rec, httptest.NewRequest(http.MethodGet, "/files"+tt.query, nil))
+		if rec.Code != 200 {
+			t.Fatalf("%q: status %d: %s", tt.query, rec.Code, rec.Body)
+		}
+		var nodes []*FileNode
+		if err := json.Unmarshal(rec.Body.Bytes(), &nodes); err != nil {
+			t.Fatal(err)
+		}
+		// Descend to the test directory, then collect its subtree
+		for _, part := range strings.Split(rel, "/") {
+			var next []*FileNode
+			for _, n := range nodes {
+				if n.Name == part {
+					next = n.Nodes
+				}
+			}
+			nodes = next
+		}
+		var got []string
+		var walk func(nodes []*FileNode)
+		walk = func(nodes []*FileNode) {
+			for _, n := range nodes {
+				p := strings.TrimPrefix(n.Path, "/"+rel)
+				if n.Ignored {
+					p += "*"
+				}
+				got = append(got, p)
+				walk(n.Nodes)
+			}
+		}
+		walk(nodes)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q listed %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func assertFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("%s = %q, want %q", filepath.Base(path), data, content)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != mode {
+		t.Errorf("%s mode = %v, want %v", filepath.Base(path), info.Mode().Perm(), mode)
+	}
+}
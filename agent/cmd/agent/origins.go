@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Aadithya-J/code_nest/origins"
+)
+
+// Origins allowed when ALLOWED_ORIGINS isn't set: local development only.
+const defaultAllowedOrigins = "http://localhost:*,https://localhost:*,http://127.0.0.1:*,https://127.0.0.1:*"
+
+// parseAllowedOrigins reads ALLOWED_ORIGINS, the same list the gateway
+// uses. A list the gateway would refuse falls back to localhost only.
+func parseAllowedOrigins(v string) []string {
+	patterns, err := origins.Parse(v)
+	if err != nil {
+		log.Printf("Invalid ALLOWED_ORIGINS %q (%v), allowing localhost only", v, err)
+		patterns, _ = origins.Parse(defaultAllowedOrigins)
+	}
+	return patterns
+}
+
+func originAllowed(origin string) bool {
+	return origins.Allowed(cfg.AllowedOrigins, origin)
+}
+
+// checkOrigin is the WebSocket upgrader's origin check. Browsers always send
+// an Origin on WebSocket handshakes, so requests without one are refused.
+func checkOrigin(r *http.Request) bool {
+	return originAllowed(r.Header.Get("Origin"))
+}
+
+// corsMiddleware lets the IDE, served from an allowed origin, call the agent
+// from the browser. Requests from any other origin are refused outright:
+// the proxy adds the workspace token from the user's cookie, so another
+// site could otherwise act on the workspace. Preflight requests are answered
+// here, before authentication, since browsers send them without
+// credentials.
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		if !originAllowed(origin) {
+			logWithRequestID(r, "Rejected request from origin %s", origin)
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-ID, Accept-Ranges, Content-Range, Content-Disposition")
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, If-None-Match, If-Range, Range, X-Workspace-Token")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
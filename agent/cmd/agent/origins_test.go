@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseAllowedOrigins(t *testing.T) {
+	got := parseAllowedOrigins("https://*.codenest.dev, http://localhost:5173/")
+	if want := []string{"https://*.codenest.dev", "http://localhost:5173"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAllowedOrigins = %q, want %q", got, want)
+	}
+	// A wildcard would let any site use the workspace with the user's cookie
+	local := parseAllowedOrigins(defaultAllowedOrigins)
+	if got := parseAllowedOrigins("https://codenest.dev,*"); !reflect.DeepEqual(got, local) {
+		t.Errorf("parseAllowedOrigins with * = %q, want the localhost default", got)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	saved := cfg.AllowedOrigins
+	defer func() { cfg.AllowedOrigins = saved }()
+	cfg.AllowedOrigins = parseAllowedOrigins("https://*.codenest.dev")
+
+	tests := map[string]bool{
+		"":                          false,
+		"https://ide.codenest.dev":  true,
+		"http://ide.codenest.dev":   false,
+		"https://codenest.dev.evil": false,
+	}
+	for origin, want := range tests {
+		r := httptest.NewRequest("GET", "/terminal", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if got := checkOrigin(r); got != want {
+			t.Errorf("checkOrigin(%q) = %v, want %v", origin, got, want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Paths the file routes refuse unless FILE_DENYLIST replaces the list: git
+// internals and files that usually hold credentials.
+const defaultFileDenylist = ".git/,.env,.env.*,!.env.example,!.env.sample,!.env.template,*.pem,*.key,id_rsa*,id_ecdsa*,id_ed25519*,.ssh/,.aws/,.netrc"
+
+// Longest symlink chain followed when resolving a path
+const maxSymlinkHops = 40
+
+var (
+	errInvalidPath = errors.New("invalid path")
+	errPathDenied  = errors.New("path denied by policy")
+	errPathOutside = errors.New("path resolves outside the workspace")
+)
+
+// denyRule is one FILE_DENYLIST pattern. A pattern without a slash is
+// matched against every path component, one with a slash against the path
+// from the workspace root; "!" re-allows what an earlier rule denied.
+type denyRule struct {
+	pattern  string
+	allow    bool
+	anchored bool
+}
+
+// parseDenylist splits a comma-separated FILE_DENYLIST. Patterns use
+// path.Match syntax; a trailing slash is allowed for readability but
+// directories and files are matched alike.
+func parseDenylist(s string) []denyRule {
+	var rules []denyRule
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		rule := denyRule{}
+		if rest, ok := strings.CutPrefix(p, "!"); ok {
+			rule.allow, p = true, rest
+		}
+		p = strings.Trim(p, "/")
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			continue
+		}
+		rule.pattern, rule.anchored = p, strings.Contains(p, "/")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// pathDenied reports whether a workspace-relative path, or a directory on
+// the way to it, is denied. As in .gitignore the last matching rule wins,
+// and nothing under a denied directory can be re-allowed.
+func pathDenied(rel string) bool {
+	rel = strings.Trim(filepath.ToSlash(rel), "/")
+	if rel == "" || rel == "." {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i, name := range parts {
+		sub := strings.Join(parts[:i+1], "/")
+		denied := false
+		for _, rule := range cfg.FileDenylist {
+			target := name
+			if rule.anchored {
+				target = sub
+			}
+			if ok, _ := path.Match(rule.pattern, target); ok {
+				denied = !rule.allow
+			}
+		}
+		if denied {
+			return true
+		}
+	}
+	return false
+}
+
+var workspaceRealPath = sync.OnceValue(func() string {
+	if real, err := filepath.EvalSymlinks("/workspace"); err == nil {
+		return real
+	}
+	return "/workspace"
+})
+
+// realPath resolves the symlinks in p. Components that don't exist yet,
+// e.g. for a file about to be created, are kept as they are; dangling
+// links are followed to where they would point.
+func realPath(p string) (string, error) {
+	rest := ""
+	for hops := 0; ; {
+		real, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(real, rest), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if info, lerr := os.Lstat(p); lerr == nil && info.Mode()&os.ModeSymlink != 0 {
+			if hops++; hops > maxSymlinkHops {
+				return "", errors.New("too many levels of symbolic links")
+			}
+			target, err := os.Readlink(p)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(p), target)
+			}
+			p = target
+			continue
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return filepath.Join(p, rest), nil
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+		p = parent
+	}
+}
+
+// resolveWorkspacePath validates a client supplied relative path and maps
+// it into /workspace. Both the path and whatever it resolves to through
+// symlinks must stay inside the workspace and clear the denylist; the
+// resolved path is returned.
+func resolveWorkspacePath(path string) (string, error) {
+	return resolvePath(path, true)
+}
+
+// resolveWorkspaceEntry is resolveWorkspacePath for operations on the
+// entry itself (delete, rename, copy), which don't follow a final symlink:
+// removing a link that points outside the workspace is fine.
+func resolveWorkspaceEntry(path string) (string, error) {
+	return resolvePath(path, false)
+}
+
+func resolvePath(path string, follow bool) (string, error) {
+	if !isValidPath(path) {
+		return "", errInvalidPath
+	}
+	fullPath := filepath.Join("/workspace", path)
+	if !strings.HasPrefix(fullPath, "/workspace/") {
+		return "", errInvalidPath
+	}
+	if pathDenied(strings.TrimPrefix(fullPath, "/workspace/")) {
+		return "", errPathDenied
+	}
+
+	var real string
+	var err error
+	if follow {
+		real, err = realPath(fullPath)
+	} else {
+		real, err = realPath(filepath.Dir(fullPath))
+		real = filepath.Join(real, filepath.Base(fullPath))
+	}
+	if err != nil {
+		return "", err
+	}
+	root := workspaceRealPath()
+	if real == root {
+		return "/workspace", nil
+	}
+	if !strings.HasPrefix(real, root+"/") {
+		return "", errPathOutside
+	}
+	if pathDenied(strings.TrimPrefix(real, root+"/")) {
+		return "", errPathDenied
+	}
+	// The location that was checked, so callers don't follow the links again
+	return "/workspace" + strings.TrimPrefix(real, root), nil
+}
+
+// pathError answers a path the policy rejected: 400 when it is malformed,
+// 403 when it is denied or escapes the workspace.
+func pathError(w http.ResponseWriter, r *http.Request, path string, err error) {
+	logWithRequestID(r, "Rejected path %s: %v", path, err)
+	switch {
+	case errors.Is(err, errInvalidPath):
+		http.Error(w, "invalid path", 400)
+	case errors.Is(err, errPathDenied), errors.Is(err, errPathOutside):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), 500)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseDenylist(t *testing.T) {
+	got := parseDenylist(" .git/ , ,!.env.example, config/secrets/*.json,[bad ,/.ssh/")
+	want := []denyRule{
+		{pattern: ".git"},
+		{pattern: ".env.example", allow: true},
+		{pattern: "config/secrets/*.json", anchored: true},
+		{pattern: ".ssh"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDenylist = %+v, want %+v", got, want)
+	}
+}
+
+func TestPathDenied(t *testing.T) {
+	saved := cfg.FileDenylist
+	defer func() { cfg.FileDenylist = saved }()
+	cfg.FileDenylist = parseDenylist(defaultFileDenylist + ",config/*.json,build/,!build/keep")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"", false},
+		{".", false},
+		{"main.go", false},
+		{".env", true},
+		{"app/.env", true},
+		{".env.local", true},
+		{".env.example", false},
+		{"app/.env.example", false},
+		{".envrc", false},
+		{".git", true},
+		{".git/config", true},
+		{"/.git/HEAD", true},
+		{"vendor/.git/HEAD", true},
+		{"certs/server.pem", true},
+		{"server.pem.txt", false},
+		{".ssh/config", true},
+		{"home/id_rsa.pub", true},
+		{"config/app.json", true},
+		{"config/nested/app.json", false},
+		{"src/config/app.json", false},
+		// Nothing under a denied directory can be re-allowed
+		{"build/keep", true},
+	}
+	for _, tt := range tests {
+		if got := pathDenied(tt.path); got != tt.want {
+			t.Errorf("pathDenied(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRealPath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustMkdir(t, filepath.Join(dir, "real"))
+	mustSymlink(t, "real", filepath.Join(dir, "link"))
+	outside, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustSymlink(t, outside, filepath.Join(dir, "abs"))
+	mustSymlink(t, "missing/file", filepath.Join(dir, "dangling"))
+	mustSymlink(t, "loop", filepath.Join(dir, "loop"))
+
+	tests := []struct {
+		path, want string
+	}{
+		{"real", "real"},
+		{"link", "real"},
+		{"link/new/file.txt", "real/new/file.txt"},
+		{"dangling", "missing/file"},
+		{"not/there", "not/there"},
+	}
+	for _, tt := range tests {
+		got, err := realPath(filepath.Join(dir, tt.path))
+		if err != nil {
+			t.Errorf("realPath(%q): %v", tt.path, err)
+			continue
+		}
+		if want := filepath.Join(dir, tt.want); got != want {
+			t.Errorf("realPath(%q) = %q, want %q", tt.path, got, want)
+		}
+	}
+
+	want := filepath.Join(outside, "secret")
+	if got, err := realPath(filepath.Join(dir, "abs/secret")); err != nil || got != want {
+		t.Errorf("realPath(abs/secret) = %q, %v, want %q", got, err, want)
+	}
+	if _, err := realPath(filepath.Join(dir, "loop")); err == nil {
+		t.Error("realPath(loop) should fail")
+	}
+}
+
+func mustMkdir(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustSymlink(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	portScanInterval = 2 * time.Second
+	// Failed port reports are retried with backoff up to this delay
+	maxPortRetryDelay = time.Minute
+	// TCP state for a listening socket in /proc/net/tcp
+	tcpListen = "0A"
+)
+
+// listeningPort is a TCP port some process in the workspace listens on.
+type listeningPort struct {
+	Port      int       `json:"port"`
+	Addresses []string  `json:"addresses"`
+	Pid       int       `json:"pid,omitempty"`
+	Process   string    `json:"process,omitempty"` // executable name
+	Command   string    `json:"command,omitempty"` // full command line
+	Label     string    `json:"label,omitempty"`   // from the workspace config
+	Protocol  string    `json:"protocol,omitempty"`
+	Since     time.Time `json:"since"`
+
+	inodes []string
+	// Set when the port opens, so its closing is judged the same way
+	agentOwned bool
+}
+
+// portState is the set of listening ports seen by the last scan.
+var portState = struct {
+	sync.Mutex
+	ports map[int]*listeningPort
+}{ports: map[int]*listeningPort{}}
+
+// portReport is a port state project-service hasn't acknowledged yet.
+type portReport struct {
+	port listeningPort
+	open bool
+}
+
+// portReports holds the latest unreported state of each port. A report
+// that fails stays queued and is sent again on a later scan, so a brief
+// project-service outage doesn't leave it with a stale port list. Only the
+// watcher goroutine touches it.
+var portReports = struct {
+	pending    map[int]portReport
+	retryAt    time.Time
+	retryDelay time.Duration
+}{pending: map[int]portReport{}}
+
+// portWatcher polls the kernel's socket tables and reports ports opening
+// and closing, whatever the port number.
+func portWatcher() {
+	for {
+		scanPorts()
+		time.Sleep(portScanInterval)
+	}
+}
+
+func scanPorts() {
+	current, err := readListeningPorts()
+	if err != nil {
+		log.Printf("Port scan failed: %v", err)
+		return
+	}
+
+	portState.Lock()
+	var opened, closed []*listeningPort
+	for port, p := range current {
+		if prev, ok := portState.ports[port]; ok && sameInodes(prev.inodes, p.inodes) {
+			current[port] = prev
+			continue
+		}
+		// New listener, or a different socket on a known port because the
+		// server restarted
+		opened = append(opened, p)
+	}
+	for port, p := range portState.ports {
+		if _, ok := current[port]; !ok {
+			closed = append(closed, p)
+		}
+	}
+	portState.Unlock()
+
+	if len(opened) > 0 {
+		owners := socketOwners()
+		for _, p := range opened {
+			p.Since = time.Now()
+			for _, inode := range p.inodes {
+				if pid, ok := owners[inode]; ok {
+					p.Pid = pid
+					break
+				}
+			}
+			describeProcess(p)
+			applyPortConfig(p)
+			p.agentOwned = isAgentPort(p)
+		}
+	}
+
+	portState.Lock()
+	portState.ports = current
+	portState.Unlock()
+
+	for _, p := range opened {
+		if p.agentOwned {
+			continue
+		}
+		log.Printf("Port %d opened by %s (pid %d)", p.Port, p.Process, p.Pid)
+		portReports.pending[p.Port] = portReport{*p, true}
+	}
+	for _, p := range closed {
+		if p.agentOwned {
+			continue
+		}
+		log.Printf("Port %d closed", p.Port)
+		portReports.pending[p.Port] = portReport{*p, false}
+	}
+	flushPortReports()
+}
+
+// flushPortReports sends the queued port reports in port order. If one
+// fails the rest wait too, and the next attempt is delayed, doubling up to
+// maxPortRetryDelay while failures continue.
+func flushPortReports() {
+	if len(portReports.pending) == 0 || time.Now().Before(portReports.retryAt) {
+		return
+	}
+	ports := make([]int, 0, len(portReports.pending))
+	for port := range portReports.pending {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	for _, port := range ports {
+		r := portReports.pending[port]
+		if !notifyPort(r.port, r.open) {
+			portReports.retryDelay = min(max(2*portReports.retryDelay, portScanInterval), maxPortRetryDelay)
+			portReports.retryAt = time.Now().Add(portReports.retryDelay)
+			log.Printf("%d port reports pending, retrying in %v", len(portReports.pending), portReports.retryDelay)
+			return
+		}
+		delete(portReports.pending, port)
+	}
+	portReports.retryDelay = 0
+}
+
+// isAgentPort reports whether the socket belongs to the agent: its own
+// server or a debug adapter it started. Those aren't workspace ports.
+func isAgentPort(p *listeningPort) bool {
+	return p.Pid == os.Getpid() || isDebugPort(p.Port)
+}
+
+func sameInodes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// readListeningPorts parses /proc/net/tcp and /proc/net/tcp6 for sockets in
+// the LISTEN state, merging IPv4 and IPv6 listeners on the same port.
+func readListeningPorts() (map[int]*listeningPort, error) {
+	ports := map[int]*listeningPort{}
+	found := false
+	for _, file := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(file)
+		if os.IsNotExist(err) {
+			continue // no IPv6
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		err = parseProcNetTCP(f, ports)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no /proc/net/tcp")
+	}
+	for _, p := range ports {
+		sort.Strings(p.Addresses)
+		sort.Strings(p.inodes)
+	}
+	return ports, nil
+}
+
+func parseProcNetTCP(f *os.File, ports map[int]*listeningPort) error {
+	sc := bufio.NewScanner(f)
+	sc.Scan() // header
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 10 || fields[3] != tcpListen {
+			continue
+		}
+		addr, port, err := parseHexAddr(fields[1])
+		if err != nil {
+			return err
+		}
+		p, ok := ports[port]
+		if !ok {
+			p = &listeningPort{Port: port}
+			ports[port] = p
+		}
+		p.Addresses = append(p.Addresses, addr)
+		p.inodes = append(p.inodes, fields[9])
+	}
+	return sc.Err()
+}
+
+// parseHexAddr decodes "0100007F:1F90" style addresses. The kernel prints
+// the address as native-endian 32-bit words, i.e. little-endian here.
+func parseHexAddr(s string) (string, int, error) {
+	hexIP, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("invalid address %q", s)
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in %q", s)
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return "", 0, fmt.Errorf("invalid address %q", s)
+	}
+	for i := 0; i < len(raw); i += 4 {
+		raw[i], raw[i+1], raw[i+2], raw[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return net.IP(raw).String(), int(port), nil
+}
+
+// socketOwners maps socket inodes to the pid holding them, by walking
+// /proc/<pid>/fd. Processes we can't inspect are skipped.
+func socketOwners() map[string]int {
+	owners := map[string]int{}
+	procs, _ := filepath.Glob("/proc/[0-9]*/fd")
+	for _, fdDir := range procs {
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(fdDir)))
+		if err != nil {
+			continue
+		}
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode := strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")
+			if _, seen := owners[inode]; !seen {
+				owners[inode] = pid
+			}
+		}
+	}
+	return owners
+}
+
+func describeProcess(p *listeningPort) {
+	if p.Pid == 0 {
+		return
+	}
+	if comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", p.Pid)); err == nil {
+		p.Process = strings.TrimSpace(string(comm))
+	}
+	if cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", p.Pid)); err == nil {
+		cmd := strings.TrimSpace(string(bytes.ReplaceAll(cmdline, []byte{0}, []byte{' '})))
+		if len(cmd) > 512 {
+			cmd = cmd[:512]
+		}
+		p.Command = cmd
+	}
+}
+
+// applyPortConfig labels a port the workspace config declares.
+func applyPortConfig(p *listeningPort) {
+	for _, pc := range currentWorkspaceConfig().Ports {
+		if pc.Port == p.Port {
+			p.Label, p.Protocol = pc.Label, pc.Protocol
+			return
+		}
+	}
+}
+
+func listListeningPorts() []listeningPort {
+	portState.Lock()
+	defer portState.Unlock()
+	list := make([]listeningPort, 0, len(portState.ports))
+	for _, p := range portState.ports {
+		if !p.agentOwned {
+			list = append(list, *p)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Port < list[j].Port })
+	return list
+}
+
+// portsHandler lists the ports currently listening in the workspace.
+func portsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(listListeningPorts())
+}
+
+// notifyPort reports a port opening or closing to project-service, which
+// records it and has Atlas forward it. It runs on the watcher goroutine so
+// reports for a port arrive in order. It returns false if the report should
+// be retried.
+func notifyPort(p listeningPort, open bool) bool {
+	if cfg.PortsURL == "" || cfg.CallbackToken == "" {
+		log.Printf("Ports URL not configured, skipping port notification")
+		return true
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"id":       cfg.AtlasID,
+		"port":     p.Port,
+		"open":     open,
+		"process":  p.Process,
+		"label":    p.Label,
+		"protocol": p.Protocol,
+	})
+	if err != nil {
+		log.Printf("Failed to marshal port notification: %v", err)
+		return true
+	}
+	req, _ := http.NewRequest(http.MethodPost, cfg.PortsURL, bytes.NewReader(data))
+	req.Header.Set("Authorization", cfg.CallbackToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Failed to report port %d: %v", p.Port, err)
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		log.Printf("Port report for %d returned status %d", p.Port, resp.StatusCode)
+		// Client errors won't go away by sending the same report again
+		return resp.StatusCode < 500
+	}
+
+	log.Printf("Reported port %d (open=%t)", p.Port, open)
+	return true
+}
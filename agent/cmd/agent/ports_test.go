@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseHexAddr(t *testing.T) {
+	tests := []struct {
+		in   string
+		addr string
+		port int
+	}{
+		{"0100007F:1F90", "127.0.0.1", 8080},
+		{"00000000:0016", "0.0.0.0", 22},
+		{"00000000000000000000000000000000:1435", "::", 5173},
+		{"00000000000000000000000001000000:0BB8", "::1", 3000},
+	}
+	for _, tt := range tests {
+		addr, port, err := parseHexAddr(tt.in)
+		if err != nil || addr != tt.addr || port != tt.port {
+			t.Errorf("parseHexAddr(%q) = %q, %d, %v, want %q, %d", tt.in, addr, port, err, tt.addr, tt.port)
+		}
+	}
+	for _, in := range []string{"", "0100007F", "0100007F:XYZ", "01007F:1F90", "0100007F:10000"} {
+		if _, _, err := parseHexAddr(in); err == nil {
+			t.Errorf("parseHexAddr(%q) succeeded", in)
+		}
+	}
+}
+
+func TestParseProcNetTCP(t *testing.T) {
+	const table = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 41001 1 0000000000000000 100 0 0 10 0
+   1: 00000000:1435 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 41002 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:1F90 0100007F:C350 01 00000000:00000000 00:00000000 00000000  1000        0 41003 1 0000000000000000 20 4 30 10 -1
+   3: 0100007F:1435 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 41004 1 0000000000000000 100 0 0 10 0
+`
+	path := filepath.Join(t.TempDir(), "tcp")
+	if err := os.WriteFile(path, []byte(table), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ports := map[int]*listeningPort{}
+	if err := parseProcNetTCP(f, ports); err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]*listeningPort{
+		8080: {Port: 8080, Addresses: []string{"127.0.0.1"}, inodes: []string{"41001"}},
+		5173: {Port: 5173, Addresses: []string{"0.0.0.0", "127.0.0.1"}, inodes: []string{"41002", "41004"}},
+	}
+	if !reflect.DeepEqual(ports, want) {
+		for port, p := range ports {
+			t.Logf("%d: %+v", port, *p)
+		}
+		t.Errorf("parseProcNetTCP found the wrong listening ports")
+	}
+}
+
+func TestFlushPortReportsRetries(t *testing.T) {
+	var got []string
+	failing := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body struct {
+			Port int  `json:"port"`
+			Open bool `json:"open"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		got = append(got, fmt.Sprintf("%d:%t", body.Port, body.Open))
+	}))
+	defer srv.Close()
+
+	savedCfg, savedReports := cfg, portReports
+	defer func() { cfg, portReports = savedCfg, savedReports }()
+	cfg.PortsURL, cfg.CallbackToken = srv.URL, "secret"
+	portReports.pending = map[int]portReport{}
+	portReports.retryAt, portReports.retryDelay = time.Time{}, 0
+
+	portReports.pending[5173] = portReport{listeningPort{Port: 5173}, true}
+	portReports.pending[3000] = portReport{listeningPort{Port: 3000}, true}
+	flushPortReports()
+	if len(portReports.pending) != 2 || portReports.retryDelay != portScanInterval {
+		t.Fatalf("after failure: %d pending, retry delay %v", len(portReports.pending), portReports.retryDelay)
+	}
+	// Nothing is sent again before the retry time
+	failing = false
+	flushPortReports()
+	if len(got) != 0 {
+		t.Fatalf("reports sent before the retry time: %v", got)
+	}
+
+	// A later state for the same port replaces the queued one
+	portReports.pending[3000] = portReport{listeningPort{Port: 3000}, false}
+	portReports.retryAt = time.Time{}
+	flushPortReports()
+	want := []string{"3000:false", "5173:true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reported %v, want %v", got, want)
+	}
+	if len(portReports.pending) != 0 || portReports.retryDelay != 0 {
+		t.Errorf("after success: %d pending, retry delay %v", len(portReports.pending), portReports.retryDelay)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Clone phases, in the order git goes through them.
+const (
+	phaseStarting    = "starting"
+	phaseCounting    = "counting"
+	phaseCompressing = "compressing"
+	phaseReceiving   = "receiving"
+	phaseResolving   = "resolving"
+	phaseCheckout    = "checkout"
+	phaseDone        = "done"
+	phaseError       = "error"
+)
+
+// phaseSpan maps each phase onto a slice of the overall progress bar.
+// Receiving dominates on any real repository.
+var phaseSpan = map[string][2]int{
+	phaseCounting:    {0, 5},
+	phaseCompressing: {5, 10},
+	phaseReceiving:   {10, 80},
+	phaseResolving:   {80, 95},
+	phaseCheckout:    {95, 100},
+}
+
+// gitProgressLabels maps git's progress titles onto phases.
+var gitProgressLabels = map[string]string{
+	"Enumerating objects": phaseCounting,
+	"Counting objects":    phaseCounting,
+	"Compressing objects": phaseCompressing,
+	"Receiving objects":   phaseReceiving,
+	"Resolving deltas":    phaseResolving,
+	"Updating files":      phaseCheckout,
+	"Checking out files":  phaseCheckout,
+}
+
+// Matches e.g. "remote: Counting objects:  45% (55/123)" and
+// "Receiving objects:  37% (46/123), 1.20 MiB | 2.31 MiB/s".
+var gitProgressRe = regexp.MustCompile(`^(?:remote: )?([A-Z][a-z]+ [a-z]+(?: [a-z]+)?):\s+(\d+)% \((\d+)/(\d+)\)(?:, ([0-9.]+ [KMG]?i?B))?(?: \| ([0-9.]+ [KMG]?i?B/s))?`)
+
+type cloneProgress struct {
+	Phase      string    `json:"phase"`
+	Percent    int       `json:"percent"` // within the phase
+	Overall    int       `json:"overall"` // estimate across the whole clone
+	Current    int64     `json:"current,omitempty"`
+	Total      int64     `json:"total,omitempty"`
+	Received   string    `json:"received,omitempty"`
+	Throughput string    `json:"throughput,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+func (p cloneProgress) finished() bool {
+	return p.Phase == phaseDone || p.Phase == phaseError
+}
+
+// cloneState holds the raw clone output, shown in terminals while the
+// workspace is being prepared, and the parsed progress.
+var cloneState = struct {
+	sync.Mutex
+	log          bytes.Buffer
+	progress     cloneProgress
+	subs         map[chan cloneProgress]bool
+	lastReported time.Time
+}{
+	progress: cloneProgress{Phase: phaseStarting},
+	subs:     map[chan cloneProgress]bool{},
+}
+
+// cloneLogFrom returns clone output written after offset.
+func cloneLogFrom(offset int) []byte {
+	cloneState.Lock()
+	defer cloneState.Unlock()
+	if offset >= cloneState.log.Len() {
+		return nil
+	}
+	return bytes.Clone(cloneState.log.Bytes()[offset:])
+}
+
+func appendCloneLog(p []byte) {
+	cloneState.Lock()
+	cloneState.log.Write(p)
+	cloneState.Unlock()
+}
+
+func currentCloneProgress() cloneProgress {
+	cloneState.Lock()
+	defer cloneState.Unlock()
+	return cloneState.progress
+}
+
+// setCloneProgress publishes a progress update to stream subscribers and,
+// throttled, to project-service. Phase changes are always reported.
+func setCloneProgress(p cloneProgress) {
+	p.UpdatedAt = time.Now()
+	if span, ok := phaseSpan[p.Phase]; ok {
+		p.Overall = span[0] + (span[1]-span[0])*p.Percent/100
+	}
+	if p.Phase == phaseDone {
+		p.Overall = 100
+	}
+
+	cloneState.Lock()
+	prev := cloneState.progress
+	if p.Phase == phaseError {
+		p.Overall = prev.Overall
+	}
+	// Fetches repeat the counting phases; never let the bar move backwards
+	if p.Overall < prev.Overall && !p.finished() {
+		p.Overall = prev.Overall
+	}
+	cloneState.progress = p
+	for ch := range cloneState.subs {
+		select {
+		case ch <- p:
+		default:
+			// Slow subscribers just miss intermediate updates, but the
+			// final event must get through so their streams end
+			if p.finished() {
+				select {
+				case <-ch:
+				default:
+				}
+				select {
+				case ch <- p:
+				default:
+				}
+			}
+		}
+	}
+	report := p.Phase != prev.Phase || p.finished() || time.Since(cloneState.lastReported) >= 2*time.Second
+	if report {
+		cloneState.lastReported = p.UpdatedAt
+	}
+	cloneState.Unlock()
+
+	if report {
+		go notifyProgress(p)
+	}
+}
+
+func subscribeCloneProgress() (chan cloneProgress, cloneProgress) {
+	ch := make(chan cloneProgress, 16)
+	cloneState.Lock()
+	defer cloneState.Unlock()
+	cloneState.subs[ch] = true
+	return ch, cloneState.progress
+}
+
+func unsubscribeCloneProgress(ch chan cloneProgress) {
+	cloneState.Lock()
+	defer cloneState.Unlock()
+	delete(cloneState.subs, ch)
+}
+
+// cloneFailed records a failed clone or checkout: the message goes to the
+// clone log and the progress stream, and project-service is told.
+func cloneFailed(msg string) {
+	appendCloneLog([]byte(maskGitToken(msg) + "\n"))
+	setCloneProgress(cloneProgress{Phase: phaseError, Message: maskGitToken(msg)})
+	notifyCallback("ERROR")
+}
+
+// progressWriter receives git's stderr. git redraws progress lines with
+// \r, so output is split on both \r and \n; each complete line is masked,
+// appended to the clone log and parsed for progress.
+type progressWriter struct {
+	buf []byte
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	pw.buf = append(pw.buf, p...)
+	for {
+		i := bytes.IndexAny(pw.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		line := string(pw.buf[:i])
+		appendCloneLog([]byte(maskGitToken(line) + string(pw.buf[i])))
+		pw.buf = pw.buf[i+1:]
+		if ev, ok := parseGitProgress(line); ok {
+			setCloneProgress(ev)
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes out a trailing partial line.
+func (pw *progressWriter) Flush() {
+	if len(pw.buf) > 0 {
+		appendCloneLog([]byte(maskGitToken(string(pw.buf)) + "\n"))
+		pw.buf = nil
+	}
+}
+
+// parseGitProgress turns one git progress line into a progress event.
+func parseGitProgress(line string) (cloneProgress, bool) {
+	m := gitProgressRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return cloneProgress{}, false
+	}
+	phase, ok := gitProgressLabels[m[1]]
+	if !ok {
+		return cloneProgress{}, false
+	}
+	percent, _ := strconv.Atoi(m[2])
+	current, _ := strconv.ParseInt(m[3], 10, 64)
+	total, _ := strconv.ParseInt(m[4], 10, 64)
+	return cloneProgress{
+		Phase:      phase,
+		Percent:    min(percent, 100),
+		Current:    current,
+		Total:      total,
+		Received:   m[5],
+		Throughput: m[6],
+	}, true
+}
+
+// progressReportMu keeps reports in order; a report older than the last one
+// sent is dropped.
+var (
+	progressReportMu   sync.Mutex
+	lastProgressReport time.Time
+)
+
+// notifyProgress forwards clone progress to project-service for the
+// dashboard.
+func notifyProgress(p cloneProgress) {
+	if cfg.ProgressURL == "" || cfg.CallbackToken == "" {
+		return
+	}
+	progressReportMu.Lock()
+	defer progressReportMu.Unlock()
+	if p.UpdatedAt.Before(lastProgressReport) {
+		return
+	}
+	lastProgressReport = p.UpdatedAt
+	body, err := json.Marshal(map[string]interface{}{
+		"id":      cfg.AtlasID,
+		"phase":   p.Phase,
+		"percent": p.Percent,
+		"overall": p.Overall,
+		"message": p.Message,
+	})
+	if err != nil {
+		return
+	}
+	req, _ := http.NewRequest(http.MethodPost, cfg.ProgressURL, bytes.NewReader(body))
+	req.Header.Set("Authorization", cfg.CallbackToken)
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Failed to report clone progress: %v", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// cloneProgressHandler streams clone progress events as JSON over a
+// WebSocket, starting with the current state, and closes once the clone
+// has finished or failed.
+func cloneProgressHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logWithRequestID(r, "WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ch, current := subscribeCloneProgress()
+	defer unsubscribeCloneProgress(ch)
+
+	// Reader only exists to notice the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	p := current
+	for {
+		if err := conn.WriteJSON(p); err != nil {
+			logWithRequestID(r, "Clone progress write error: %v", err)
+			return
+		}
+		if p.finished() {
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, p.Phase))
+			return
+		}
+		select {
+		case p = <-ch:
+		case <-closed:
+			return
+		}
+	}
+}
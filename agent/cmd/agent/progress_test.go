@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestParseGitProgress(t *testing.T) {
+	tests := []struct {
+		line string
+		want cloneProgress
+	}{
+		{"remote: Enumerating objects: 100% (1234/1234), done.", cloneProgress{Phase: phaseCounting, Percent: 100, Current: 1234, Total: 1234}},
+		{"remote: Counting objects:  45% (55/123)", cloneProgress{Phase: phaseCounting, Percent: 45, Current: 55, Total: 123}},
+		{"remote: Compressing objects:   3% (2/60)", cloneProgress{Phase: phaseCompressing, Percent: 3, Current: 2, Total: 60}},
+		{"Receiving objects:  37% (46/123), 1.20 MiB | 2.31 MiB/s", cloneProgress{Phase: phaseReceiving, Percent: 37, Current: 46, Total: 123, Received: "1.20 MiB", Throughput: "2.31 MiB/s"}},
+		{"Receiving objects: 100% (123/123), 4.00 KiB | 4.00 KiB/s, done.", cloneProgress{Phase: phaseReceiving, Percent: 100, Current: 123, Total: 123, Received: "4.00 KiB", Throughput: "4.00 KiB/s"}},
+		{"Resolving deltas:  12% (7/58)", cloneProgress{Phase: phaseResolving, Percent: 12, Current: 7, Total: 58}},
+		{"Updating files:  90% (9/10)", cloneProgress{Phase: phaseCheckout, Percent: 90, Current: 9, Total: 10}},
+	}
+	for _, tt := range tests {
+		got, ok := parseGitProgress(tt.line)
+		if !ok || got != tt.want {
+			t.Errorf("parseGitProgress(%q) = %+v, %v, want %+v", tt.line, got, ok, tt.want)
+		}
+	}
+
+	for _, line := range []string{
+		"",
+		"Cloning into '/workspace'...",
+		"remote: Total 1234 (delta 56), reused 0 (delta 0), pack-reused 0",
+		"Writing objects:  50% (1/2)",
+		"fatal: repository not found",
+	} {
+		if got, ok := parseGitProgress(line); ok {
+			t.Errorf("parseGitProgress(%q) = %+v, want no match", line, got)
+		}
+	}
+}
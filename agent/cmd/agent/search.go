@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	defaultSearchLimit = 500
+	maxSearchLimit     = 5000
+	maxSearchContext   = 10
+	// Files larger than this are skipped by content search
+	maxSearchFileSize = 5 << 20 // 5MB
+	// Matched lines are truncated to this many bytes in results
+	maxSearchLineLen = 400
+	// Overall budget for one search request
+	searchTimeout = 60 * time.Second
+)
+
+// searchResult is one NDJSON line of a /search response.
+type searchResult struct {
+	Type      string   `json:"type"` // match | file | done | error
+	Path      string   `json:"path,omitempty"`
+	Line      int      `json:"line,omitempty"`
+	Column    int      `json:"column,omitempty"`
+	Text      string   `json:"text,omitempty"`
+	Before    []string `json:"before,omitempty"`
+	After     []string `json:"after,omitempty"`
+	Score     int      `json:"score,omitempty"`
+	Matches   int      `json:"matches,omitempty"`
+	Files     int      `json:"files,omitempty"`
+	Truncated bool     `json:"truncated,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type searchOptions struct {
+	query   string
+	root    string // workspace-relative directory, "" for everything
+	include []string
+	exclude []string
+	limit   int
+	context int
+	matcher *regexp.Regexp
+	ignored bool
+}
+
+// searchHandler streams content matches (mode=content, the default) or
+// fuzzy filename matches (mode=files) as newline-delimited JSON.
+//
+// Parameters: q, mode, regex, case, word, include/exclude (comma separated
+// globs, ** supported), path, limit, context, showIgnored.
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	q := r.URL.Query()
+	opts := searchOptions{
+		query:   q.Get("q"),
+		include: splitGlobs(q.Get("include")),
+		exclude: splitGlobs(q.Get("exclude")),
+		limit:   defaultSearchLimit,
+		ignored: q.Get("showIgnored") == "true",
+	}
+	if opts.query == "" {
+		http.Error(w, "q required", 400)
+		return
+	}
+	if p := strings.Trim(q.Get("path"), "/"); p != "" {
+		if _, err := resolveWorkspacePath(p); err != nil {
+			pathError(w, r, p, err)
+			return
+		}
+		opts.root = filepath.Clean(p)
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid limit", 400)
+			return
+		}
+		opts.limit = min(n, maxSearchLimit)
+	}
+	if v := q.Get("context"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid context", 400)
+			return
+		}
+		opts.context = min(n, maxSearchContext)
+	}
+
+	mode := q.Get("mode")
+	if mode == "" {
+		mode = "content"
+	}
+	if mode != "content" && mode != "files" {
+		http.Error(w, "mode must be content or files", 400)
+		return
+	}
+	if mode == "content" {
+		pattern := opts.query
+		if q.Get("regex") != "true" {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		if q.Get("word") == "true" {
+			pattern = `\b(?:` + pattern + `)\b`
+		}
+		if q.Get("case") != "true" {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			http.Error(w, "invalid regex: "+err.Error(), 400)
+			return
+		}
+		opts.matcher = re
+	}
+
+	files, err := searchableFiles(opts)
+	if err != nil {
+		logWithRequestID(r, "Failed to enumerate files for search: %v", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	// Searches can outlive the server's default write timeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Now().Add(searchTimeout + 5*time.Second))
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+
+	var done searchResult
+	if mode == "files" {
+		done = searchFileNames(enc, files, opts)
+	} else {
+		done = searchContents(r, enc, rc, files, opts)
+	}
+	done.Type = "done"
+	enc.Encode(done)
+	logWithRequestID(r, "Search %q (%s) finished: %d results", opts.query, mode, done.Matches)
+}
+
+func splitGlobs(v string) []string {
+	var globs []string
+	for _, g := range strings.Split(v, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			globs = append(globs, g)
+		}
+	}
+	return globs
+}
+
+// searchableFiles returns workspace-relative file paths under opts.root,
+// honouring .gitignore and the include/exclude globs.
+func searchableFiles(opts searchOptions) ([]string, error) {
+	var all []string
+	args := []string{"ls-files", "-z", "--cached", "--others"}
+	if !opts.ignored {
+		args = append(args, "--exclude-standard")
+	}
+	if opts.root != "" {
+		args = append(args, "--", opts.root)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = "/workspace"
+	if out, err := cmd.Output(); err == nil {
+		seen := map[string]bool{}
+		for _, p := range bytes.Split(out, []byte{0}) {
+			// Files both tracked and modified can be listed twice
+			if len(p) > 0 && !seen[string(p)] {
+				seen[string(p)] = true
+				all = append(all, string(p))
+			}
+		}
+	} else {
+		// Not a git repository; fall back to a plain walk that skips .git
+		base := filepath.Join("/workspace", opts.root)
+		err := filepath.WalkDir(base, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() && d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if d.Type().IsRegular() {
+				all = append(all, strings.TrimPrefix(path, "/workspace/"))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files := all[:0]
+	for _, p := range all {
+		if len(opts.include) > 0 && !matchAnyGlob(opts.include, p) {
+			continue
+		}
+		if matchAnyGlob(opts.exclude, p) || pathDenied(p) {
+			continue
+		}
+		files = append(files, p)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// matchAnyGlob matches against the full relative path and, for patterns
+// without a slash, against the base name too.
+func matchAnyGlob(globs []string, path string) bool {
+	for _, g := range globs {
+		if globMatch(g, path) {
+			return true
+		}
+		if !strings.Contains(g, "/") && globMatch(g, filepath.Base(path)) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch supports *, ? and ** (any number of path segments); [...]
+// classes work in patterns without **.
+func globMatch(pattern, path string) bool {
+	if !strings.Contains(pattern, "**") {
+		ok, _ := filepath.Match(pattern, path)
+		if !ok && strings.HasSuffix(pattern, "/") {
+			// "dir/" matches everything beneath dir
+			return strings.HasPrefix(path, pattern)
+		}
+		return ok
+	}
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			i++
+			if i+1 < len(pattern) && pattern[i+1] == '/' {
+				i++
+				re.WriteString("(?:.*/)?")
+			} else {
+				re.WriteString(".*")
+			}
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+	ok, _ := regexp.MatchString(re.String(), path)
+	return ok
+}
+
+func searchContents(r *http.Request, enc *json.Encoder, rc *http.ResponseController, files []string, opts searchOptions) searchResult {
+	var summary searchResult
+	deadline := time.Now().Add(searchTimeout)
+	for _, rel := range files {
+		if r.Context().Err() != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			summary.Truncated = true
+			enc.Encode(searchResult{Type: "error", Error: "search timed out"})
+			break
+		}
+		n, err := searchFile(enc, rel, opts, opts.limit-summary.Matches)
+		if err != nil {
+			continue
+		}
+		if n > 0 {
+			summary.Files++
+			summary.Matches += n
+			_ = rc.Flush()
+		}
+		if summary.Matches >= opts.limit {
+			summary.Truncated = true
+			break
+		}
+	}
+	return summary
+}
+
+// searchFile emits up to budget matches from one file and returns how many
+// it emitted. Binary and oversized files are skipped.
+func searchFile(enc *json.Encoder, rel string, opts searchOptions, budget int) (int, error) {
+	// The listing was filtered by name; check the path policy again where
+	// the file actually is, in case a directory on the way is a symlink
+	fullPath, err := resolveWorkspacePath(rel)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Lstat(fullPath)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxSearchFileSize {
+		return 0, err
+	}
+	data, err := os.ReadFile(fullPath)
+	if err != nil {
+		return 0, err
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) != -1 {
+		return 0, nil
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), maxSearchFileSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	found := 0
+	for i, line := range lines {
+		loc := opts.matcher.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		res := searchResult{
+			Type:   "match",
+			Path:   "/" + rel,
+			Line:   i + 1,
+			Column: loc[0] + 1,
+			Text:   truncateLine(line),
+		}
+		if opts.context > 0 {
+			for j := max(0, i-opts.context); j < i; j++ {
+				res.Before = append(res.Before, truncateLine(lines[j]))
+			}
+			for j := i + 1; j < min(len(lines), i+1+opts.context); j++ {
+				res.After = append(res.After, truncateLine(lines[j]))
+			}
+		}
+		if err := enc.Encode(res); err != nil {
+			return found, err
+		}
+		found++
+		if found >= budget {
+			break
+		}
+	}
+	return found, nil
+}
+
+func truncateLine(s string) string {
+	if len(s) <= maxSearchLineLen {
+		return s
+	}
+	return s[:maxSearchLineLen]
+}
+
+// searchFileNames ranks files by fuzzy match of the query against their path.
+func searchFileNames(enc *json.Encoder, files []string, opts searchOptions) searchResult {
+	type scored struct {
+		path  string
+		score int
+	}
+	var hits []scored
+	for _, rel := range files {
+		if score, ok := fuzzyScore(opts.query, rel); ok {
+			hits = append(hits, scored{rel, score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return len(hits[i].path) < len(hits[j].path)
+	})
+
+	summary := searchResult{Matches: min(len(hits), opts.limit), Truncated: len(hits) > opts.limit}
+	for _, h := range hits[:summary.Matches] {
+		enc.Encode(searchResult{Type: "file", Path: "/" + h.path, Score: h.score})
+	}
+	summary.Files = summary.Matches
+	return summary
+}
+
+// fuzzyScore reports whether every rune of query appears in path in order
+// (case-insensitively) and scores the match: consecutive runs, matches at
+// segment starts and matches inside the base name rank higher.
+func fuzzyScore(query, path string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	p := []rune(path)
+	baseStart := strings.LastIndex(path, "/") + 1
+	baseStart = len([]rune(path[:baseStart]))
+
+	score, qi, prev := 0, 0, -2
+	for pi := 0; pi < len(p) && qi < len(q); pi++ {
+		if unicode.ToLower(p[pi]) != q[qi] {
+			continue
+		}
+		points := 1
+		if pi == prev+1 {
+			points += 5
+		}
+		if pi == 0 || strings.ContainsRune("/_-. ", p[pi-1]) || (unicode.IsUpper(p[pi]) && unicode.IsLower(p[pi-1])) {
+			points += 8
+		}
+		if pi >= baseStart {
+			points += 2
+		}
+		score += points
+		prev = pi
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	// Prefer shorter paths for equal quality
+	return score*10 - len(p)/4, true
+}
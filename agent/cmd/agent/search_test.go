@@ -0,0 +1,114 @@
+package main
+
+import "testing"
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"cmd/*.go", "cmd/main.go", true},
+		{"cmd/*.go", "cmd/agent/main.go", false},
+		{"?.txt", "a.txt", true},
+		{"?.txt", "ab.txt", false},
+		{"[ab].txt", "b.txt", true},
+		{"[ab].txt", "c.txt", false},
+		{"vendor/", "vendor/x/y.go", true},
+		{"vendor/", "src/vendor/y.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/c/main.go", true},
+		{"**/*.go", "a/b/main.gox", false},
+		{"src/**/*.ts", "src/app.ts", true},
+		{"src/**/*.ts", "src/a/b/app.ts", true},
+		{"src/**/*.ts", "lib/src/app.ts", false},
+		{"src/**", "src/a/b", true},
+		{"**/test/**", "pkg/test/x_test.go", true},
+		{"**/?.md", "docs/a.md", true},
+		{"**/?.md", "docs/ab.md", false},
+		{"**/*.go", "a/b/main.go.orig", false},
+		// Regex metacharacters in the pattern are literal
+		{"**/a+b.txt", "x/a+b.txt", true},
+		{"**/a+b.txt", "x/aab.txt", false},
+		{"**/(x).go", "y/(x).go", true},
+	}
+	for _, tt := range tests {
+		if got := globMatch(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMatchAnyGlob(t *testing.T) {
+	tests := []struct {
+		globs []string
+		path  string
+		want  bool
+	}{
+		{nil, "main.go", false},
+		// Patterns without a slash also match the base name
+		{[]string{"*.go"}, "cmd/agent/main.go", true},
+		{[]string{"*.md", "*.go"}, "cmd/main.go", true},
+		{[]string{"cmd/*.go"}, "x/cmd/main.go", false},
+		{[]string{"node_modules/"}, "node_modules/x/index.js", true},
+	}
+	for _, tt := range tests {
+		if got := matchAnyGlob(tt.globs, tt.path); got != tt.want {
+			t.Errorf("matchAnyGlob(%q, %q) = %v, want %v", tt.globs, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query string
+		path  string
+		want  bool
+	}{
+		{"main", "cmd/agent/main.go", true},
+		{"cam", "cmd/agent/main.go", true},
+		{"MAIN", "cmd/agent/main.go", true},
+		{"mian", "cmd/agent/main.go", false},
+		{"xyz", "cmd/agent/main.go", false},
+		{"", "anything", true},
+		{"résumé", "docs/Résumé.md", true},
+	}
+	for _, tt := range tests {
+		if _, got := fuzzyScore(tt.query, tt.path); got != tt.want {
+			t.Errorf("fuzzyScore(%q, %q) matched = %v, want %v", tt.query, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyScoreRanking(t *testing.T) {
+	// Each pair is (better, worse) for the query
+	tests := []struct {
+		query  string
+		better string
+		worse  string
+	}{
+		// Consecutive runs beat scattered matches
+		{"main", "src/main.go", "src/mxaxixn.go"},
+		// Segment starts beat mid-word matches
+		{"fb", "src/foo_bar.go", "src/afxxbx.go"},
+		// camelCase humps count as segment starts
+		{"fb", "src/fooBar.go", "src/foobar.go"},
+		// Matches in the base name beat matches in directories
+		{"util", "pkg/util.go", "util/pkg.go"},
+		// Equal matches prefer the shorter path
+		{"main", "main.go", "cmd/very/long/path/to/main.go"},
+	}
+	for _, tt := range tests {
+		b, okB := fuzzyScore(tt.query, tt.better)
+		w, okW := fuzzyScore(tt.query, tt.worse)
+		if !okB || !okW {
+			t.Errorf("%q: expected both %q and %q to match", tt.query, tt.better, tt.worse)
+			continue
+		}
+		if b <= w {
+			t.Errorf("%q: %q scored %d, not above %q at %d", tt.query, tt.better, b, tt.worse, w)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+const (
+	// Scrollback kept per session and replayed to viewers on attach
+	scrollbackSize = 256 << 10 // 256KB
+	// Upper bound on concurrently running terminal sessions
+	maxSessions = 16
+	// Pending output chunks buffered per viewer before it is dropped
+	viewerQueueSize = 256
+	// Session attached to by clients that don't name one
+	defaultSessionName = "default"
+)
+
+var (
+	errTooManySessions = errors.New("too many terminal sessions")
+	errSessionExists   = errors.New("session name already in use")
+
+	sessionsMu sync.Mutex
+	sessions   = map[string]*terminalSession{}
+)
+
+// ringBuffer keeps the last len(buf) bytes written to it.
+type ringBuffer struct {
+	buf  []byte
+	pos  int
+	full bool
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{buf: make([]byte, size)}
+}
+
+func (rb *ringBuffer) Write(p []byte) {
+	if len(p) >= len(rb.buf) {
+		copy(rb.buf, p[len(p)-len(rb.buf):])
+		rb.pos = 0
+		rb.full = true
+		return
+	}
+	n := copy(rb.buf[rb.pos:], p)
+	if n < len(p) {
+		copy(rb.buf, p[n:])
+		rb.full = true
+	}
+	rb.pos = (rb.pos + len(p)) % len(rb.buf)
+	if rb.pos == 0 && len(p) > 0 {
+		rb.full = true
+	}
+}
+
+// Bytes returns a copy of the buffered data in write order.
+func (rb *ringBuffer) Bytes() []byte {
+	if !rb.full {
+		return append([]byte(nil), rb.buf[:rb.pos]...)
+	}
+	out := make([]byte, 0, len(rb.buf))
+	out = append(out, rb.buf[rb.pos:]...)
+	return append(out, rb.buf[:rb.pos]...)
+}
+
+// sessionViewer is a single attached client of a terminal session.
+type sessionViewer struct {
+	out  chan []byte
+	done chan struct{}
+	once sync.Once
+}
+
+func (v *sessionViewer) close() {
+	v.once.Do(func() { close(v.done) })
+}
+
+// terminalSession is a server-side PTY that outlives the websocket
+// connections attached to it.
+type terminalSession struct {
+	ID        string
+	Name      string
+	CreatedAt time.Time
+
+	cmd  *exec.Cmd
+	ptmx *os.File
+
+	mu         sync.Mutex
+	scrollback *ringBuffer
+	viewers    map[*sessionViewer]struct{}
+	lastActive time.Time
+	exited     chan struct{}
+	exitCode   int
+}
+
+type sessionInfo struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastActive time.Time `json:"lastActive"`
+	Viewers    int       `json:"viewers"`
+	Pid        int       `json:"pid"`
+}
+
+func (s *terminalSession) info() sessionInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := sessionInfo{
+		ID:         s.ID,
+		Name:       s.Name,
+		CreatedAt:  s.CreatedAt,
+		LastActive: s.lastActive,
+		Viewers:    len(s.viewers),
+	}
+	if s.cmd.Process != nil {
+		info.Pid = s.cmd.Process.Pid
+	}
+	return info
+}
+
+// createSession starts a new shell under a PTY and registers it.
+func createSession(name string) (*terminalSession, error) {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	if len(sessions) >= maxSessions {
+		return nil, errTooManySessions
+	}
+	id := generateRequestID()
+	if name == "" {
+		name = id
+	}
+	for _, s := range sessions {
+		if s.Name == name {
+			return nil, errSessionExists
+		}
+	}
+
+	cmd := exec.Command("/bin/bash")
+	cmd.Dir = "/workspace"
+	cmd.Env = workspaceEnviron()
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &terminalSession{
+		ID:         id,
+		Name:       name,
+		CreatedAt:  time.Now(),
+		cmd:        cmd,
+		ptmx:       ptmx,
+		scrollback: newRingBuffer(scrollbackSize),
+		viewers:    map[*sessionViewer]struct{}{},
+		lastActive: time.Now(),
+		exited:     make(chan struct{}),
+	}
+	sessions[id] = s
+	go s.readLoop()
+	log.Printf("Terminal session %s (%s) started", s.ID, s.Name)
+	return s, nil
+}
+
+// findSession looks a session up by ID or name.
+func findSession(ref string) *terminalSession {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if s, ok := sessions[ref]; ok {
+		return s
+	}
+	for _, s := range sessions {
+		if s.Name == ref {
+			return s
+		}
+	}
+	return nil
+}
+
+// getOrCreateSession attaches to a named session, starting it if needed.
+func getOrCreateSession(name string) (*terminalSession, error) {
+	if s := findSession(name); s != nil {
+		return s, nil
+	}
+	s, err := createSession(name)
+	if errors.Is(err, errSessionExists) {
+		// Lost a race with another attach for the same name
+		if s := findSession(name); s != nil {
+			return s, nil
+		}
+	}
+	return s, err
+}
+
+func listSessions() []sessionInfo {
+	sessionsMu.Lock()
+	list := make([]*terminalSession, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, s)
+	}
+	sessionsMu.Unlock()
+
+	infos := make([]sessionInfo, 0, len(list))
+	for _, s := range list {
+		infos = append(infos, s.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CreatedAt.Before(infos[j].CreatedAt) })
+	return infos
+}
+
+// readLoop fans PTY output out to the scrollback and every viewer until
+// the shell exits.
+func (s *terminalSession) readLoop() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := s.ptmx.Read(buf)
+		if n > 0 {
+			chunk := append([]byte(nil), buf[:n]...)
+			s.mu.Lock()
+			s.scrollback.Write(chunk)
+			s.lastActive = time.Now()
+			for v := range s.viewers {
+				select {
+				case v.out <- chunk:
+				default:
+					// Viewer can't keep up; drop it rather than stall the shell
+					delete(s.viewers, v)
+					v.close()
+				}
+			}
+			s.mu.Unlock()
+		}
+		if err != nil {
+			break
+		}
+	}
+	_ = s.cmd.Wait()
+	s.exitCode = s.cmd.ProcessState.ExitCode()
+	s.teardown()
+	log.Printf("Terminal session %s (%s) exited", s.ID, s.Name)
+}
+
+func (s *terminalSession) teardown() {
+	sessionsMu.Lock()
+	delete(sessions, s.ID)
+	sessionsMu.Unlock()
+
+	// Mark exited before releasing viewers so they can report the exit code
+	close(s.exited)
+	s.mu.Lock()
+	for v := range s.viewers {
+		delete(s.viewers, v)
+		v.close()
+	}
+	s.mu.Unlock()
+	_ = s.ptmx.Close()
+}
+
+// attach registers a viewer and returns it with the scrollback snapshot to
+// replay; no output is lost or duplicated between the two.
+func (s *terminalSession) attach() (*sessionViewer, []byte) {
+	v := &sessionViewer{
+		out:  make(chan []byte, viewerQueueSize),
+		done: make(chan struct{}),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.viewers[v] = struct{}{}
+	s.lastActive = time.Now()
+	return v, s.scrollback.Bytes()
+}
+
+func (s *terminalSession) detach(v *sessionViewer) {
+	s.mu.Lock()
+	delete(s.viewers, v)
+	s.mu.Unlock()
+	v.close()
+}
+
+// detachAll disconnects every viewer but leaves the shell running.
+func (s *terminalSession) detachAll() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.viewers)
+	for v := range s.viewers {
+		delete(s.viewers, v)
+		v.close()
+	}
+	return n
+}
+
+// exitStatus reports the shell's exit code once it has exited.
+func (s *terminalSession) exitStatus() (int, bool) {
+	select {
+	case <-s.exited:
+		return s.exitCode, true
+	default:
+		return 0, false
+	}
+}
+
+func (s *terminalSession) write(p []byte) error {
+	s.mu.Lock()
+	s.lastActive = time.Now()
+	s.mu.Unlock()
+	_, err := s.ptmx.Write(p)
+	return err
+}
+
+// kill terminates the shell; readLoop performs the cleanup.
+func (s *terminalSession) kill() {
+	if s.cmd.Process != nil {
+		_ = s.cmd.Process.Kill()
+	}
+	select {
+	case <-s.exited:
+	case <-time.After(5 * time.Second):
+		log.Printf("Terminal session %s did not exit after kill", s.ID)
+	}
+}
+
+func killAllSessions() {
+	sessionsMu.Lock()
+	list := make([]*terminalSession, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, s)
+	}
+	sessionsMu.Unlock()
+	for _, s := range list {
+		s.kill()
+	}
+}
+
+// sessionsHandler lists (GET) and creates (POST) terminal sessions.
+func sessionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(listSessions())
+	case http.MethodPost:
+		if !isReady() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		var body struct {
+			Name string `json:"name"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid request body", 400)
+				return
+			}
+		}
+		s, err := createSession(body.Name)
+		if err != nil {
+			switch {
+			case errors.Is(err, errSessionExists):
+				http.Error(w, err.Error(), http.StatusConflict)
+			case errors.Is(err, errTooManySessions):
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+			default:
+				logWithRequestID(r, "Failed to create terminal session: %v", err)
+				http.Error(w, err.Error(), 500)
+			}
+			return
+		}
+		logWithRequestID(r, "Created terminal session %s (%s)", s.ID, s.Name)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(s.info())
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+// sessionDetachHandler drops all viewers of a session without killing it.
+func sessionDetachHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	s := findSession(r.URL.Query().Get("id"))
+	if s == nil {
+		http.Error(w, "session not found", 404)
+		return
+	}
+	n := s.detachAll()
+	logWithRequestID(r, "Detached %d viewers from terminal session %s", n, s.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": s.ID, "detached": n})
+}
+
+// sessionKillHandler terminates a session's shell.
+func sessionKillHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	s := findSession(r.URL.Query().Get("id"))
+	if s == nil {
+		http.Error(w, "session not found", 404)
+		return
+	}
+	s.kill()
+	logWithRequestID(r, "Killed terminal session %s (%s)", s.ID, s.Name)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": s.ID, "killed": true})
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRingBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		writes []string
+		want   string
+	}{
+		{"empty", 8, nil, ""},
+		{"partial", 8, []string{"abc"}, "abc"},
+		{"exactly full", 8, []string{"abcd", "efgh"}, "abcdefgh"},
+		{"wraps", 8, []string{"abcdef", "ghij"}, "cdefghij"},
+		{"wraps twice", 4, []string{"ab", "cd", "ef", "g"}, "defg"},
+		{"write larger than buffer", 4, []string{"ab", "0123456789"}, "6789"},
+		{"write of buffer size", 4, []string{"xy", "abcd"}, "abcd"},
+		{"empty write", 4, []string{"ab", "", "c"}, "abc"},
+	}
+	for _, tt := range tests {
+		rb := newRingBuffer(tt.size)
+		for _, w := range tt.writes {
+			rb.Write([]byte(w))
+		}
+		if got := string(rb.Bytes()); got != tt.want {
+			t.Errorf("%s: Bytes() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRingBufferBytesIsCopy(t *testing.T) {
+	rb := newRingBuffer(4)
+	rb.Write([]byte("abcd"))
+	out := rb.Bytes()
+	out[0] = 'X'
+	if got := string(rb.Bytes()); got != "abcd" {
+		t.Errorf("Bytes() shares memory with the buffer: %q", got)
+	}
+}
+
+func TestSessionViewers(t *testing.T) {
+	s := startTestSession(t)
+
+	v1, _ := s.attach()
+	v2, _ := s.attach()
+	if n := s.info().Viewers; n != 2 {
+		t.Fatalf("viewers = %d, want 2", n)
+	}
+
+	// Output reaches every viewer
+	if err := s.write([]byte("echo fan-out\n")); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range []*sessionViewer{v1, v2} {
+		if !waitForOutput(v, "fan-out") {
+			t.Errorf("viewer %d did not see the output", i+1)
+		}
+	}
+
+	s.detach(v1)
+	if !isClosed(v1.done) {
+		t.Error("detached viewer is still open")
+	}
+	if n := s.info().Viewers; n != 1 {
+		t.Errorf("viewers after detach = %d, want 1", n)
+	}
+	if n := s.detachAll(); n != 1 {
+		t.Errorf("detachAll = %d, want 1", n)
+	}
+	if !isClosed(v2.done) {
+		t.Error("viewer is still open after detachAll")
+	}
+	// Detaching leaves the shell running
+	if _, exited := s.exitStatus(); exited {
+		t.Error("session exited after its viewers detached")
+	}
+}
+
+func TestSessionScrollbackReplay(t *testing.T) {
+	s := startTestSession(t)
+	v, _ := s.attach()
+	if err := s.write([]byte("echo before-attach\n")); err != nil {
+		t.Fatal(err)
+	}
+	if !waitForOutput(v, "before-attach") {
+		t.Fatal("no output from the shell")
+	}
+	s.detach(v)
+
+	// A later viewer gets what it missed from the scrollback
+	v2, scrollback := s.attach()
+	defer s.detach(v2)
+	if !bytes.Contains(scrollback, []byte("before-attach")) {
+		t.Errorf("scrollback %q is missing earlier output", scrollback)
+	}
+}
+
+func TestSessionExit(t *testing.T) {
+	s := startTestSession(t)
+	v, _ := s.attach()
+	if err := s.write([]byte("exit 7\n")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-v.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("viewer not released when the shell exited")
+	}
+	if code, exited := s.exitStatus(); !exited || code != 7 {
+		t.Errorf("exitStatus = %d, %v, want 7, true", code, exited)
+	}
+	if findSession(s.ID) != nil {
+		t.Error("exited session is still registered")
+	}
+}
+
+func TestTerminalReattach(t *testing.T) {
+	setTestReady(t)
+	name := "reattach-" + generateRequestID()
+
+	conn := dialTerminal(t, "session="+name, false)
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("echo first-viewer\n")); err != nil {
+		t.Fatal(err)
+	}
+	readUntil(t, conn, "first-viewer")
+	conn.Close()
+
+	// Reconnecting to the same session replays its scrollback
+	conn = dialTerminal(t, "session="+name, false)
+	defer conn.Close()
+	readUntil(t, conn, "first-viewer")
+
+	s := findSession(name)
+	if s == nil {
+		t.Fatal("session was not kept after the first viewer left")
+	}
+	t.Cleanup(s.kill)
+}
+
+// startTestSession starts a shell session that is killed when the test ends.
+func startTestSession(t *testing.T) *terminalSession {
+	t.Helper()
+	s, err := createSession("test-" + generateRequestID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.kill)
+	return s
+}
+
+// waitForOutput reads a viewer's output until it contains want.
+func waitForOutput(v *sessionViewer, want string) bool {
+	var seen []byte
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case chunk := <-v.out:
+			seen = append(seen, chunk...)
+			if bytes.Contains(seen, []byte(want)) {
+				return true
+			}
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func setTestReady(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	wasReady := ready
+	ready = true
+	mu.Unlock()
+	savedOrigins := cfg.AllowedOrigins
+	cfg.AllowedOrigins = parseAllowedOrigins(defaultAllowedOrigins)
+	t.Cleanup(func() {
+		mu.Lock()
+		ready = wasReady
+		mu.Unlock()
+		cfg.AllowedOrigins = savedOrigins
+	})
+}
+
+// dialTerminal connects to terminalHandler, optionally speaking the framed
+// protocol.
+func dialTerminal(t *testing.T, query string, framed bool) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(terminalHandler))
+	t.Cleanup(srv.Close)
+	dialer := websocket.Dialer{}
+	if framed {
+		dialer.Subprotocols = []string{terminalSubprotocol}
+	}
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/terminal?" + query
+	conn, resp, err := dialer.Dial(url, http.Header{"Origin": {"http://localhost:5173"}})
+	if err != nil {
+		t.Fatalf("dial terminal: %v", err)
+	}
+	if framed && resp.Header.Get("Sec-WebSocket-Protocol") != terminalSubprotocol {
+		t.Fatal("server did not accept the framed protocol")
+	}
+	return conn
+}
+
+// readUntil reads data frames until their text contains want.
+func readUntil(t *testing.T, conn *websocket.Conn, want string) {
+	t.Helper()
+	var seen []byte
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for !bytes.Contains(seen, []byte(want)) {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %q: %v (got %q)", want, err, seen)
+		}
+		seen = append(seen, msg...)
+	}
+}
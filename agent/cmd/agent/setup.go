@@ -0,0 +1,530 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Workspace config files, in order of preference. The first one found in the
+// repository wins.
+var workspaceConfigFiles = []string{
+	".codenest.yml",
+	".codenest.yaml",
+	".devcontainer/devcontainer.json",
+	".devcontainer.json",
+}
+
+const setupTimeout = 30 * time.Minute
+
+// workspaceConfig is the project-defined bootstrap read from .codenest.yml:
+//
+//	setup:
+//	  - npm install
+//	  - name: Go modules
+//	    run: go mod download
+//	    cwd: api
+//	env:
+//	  NODE_ENV: development
+//	ports:
+//	  - 3000
+//	  - port: 5173
+//	    label: Vite
+//	tasks:
+//	  dev: npm run dev
+//	  test:
+//	    command: go test ./...
+//	    cwd: api
+type workspaceConfig struct {
+	Source string                `json:"source,omitempty" yaml:"-"`
+	Setup  []setupStep           `json:"setup,omitempty" yaml:"setup"`
+	Env    map[string]string     `json:"env,omitempty" yaml:"env"`
+	Ports  []portConfig          `json:"ports,omitempty" yaml:"ports"`
+	Tasks  map[string]taskConfig `json:"tasks,omitempty" yaml:"tasks"`
+	// Language server commands by language, overriding the built-in ones
+	LanguageServers map[string]string `json:"languageServers,omitempty" yaml:"languageServers"`
+	// Debug adapter commands by name; "{port}" makes the agent connect over
+	// TCP instead of stdio
+	DebugAdapters map[string]string `json:"debugAdapters,omitempty" yaml:"debugAdapters"`
+}
+
+// setupStep is one setup command. Run goes through sh -c; Args, used for
+// devcontainer.json array commands, is executed directly.
+type setupStep struct {
+	Name string   `json:"name,omitempty" yaml:"name"`
+	Run  string   `json:"run,omitempty" yaml:"run"`
+	Args []string `json:"args,omitempty" yaml:"-"`
+	Cwd  string   `json:"cwd,omitempty" yaml:"cwd"`
+}
+
+func (s *setupStep) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Run = node.Value
+		return nil
+	}
+	type plain setupStep
+	return node.Decode((*plain)(s))
+}
+
+func (s setupStep) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	if len(s.Args) > 0 {
+		return strings.Join(s.Args, " ")
+	}
+	return s.Run
+}
+
+// portConfig describes a port the project expects to serve on.
+type portConfig struct {
+	Port       int    `json:"port" yaml:"port"`
+	Label      string `json:"label,omitempty" yaml:"label"`
+	Protocol   string `json:"protocol,omitempty" yaml:"protocol"`
+	Visibility string `json:"visibility,omitempty" yaml:"visibility"`
+}
+
+func (p *portConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&p.Port)
+	}
+	type plain portConfig
+	return node.Decode((*plain)(p))
+}
+
+// taskConfig is a named command the user can start on demand.
+type taskConfig struct {
+	Command string            `json:"command" yaml:"command"`
+	Cwd     string            `json:"cwd,omitempty" yaml:"cwd"`
+	Env     map[string]string `json:"env,omitempty" yaml:"env"`
+}
+
+func (t *taskConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		t.Command = node.Value
+		return nil
+	}
+	type plain taskConfig
+	return node.Decode((*plain)(t))
+}
+
+// Setup states, reported by /health and /workspace/config.
+const (
+	setupPending = "pending"
+	setupRunning = "running"
+	setupDone    = "done"
+	setupFailed  = "failed"
+	setupSkipped = "skipped" // no config file or no setup commands
+)
+
+type setupStatus struct {
+	State    string `json:"state"`
+	Step     int    `json:"step,omitempty"` // 1-based index of the running or failed step
+	Steps    int    `json:"steps,omitempty"`
+	Command  string `json:"command,omitempty"`
+	ExitCode int    `json:"exitCode,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// workspaceState holds the loaded workspace config and setup progress.
+var workspaceState = struct {
+	sync.RWMutex
+	config workspaceConfig
+	env    []string // config env as KEY=value, appended to os.Environ()
+	setup  setupStatus
+}{
+	setup: setupStatus{State: setupPending},
+}
+
+func currentWorkspaceConfig() workspaceConfig {
+	workspaceState.RLock()
+	defer workspaceState.RUnlock()
+	return workspaceState.config
+}
+
+func currentSetupStatus() setupStatus {
+	workspaceState.RLock()
+	defer workspaceState.RUnlock()
+	return workspaceState.setup
+}
+
+func setSetupStatus(s setupStatus) {
+	workspaceState.Lock()
+	workspaceState.setup = s
+	workspaceState.Unlock()
+}
+
+// workspaceEnviron is the environment for setup commands, tasks and
+// terminal shells: the agent's own plus the project's env.
+func workspaceEnviron() []string {
+	workspaceState.RLock()
+	defer workspaceState.RUnlock()
+	return append(os.Environ(), workspaceState.env...)
+}
+
+// loadWorkspaceConfig reads the first workspace config file present in
+// /workspace. A missing file is not an error.
+func loadWorkspaceConfig(dir string) (workspaceConfig, error) {
+	for _, name := range workspaceConfigFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return workspaceConfig{}, err
+		}
+		var wc workspaceConfig
+		if strings.HasSuffix(name, ".json") {
+			wc, err = parseDevcontainer(data)
+		} else {
+			err = yaml.Unmarshal(data, &wc)
+		}
+		if err != nil {
+			return workspaceConfig{}, fmt.Errorf("%s: %v", name, err)
+		}
+		wc.Source = name
+		return wc, validateWorkspaceConfig(wc)
+	}
+	return workspaceConfig{}, nil
+}
+
+func validateWorkspaceConfig(wc workspaceConfig) error {
+	for i, step := range wc.Setup {
+		if strings.TrimSpace(step.Run) == "" && len(step.Args) == 0 {
+			return fmt.Errorf("%s: setup step %d has no command", wc.Source, i+1)
+		}
+		if step.Cwd != "" && !isValidPath(step.Cwd) {
+			return fmt.Errorf("%s: setup step %d: invalid cwd %q", wc.Source, i+1, step.Cwd)
+		}
+	}
+	for key := range wc.Env {
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			return fmt.Errorf("%s: invalid env name %q", wc.Source, key)
+		}
+	}
+	for _, p := range wc.Ports {
+		if p.Port < 1 || p.Port > 65535 {
+			return fmt.Errorf("%s: invalid port %d", wc.Source, p.Port)
+		}
+	}
+	for name, task := range wc.Tasks {
+		if strings.TrimSpace(task.Command) == "" {
+			return fmt.Errorf("%s: task %q has no command", wc.Source, name)
+		}
+		if task.Cwd != "" && !isValidPath(task.Cwd) {
+			return fmt.Errorf("%s: task %q: invalid cwd %q", wc.Source, name, task.Cwd)
+		}
+	}
+	for lang, command := range wc.LanguageServers {
+		if !validLanguage(lang) {
+			return fmt.Errorf("%s: invalid language %q", wc.Source, lang)
+		}
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("%s: language server for %q has no command", wc.Source, lang)
+		}
+	}
+	for name, command := range wc.DebugAdapters {
+		if !validLanguage(name) {
+			return fmt.Errorf("%s: invalid debug adapter name %q", wc.Source, name)
+		}
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("%s: debug adapter %q has no command", wc.Source, name)
+		}
+	}
+	return nil
+}
+
+// devcontainer is the subset of devcontainer.json the agent understands.
+type devcontainer struct {
+	OnCreateCommand      json.RawMessage   `json:"onCreateCommand"`
+	UpdateContentCommand json.RawMessage   `json:"updateContentCommand"`
+	PostCreateCommand    json.RawMessage   `json:"postCreateCommand"`
+	PostStartCommand     json.RawMessage   `json:"postStartCommand"`
+	ContainerEnv         map[string]string `json:"containerEnv"`
+	RemoteEnv            map[string]string `json:"remoteEnv"`
+	ForwardPorts         []json.RawMessage `json:"forwardPorts"`
+	PortsAttributes      map[string]struct {
+		Label    string `json:"label"`
+		Protocol string `json:"protocol"`
+	} `json:"portsAttributes"`
+}
+
+// parseDevcontainer maps devcontainer.json onto a workspace config: the
+// lifecycle commands become setup steps in the order a dev container runs
+// them, containerEnv and remoteEnv become env, and forwardPorts become ports.
+func parseDevcontainer(data []byte) (workspaceConfig, error) {
+	var dc devcontainer
+	if err := json.Unmarshal(stripJSONC(data), &dc); err != nil {
+		return workspaceConfig{}, err
+	}
+	var wc workspaceConfig
+	for _, raw := range []json.RawMessage{dc.OnCreateCommand, dc.UpdateContentCommand, dc.PostCreateCommand, dc.PostStartCommand} {
+		steps, err := devcontainerCommand(raw)
+		if err != nil {
+			return workspaceConfig{}, err
+		}
+		wc.Setup = append(wc.Setup, steps...)
+	}
+
+	if len(dc.ContainerEnv)+len(dc.RemoteEnv) > 0 {
+		wc.Env = map[string]string{}
+		for k, v := range dc.ContainerEnv {
+			wc.Env[k] = v
+		}
+		for k, v := range dc.RemoteEnv {
+			wc.Env[k] = v
+		}
+	}
+
+	for _, raw := range dc.ForwardPorts {
+		var port int
+		if err := json.Unmarshal(raw, &port); err != nil {
+			// "host:port" forwards from another container; only the
+			// workspace's own ports matter here
+			var s string
+			if json.Unmarshal(raw, &s) != nil {
+				return workspaceConfig{}, fmt.Errorf("invalid forwardPorts entry %s", raw)
+			}
+			host, p, ok := strings.Cut(s, ":")
+			if !ok || (host != "localhost" && host != "127.0.0.1") {
+				continue
+			}
+			if port, err = strconv.Atoi(p); err != nil {
+				return workspaceConfig{}, fmt.Errorf("invalid forwardPorts entry %q", s)
+			}
+		}
+		pc := portConfig{Port: port}
+		if attrs, ok := dc.PortsAttributes[strconv.Itoa(port)]; ok {
+			pc.Label, pc.Protocol = attrs.Label, attrs.Protocol
+		}
+		wc.Ports = append(wc.Ports, pc)
+	}
+	return wc, nil
+}
+
+// devcontainerCommand decodes a lifecycle command, which may be a shell
+// string, an argv array, or an object of named commands (run in name order
+// here rather than in parallel).
+func devcontainerCommand(raw json.RawMessage) ([]setupStep, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return []setupStep{{Run: s}}, nil
+	}
+	var args []string
+	if json.Unmarshal(raw, &args) == nil {
+		if len(args) == 0 {
+			return nil, nil
+		}
+		return []setupStep{{Args: args}}, nil
+	}
+	var named map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &named); err != nil {
+		return nil, fmt.Errorf("invalid lifecycle command %s", raw)
+	}
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var steps []setupStep
+	for _, name := range names {
+		sub, err := devcontainerCommand(named[name])
+		if err != nil {
+			return nil, err
+		}
+		for _, step := range sub {
+			step.Name = name
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+// stripJSONC removes // and /* */ comments and trailing commas, which
+// devcontainer.json allows.
+func stripJSONC(data []byte) []byte {
+	var out bytes.Buffer
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i+1 < len(data) && data[i+1] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+			out.WriteByte(' ')
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return dropTrailingCommas(out.Bytes())
+}
+
+// dropTrailingCommas removes commas directly followed by a closing bracket.
+func dropTrailingCommas(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			if c == '\\' && i+1 < len(data) {
+				out = append(out, c)
+				i++
+				c = data[i]
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == ',':
+			rest := bytes.TrimLeft(data[i+1:], " \t\r\n")
+			if len(rest) > 0 && (rest[0] == '}' || rest[0] == ']') {
+				continue
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// expandEnv builds KEY=value pairs from the config env. Values may refer to
+// the agent's environment as $VAR or ${VAR}; devcontainer's
+// ${containerEnv:VAR} and ${localEnv:VAR} forms are accepted too.
+func expandEnv(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := os.Expand(env[k], func(name string) string {
+			name = strings.TrimPrefix(strings.TrimPrefix(name, "containerEnv:"), "localEnv:")
+			return os.Getenv(name)
+		})
+		out = append(out, k+"="+v)
+	}
+	return out
+}
+
+// cloneLogWriter sends command output to the clone log so terminals opened
+// during setup show it like the clone output.
+type cloneLogWriter struct{}
+
+func (cloneLogWriter) Write(p []byte) (int, error) {
+	appendCloneLog(p)
+	return len(p), nil
+}
+
+// runWorkspaceSetup loads the workspace config and runs its setup commands
+// in order, stopping at the first failure. It returns false if setup failed.
+func runWorkspaceSetup() bool {
+	wc, err := loadWorkspaceConfig("/workspace")
+	if err != nil {
+		log.Printf("Invalid workspace config: %v", err)
+		appendCloneLog([]byte("\r\ncodenest: invalid workspace config: " + err.Error() + "\r\n"))
+		setSetupStatus(setupStatus{State: setupFailed, Error: err.Error()})
+		return false
+	}
+
+	workspaceState.Lock()
+	workspaceState.config = wc
+	workspaceState.env = expandEnv(wc.Env)
+	workspaceState.Unlock()
+
+	if len(wc.Setup) == 0 {
+		setSetupStatus(setupStatus{State: setupSkipped})
+		return true
+	}
+	log.Printf("Running %d setup commands from %s", len(wc.Setup), wc.Source)
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+	env := workspaceEnviron()
+	for i, step := range wc.Setup {
+		status := setupStatus{State: setupRunning, Step: i + 1, Steps: len(wc.Setup), Command: step.String()}
+		setSetupStatus(status)
+		appendCloneLog([]byte(fmt.Sprintf("\r\n\x1b[1m[setup %d/%d] %s\x1b[0m\r\n", i+1, len(wc.Setup), step)))
+
+		var cmd *exec.Cmd
+		if len(step.Args) > 0 {
+			cmd = exec.CommandContext(ctx, step.Args[0], step.Args[1:]...)
+		} else {
+			cmd = exec.CommandContext(ctx, "/bin/sh", "-c", step.Run)
+		}
+		cmd.Dir = filepath.Join("/workspace", step.Cwd)
+		cmd.Env = env
+		cmd.Stdout = cloneLogWriter{}
+		cmd.Stderr = cloneLogWriter{}
+		start := time.Now()
+		err := cmd.Run()
+		if err == nil {
+			log.Printf("Setup step %d/%d (%s) finished in %s", i+1, len(wc.Setup), step, time.Since(start).Round(time.Millisecond))
+			continue
+		}
+
+		status.State = setupFailed
+		status.Error = err.Error()
+		if ctx.Err() != nil {
+			status.Error = fmt.Sprintf("setup timed out after %s", setupTimeout)
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			status.ExitCode = exitErr.ExitCode()
+		}
+		setSetupStatus(status)
+		log.Printf("Setup step %d/%d (%s) failed: %s", i+1, len(wc.Setup), step, status.Error)
+		appendCloneLog([]byte(fmt.Sprintf("\r\ncodenest: setup step %q failed: %s\r\n", step.String(), status.Error)))
+		return false
+	}
+	setSetupStatus(setupStatus{State: setupDone, Steps: len(wc.Setup)})
+	return true
+}
+
+// workspaceConfigHandler returns the parsed workspace config (tasks, ports,
+// env) and the setup status.
+func workspaceConfigHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"config": currentWorkspaceConfig(),
+		"setup":  currentSetupStatus(),
+	})
+}
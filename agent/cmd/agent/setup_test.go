@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadWorkspaceConfig(t *testing.T) {
+	dir := t.TempDir()
+	if wc, err := loadWorkspaceConfig(dir); err != nil || wc.Source != "" {
+		t.Fatalf("no config file: %+v, %v", wc, err)
+	}
+
+	writeConfig(t, dir, ".devcontainer.json", `{"postCreateCommand": "make"}`)
+	writeConfig(t, dir, ".codenest.yml", `
+setup:
+  - npm install
+  - name: Go modules
+    run: go mod download
+    cwd: api
+env:
+  NODE_ENV: development
+ports:
+  - 3000
+  - port: 5173
+    label: Vite
+tasks:
+  dev: npm run dev
+  test:
+    command: go test ./...
+    cwd: api
+`)
+	wc, err := loadWorkspaceConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := workspaceConfig{
+		Source: ".codenest.yml",
+		Setup: []setupStep{
+			{Run: "npm install"},
+			{Name: "Go modules", Run: "go mod download", Cwd: "api"},
+		},
+		Env:   map[string]string{"NODE_ENV": "development"},
+		Ports: []portConfig{{Port: 3000}, {Port: 5173, Label: "Vite"}},
+		Tasks: map[string]taskConfig{
+			"dev":  {Command: "npm run dev"},
+			"test": {Command: "go test ./...", Cwd: "api"},
+		},
+	}
+	if !reflect.DeepEqual(wc, want) {
+		t.Errorf("config = %+v\nwant %+v", wc, want)
+	}
+}
+
+func TestLoadWorkspaceConfigErrors(t *testing.T) {
+	tests := []struct {
+		config string
+		want   string
+	}{
+		{"setup: [{name: empty}]", "setup step 1 has no command"},
+		{"setup: [{run: ls, cwd: ../..}]", "invalid cwd"},
+		{"ports: [70000]", "invalid port 70000"},
+		{"tasks: {dev: {cwd: web}}", `task "dev" has no command`},
+		{"languageServers: {'go lang': gopls}", "invalid language"},
+		{"setup: {not: a list}", ".codenest.yml"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeConfig(t, dir, ".codenest.yml", tt.config)
+		_, err := loadWorkspaceConfig(dir)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want %q", tt.config, err, tt.want)
+		}
+	}
+}
+
+func TestParseDevcontainer(t *testing.T) {
+	data := `{
+		// Comments and trailing commas are allowed
+		"onCreateCommand": ["npm", "ci"],
+		"postCreateCommand": {
+			"server": "go mod download",
+			"client": "npm run build", /* named commands run in name order */
+		},
+		"postStartCommand": "echo \"// not a comment\"",
+		"containerEnv": {"A": "container"},
+		"remoteEnv": {"A": "remote", "B": "b"},
+		"forwardPorts": [3000, "localhost:8080", "db:5432"],
+		"portsAttributes": {"3000": {"label": "App", "protocol": "http"}},
+	}`
+	wc, err := parseDevcontainer([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := workspaceConfig{
+		Setup: []setupStep{
+			{Args: []string{"npm", "ci"}},
+			{Name: "client", Run: "npm run build"},
+			{Name: "server", Run: "go mod download"},
+			{Run: `echo "// not a comment"`},
+		},
+		Env:   map[string]string{"A": "remote", "B": "b"},
+		Ports: []portConfig{{Port: 3000, Label: "App", Protocol: "http"}, {Port: 8080}},
+	}
+	if !reflect.DeepEqual(wc, want) {
+		t.Errorf("config = %+v\nwant %+v", wc, want)
+	}
+
+	for _, bad := range []string{`{"forwardPorts": [true]}`, `{"postCreateCommand": 42}`, `{`} {
+		if _, err := parseDevcontainer([]byte(bad)); err == nil {
+			t.Errorf("parseDevcontainer(%s) succeeded", bad)
+		}
+	}
+}
+
+func TestStripJSONC(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`{"a": 1} // trailing`, `{"a": 1} `},
+		{`{"a": /* inline */ 1}`, `{"a":   1}`},
+		{`{"url": "http://x"}`, `{"url": "http://x"}`},
+		{`{"s": "a\"//b"}`, `{"s": "a\"//b"}`},
+		{`[1, 2, ]`, `[1, 2 ]`},
+		{`{"a": [1,], "b": ",}"}`, `{"a": [1], "b": ",}"}`},
+	}
+	for _, tt := range tests {
+		if got := string(stripJSONC([]byte(tt.in))); got != tt.want {
+			t.Errorf("stripJSONC(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNotifyCallback(t *testing.T) {
+	var got map[string]string
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	saved := cfg
+	defer func() { cfg = saved }()
+	cfg.CallbackURL, cfg.CallbackToken, cfg.AtlasID = srv.URL, "secret", "atlas-1"
+
+	notifyCallback("SETUP_FAILED")
+	// The gateway webhook binds the atlas id from "id"
+	want := map[string]string{"id": "atlas-1", "status": "SETUP_FAILED"}
+	if !reflect.DeepEqual(got, want) || auth != "secret" {
+		t.Errorf("callback body %v with token %q, want %v with the callback token", got, auth, want)
+	}
+}
+
+func writeConfig(t *testing.T, dir, name, content string) {
+	t.Helper()
+	mustMkdir(t, filepath.Dir(filepath.Join(dir, name)))
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
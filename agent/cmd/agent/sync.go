@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Outcomes of the shutdown push, reported to project-service.
+const (
+	syncPushed   = "PUSHED"   // the branch reached its upstream
+	syncRecovery = "RECOVERY" // upstream rejected the push; work is on a recovery branch
+	syncFailed   = "FAILED"   // nothing could be pushed
+	syncSkipped  = "SKIPPED"  // no commits to push
+)
+
+type syncResult struct {
+	Status    string `json:"status"`
+	Branch    string `json:"branch,omitempty"`
+	RemoteRef string `json:"remoteRef,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// syncUpstream resolves where a local branch pushes to: its configured
+// remote and merge ref, or a same-named branch on origin.
+func syncUpstream(ctx context.Context, branch string) (remote, ref string) {
+	remote, ref = "origin", "refs/heads/"+branch
+	if out, _, err := runGit(ctx, "config", "--get", "branch."+branch+".remote"); err == nil && strings.TrimSpace(out) != "" {
+		remote = strings.TrimSpace(out)
+	}
+	if out, _, err := runGit(ctx, "config", "--get", "branch."+branch+".merge"); err == nil && strings.TrimSpace(out) != "" {
+		ref = strings.TrimSpace(out)
+	}
+	return remote, ref
+}
+
+func recoveryBranch() string {
+	id := cfg.AtlasID
+	if id == "" {
+		id = "local"
+	}
+	return "codenest/recovery-" + id
+}
+
+// pushWorkspace pushes the checked-out branch to its upstream. If the remote
+// rejects it (usually because it moved on) the commits are pushed to a
+// recovery branch instead so they are never silently dropped. A detached
+// HEAD or a pull request checkout goes straight to the recovery branch.
+// Nothing is pushed when every commit is already on a remote.
+func pushWorkspace() syncResult {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*gitRemoteTimeout)
+	defer cancel()
+
+	gitMu.Lock()
+	defer gitMu.Unlock()
+
+	commit, _, err := runGit(ctx, "rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		return syncResult{Status: syncSkipped, Message: "repository has no commits"}
+	}
+	res := syncResult{Commit: strings.TrimSpace(commit)}
+	branch, _, _ := runGit(ctx, "symbolic-ref", "--short", "-q", "HEAD")
+	res.Branch = strings.TrimSpace(branch)
+
+	unpushed, _, err := runGit(ctx, "rev-list", "-n", "1", "HEAD", "--not", "--remotes")
+	if err == nil && strings.TrimSpace(unpushed) == "" {
+		res.Status = syncSkipped
+		res.Message = "all commits are already on a remote"
+		return res
+	}
+
+	// pr/<n> branches are local copies of a pull request, not branches the
+	// user meant to publish
+	if res.Branch != "" && !strings.HasPrefix(res.Branch, "pr/") {
+		remote, ref := syncUpstream(ctx, res.Branch)
+		res.RemoteRef = remote + "/" + strings.TrimPrefix(ref, "refs/heads/")
+		_, stderr, err := runGit(ctx, "push", remote, "HEAD:"+ref)
+		if err == nil {
+			res.Status = syncPushed
+			return res
+		}
+		res.Message = strings.TrimSpace(stderr)
+		if !strings.Contains(stderr, "rejected") {
+			res.Status = syncFailed
+			return res
+		}
+		res.Message = rejectionReason(stderr)
+		log.Printf("push to %s rejected, falling back to %s", res.RemoteRef, recoveryBranch())
+	}
+
+	// The recovery branch belongs to this workspace, so overwrite it
+	recovery := recoveryBranch()
+	if _, stderr, err := runGit(ctx, "push", "origin", "+HEAD:refs/heads/"+recovery); err != nil {
+		res.Status = syncFailed
+		res.Message = strings.TrimSpace(res.Message + "\n" + stderr)
+		return res
+	}
+	res.Status = syncRecovery
+	res.RemoteRef = "origin/" + recovery
+	return res
+}
+
+// rejectionReason picks the "! [rejected] ..." line out of git push output.
+func rejectionReason(stderr string) string {
+	for _, line := range strings.Split(stderr, "\n") {
+		if strings.Contains(line, "rejected") {
+			return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "!"))
+		}
+	}
+	return strings.TrimSpace(stderr)
+}
+
+// notifySync tells project-service where the session's commits ended up.
+func notifySync(res syncResult) {
+	if cfg.SyncURL == "" || cfg.CallbackToken == "" {
+		return
+	}
+	body, err := json.Marshal(struct {
+		ID string `json:"id"`
+		syncResult
+	}{cfg.AtlasID, res})
+	if err != nil {
+		log.Printf("Failed to marshal sync report: %v", err)
+		return
+	}
+	req, _ := http.NewRequest(http.MethodPost, cfg.SyncURL, bytes.NewReader(body))
+	req.Header.Set("Authorization", cfg.CallbackToken)
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Failed to report sync result: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		log.Printf("Sync report returned status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,611 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Output kept per run; the oldest chunks are dropped beyond this
+	taskOutputLimit = 1 << 20 // 1MB
+	// Upper bound on concurrently running tasks
+	maxRunningTasks = 16
+	// Finished runs kept for the history
+	maxTaskHistory = 50
+	// Time a canceled task gets to exit after SIGTERM before SIGKILL
+	taskKillGrace = 5 * time.Second
+)
+
+// Task run states.
+const (
+	taskRunning   = "running"
+	taskSucceeded = "succeeded"
+	taskFailed    = "failed"
+	taskCanceled  = "canceled"
+)
+
+var (
+	errTooManyTasks = errors.New("too many running tasks")
+	errUnknownTask  = errors.New("unknown task")
+
+	tasksMu  sync.Mutex
+	taskRuns = map[string]*taskRun{}
+)
+
+// taskChunk is a piece of task output. Seq numbers are per run and
+// consecutive, so clients can resume a stream after the last one they saw.
+type taskChunk struct {
+	Seq    int64     `json:"seq"`
+	Stream string    `json:"stream"` // stdout or stderr
+	Data   string    `json:"data"`
+	Time   time.Time `json:"time"`
+}
+
+// taskRun is one execution of a configured task or an ad hoc command. It
+// runs outside any PTY, with stdout and stderr kept apart so the UI can
+// apply problem matchers.
+type taskRun struct {
+	ID      string
+	Name    string // configured task name, empty for ad hoc commands
+	Command string
+	Cwd     string
+	Adapter string // debug adapter, for debug sessions
+
+	cmd      *exec.Cmd
+	started  time.Time
+	exited   chan struct{}
+	canceled bool
+
+	mu       sync.Mutex
+	chunks   []taskChunk
+	partial  map[string][]byte // incomplete UTF-8 sequence ending each stream
+	size     int
+	nextSeq  int64
+	ended    time.Time
+	exitCode int
+	status   string
+	errMsg   string
+	subs     map[chan struct{}]struct{}
+}
+
+type taskRunInfo struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name,omitempty"`
+	Command   string     `json:"command"`
+	Cwd       string     `json:"cwd,omitempty"`
+	Adapter   string     `json:"adapter,omitempty"`
+	Status    string     `json:"status"`
+	ExitCode  *int       `json:"exitCode,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Pid       int        `json:"pid,omitempty"`
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+}
+
+func (t *taskRun) info() taskRunInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info := taskRunInfo{
+		ID:        t.ID,
+		Name:      t.Name,
+		Command:   t.Command,
+		Cwd:       t.Cwd,
+		Adapter:   t.Adapter,
+		Status:    t.status,
+		Error:     t.errMsg,
+		StartedAt: t.started,
+	}
+	if t.status == taskRunning {
+		if t.cmd.Process != nil {
+			info.Pid = t.cmd.Process.Pid
+		}
+	} else {
+		code, ended := t.exitCode, t.ended
+		info.ExitCode = &code
+		info.EndedAt = &ended
+	}
+	return info
+}
+
+// taskStreamWriter records one of a task's output streams.
+type taskStreamWriter struct {
+	run    *taskRun
+	stream string
+}
+
+func (w taskStreamWriter) Write(p []byte) (int, error) {
+	w.run.appendOutput(w.stream, p)
+	return len(p), nil
+}
+
+// appendOutput buffers p as the next chunk. A multi-byte character split
+// across writes is held back until the rest of it arrives, so chunks stay
+// valid UTF-8 when encoded as JSON strings.
+func (t *taskRun) appendOutput(stream string, p []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	data := append(t.partial[stream], p...)
+	cut := incompleteRuneStart(data)
+	if cut < len(data) {
+		if t.partial == nil {
+			t.partial = map[string][]byte{}
+		}
+		t.partial[stream] = append([]byte(nil), data[cut:]...)
+	} else {
+		delete(t.partial, stream)
+	}
+	t.addChunkLocked(stream, data[:cut])
+}
+
+// flushPartialLocked emits any bytes still held back once the streams are
+// closed; they can no longer be completed.
+func (t *taskRun) flushPartialLocked() {
+	for _, stream := range []string{"stdout", "stderr"} {
+		if data, ok := t.partial[stream]; ok {
+			delete(t.partial, stream)
+			t.addChunkLocked(stream, data)
+		}
+	}
+}
+
+func (t *taskRun) addChunkLocked(stream string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	t.chunks = append(t.chunks, taskChunk{Seq: t.nextSeq, Stream: stream, Data: string(data), Time: time.Now()})
+	t.nextSeq++
+	t.size += len(data)
+	for t.size > taskOutputLimit && len(t.chunks) > 1 {
+		t.size -= len(t.chunks[0].Data)
+		t.chunks = t.chunks[1:]
+	}
+	t.notifyLocked()
+}
+
+// incompleteRuneStart returns the offset of a multi-byte UTF-8 sequence
+// that is cut off at the end of data, or len(data) if there is none.
+// Invalid bytes are left in place.
+func incompleteRuneStart(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax+1; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+func (t *taskRun) notifyLocked() {
+	for ch := range t.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// outputSince returns buffered chunks with Seq >= since, the seq to resume
+// from and whether the run has finished.
+func (t *taskRun) outputSince(since int64) ([]taskChunk, int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var out []taskChunk
+	if len(t.chunks) > 0 {
+		first := t.chunks[0].Seq
+		i := max(since-first, 0)
+		if i < int64(len(t.chunks)) {
+			out = append(out, t.chunks[i:]...)
+		}
+	}
+	return out, t.nextSeq, t.status != taskRunning
+}
+
+func (t *taskRun) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	t.mu.Lock()
+	t.subs[ch] = struct{}{}
+	t.mu.Unlock()
+	return ch
+}
+
+func (t *taskRun) unsubscribe(ch chan struct{}) {
+	t.mu.Lock()
+	delete(t.subs, ch)
+	t.mu.Unlock()
+}
+
+// startTask launches command in cwd (relative to /workspace) with the
+// workspace env plus env.
+func startTask(name, command, cwd string, env map[string]string) (*taskRun, error) {
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Dir = filepath.Join("/workspace", cwd)
+	cmd.Env = append(workspaceEnviron(), expandEnv(env)...)
+	t := newTaskRun(name, command, cwd, cmd)
+	cmd.Stdout = taskStreamWriter{t, "stdout"}
+	cmd.Stderr = taskStreamWriter{t, "stderr"}
+	if err := launchTask(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func newTaskRun(name, command, cwd string, cmd *exec.Cmd) *taskRun {
+	// Own process group so cancel reaches everything the task spawned
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// Don't wait forever on output from background processes the task left
+	// running
+	cmd.WaitDelay = taskKillGrace
+	return &taskRun{
+		ID:      generateRequestID(),
+		Name:    name,
+		Command: command,
+		Cwd:     cwd,
+		cmd:     cmd,
+		exited:  make(chan struct{}),
+		status:  taskRunning,
+		subs:    map[chan struct{}]struct{}{},
+	}
+}
+
+// launchTask starts a prepared run and adds it to the history, unless too
+// many runs are going already.
+func launchTask(t *taskRun) error {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+
+	running := 0
+	for _, run := range taskRuns {
+		if run.info().Status == taskRunning {
+			running++
+		}
+	}
+	if running >= maxRunningTasks {
+		return errTooManyTasks
+	}
+
+	if err := t.cmd.Start(); err != nil {
+		return err
+	}
+	t.started = time.Now()
+	taskRuns[t.ID] = t
+	pruneTaskHistoryLocked()
+	go t.wait()
+	log.Printf("Task %s started: %s", t.ID, t.label())
+	return nil
+}
+
+func (t *taskRun) label() string {
+	if t.Adapter != "" {
+		return "debug:" + t.Adapter
+	}
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Command
+}
+
+func (t *taskRun) wait() {
+	err := t.cmd.Wait()
+	t.mu.Lock()
+	t.flushPartialLocked()
+	t.ended = time.Now()
+	t.exitCode = t.cmd.ProcessState.ExitCode()
+	var exitErr *exec.ExitError
+	switch {
+	case t.canceled:
+		t.status = taskCanceled
+	case err == nil:
+		t.status = taskSucceeded
+	case errors.As(err, &exitErr):
+		t.status = taskFailed
+	default:
+		t.status = taskFailed
+		t.errMsg = err.Error()
+	}
+	t.notifyLocked()
+	t.mu.Unlock()
+	close(t.exited)
+	log.Printf("Task %s (%s) %s with code %d", t.ID, t.label(), t.status, t.exitCode)
+}
+
+// cancel stops the task's process group, escalating to SIGKILL if it
+// ignores SIGTERM.
+func (t *taskRun) cancel() {
+	t.mu.Lock()
+	if t.status != taskRunning {
+		t.mu.Unlock()
+		return
+	}
+	t.canceled = true
+	t.mu.Unlock()
+
+	pgid := -t.cmd.Process.Pid
+	_ = syscall.Kill(pgid, syscall.SIGTERM)
+	select {
+	case <-t.exited:
+	case <-time.After(taskKillGrace):
+		_ = syscall.Kill(pgid, syscall.SIGKILL)
+		<-t.exited
+	}
+}
+
+// pruneTaskHistoryLocked drops the oldest finished runs beyond the history
+// limit. Callers hold tasksMu.
+func pruneTaskHistoryLocked() {
+	var finished []*taskRun
+	for _, t := range taskRuns {
+		if t.info().Status != taskRunning {
+			finished = append(finished, t)
+		}
+	}
+	if len(finished) <= maxTaskHistory {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].started.Before(finished[j].started) })
+	for _, t := range finished[:len(finished)-maxTaskHistory] {
+		delete(taskRuns, t.ID)
+	}
+}
+
+func findTaskRun(id string) *taskRun {
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	return taskRuns[id]
+}
+
+// listTaskRuns returns the run history, newest first.
+func listTaskRuns() []taskRunInfo {
+	tasksMu.Lock()
+	list := make([]*taskRun, 0, len(taskRuns))
+	for _, t := range taskRuns {
+		list = append(list, t)
+	}
+	tasksMu.Unlock()
+
+	infos := make([]taskRunInfo, 0, len(list))
+	for _, t := range list {
+		infos = append(infos, t.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].StartedAt.After(infos[j].StartedAt) })
+	return infos
+}
+
+func cancelAllTasks() {
+	tasksMu.Lock()
+	list := make([]*taskRun, 0, len(taskRuns))
+	for _, t := range taskRuns {
+		list = append(list, t)
+	}
+	tasksMu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, t := range list {
+		wg.Add(1)
+		go func(t *taskRun) {
+			defer wg.Done()
+			t.cancel()
+		}(t)
+	}
+	wg.Wait()
+}
+
+type taskDefinition struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	Cwd     string `json:"cwd,omitempty"`
+}
+
+// tasksHandler lists the tasks defined in the workspace config together with
+// the run history.
+func tasksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	configured := currentWorkspaceConfig().Tasks
+	defs := make([]taskDefinition, 0, len(configured))
+	for name, task := range configured {
+		defs = append(defs, taskDefinition{Name: name, Command: task.Command, Cwd: task.Cwd})
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"tasks": defs,
+		"runs":  listTaskRuns(),
+	})
+}
+
+// taskRunHandler starts a configured task ({"task": "test"}) or an ad hoc
+// command ({"command": "go vet ./...", "cwd": "api"}).
+func taskRunHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	var body struct {
+		Task    string            `json:"task"`
+		Command string            `json:"command"`
+		Cwd     string            `json:"cwd"`
+		Env     map[string]string `json:"env"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", 400)
+		return
+	}
+
+	name, command, cwd, env := "", body.Command, body.Cwd, body.Env
+	switch {
+	case body.Task != "" && body.Command != "":
+		http.Error(w, "specify either task or command", 400)
+		return
+	case body.Task != "":
+		task, ok := currentWorkspaceConfig().Tasks[body.Task]
+		if !ok {
+			http.Error(w, errUnknownTask.Error(), 404)
+			return
+		}
+		name, command = body.Task, task.Command
+		if cwd == "" {
+			cwd = task.Cwd
+		}
+		merged := map[string]string{}
+		for k, v := range task.Env {
+			merged[k] = v
+		}
+		for k, v := range body.Env {
+			merged[k] = v
+		}
+		env = merged
+	case strings.TrimSpace(body.Command) == "":
+		http.Error(w, "task or command required", 400)
+		return
+	}
+	if cwd != "" && !isValidPath(cwd) {
+		http.Error(w, "invalid cwd", 400)
+		return
+	}
+	for k := range env {
+		if k == "" || strings.ContainsAny(k, "=\x00") {
+			http.Error(w, fmt.Sprintf("invalid env name %q", k), 400)
+			return
+		}
+	}
+
+	t, err := startTask(name, command, cwd, env)
+	if err != nil {
+		if errors.Is(err, errTooManyTasks) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		logWithRequestID(r, "Failed to start task: %v", err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	logWithRequestID(r, "Started task %s (%s)", t.ID, t.label())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(t.info())
+}
+
+// taskOutputHandler returns a run's buffered output from ?since= onwards.
+func taskOutputHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	t := findTaskRun(r.URL.Query().Get("id"))
+	if t == nil {
+		http.Error(w, "task run not found", 404)
+		return
+	}
+	since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+	chunks, next, _ := t.outputSince(since)
+	if chunks == nil {
+		chunks = []taskChunk{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"run":    t.info(),
+		"output": chunks,
+		"next":   next,
+	})
+}
+
+// taskEvent is sent over the task stream WebSocket.
+type taskEvent struct {
+	Type       string       `json:"type"` // output or exit
+	*taskChunk              // output
+	Run        *taskRunInfo `json:"run,omitempty"` // exit
+}
+
+// taskStreamHandler streams a run's output over a WebSocket, replaying from
+// ?since= first, and sends an exit event with the final status when the run
+// ends.
+func taskStreamHandler(w http.ResponseWriter, r *http.Request) {
+	t := findTaskRun(r.URL.Query().Get("id"))
+	if t == nil {
+		http.Error(w, "task run not found", 404)
+		return
+	}
+	since, _ := strconv.ParseInt(r.URL.Query().Get("since"), 10, 64)
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logWithRequestID(r, "WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	notify := t.subscribe()
+	defer t.unsubscribe(notify)
+
+	// Reader only exists to notice the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		chunks, next, done := t.outputSince(since)
+		for i := range chunks {
+			if err := conn.WriteJSON(taskEvent{Type: "output", taskChunk: &chunks[i]}); err != nil {
+				logWithRequestID(r, "Task stream write error: %v", err)
+				return
+			}
+		}
+		since = next
+		if done {
+			info := t.info()
+			_ = conn.WriteJSON(taskEvent{Type: "exit", Run: &info})
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, info.Status))
+			return
+		}
+		select {
+		case <-notify:
+		case <-closed:
+			return
+		}
+	}
+}
+
+// taskCancelHandler stops a running task.
+func taskCancelHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+	t := findTaskRun(r.URL.Query().Get("id"))
+	if t == nil {
+		http.Error(w, "task run not found", 404)
+		return
+	}
+	t.cancel()
+	logWithRequestID(r, "Canceled task %s (%s)", t.ID, t.label())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.info())
+}
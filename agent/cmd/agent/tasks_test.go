@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestTaskOutputKeepsRunesWhole(t *testing.T) {
+	text := []byte("héllo €𝄞 wörld")
+	// Split the output at every byte offset into two and three writes
+	for i := 1; i < len(text); i++ {
+		for j := i; j < len(text); j++ {
+			run := newTaskRun("", "", "", exec.Command("true"))
+			run.appendOutput("stdout", text[:i])
+			run.appendOutput("stdout", text[i:j])
+			run.appendOutput("stdout", text[j:])
+			chunks, _, _ := run.outputSince(0)
+			var got strings.Builder
+			for _, c := range chunks {
+				if !utf8.ValidString(c.Data) {
+					t.Fatalf("split at %d,%d: chunk %q is not valid UTF-8", i, j, c.Data)
+				}
+				got.WriteString(c.Data)
+			}
+			if got.String() != string(text) {
+				t.Fatalf("split at %d,%d: output %q", i, j, got.String())
+			}
+		}
+	}
+}
+
+func TestTaskOutputStreamsHeldApart(t *testing.T) {
+	run := newTaskRun("", "", "", exec.Command("true"))
+	euro := []byte("€")
+	run.appendOutput("stdout", euro[:1])
+	run.appendOutput("stderr", []byte("err\n"))
+	run.appendOutput("stdout", euro[1:])
+	// Invalid bytes that can't start a character are passed through
+	run.appendOutput("stderr", []byte{0xff, 'x'})
+
+	chunks, next, _ := run.outputSince(0)
+	var got []string
+	for _, c := range chunks {
+		got = append(got, c.Stream+":"+c.Data)
+	}
+	want := []string{"stderr:err\n", "stdout:€", "stderr:\xffx"}
+	if !reflect.DeepEqual(got, want) || next != 3 {
+		t.Errorf("chunks %q next %d, want %q next 3", got, next, want)
+	}
+}
+
+func TestTaskOutputSince(t *testing.T) {
+	run := newTaskRun("", "", "", exec.Command("true"))
+	for _, s := range []string{"a", "b", "c"} {
+		run.appendOutput("stdout", []byte(s))
+	}
+	tests := []struct {
+		since int64
+		want  string
+	}{
+		{0, "abc"},
+		{2, "c"},
+		{3, ""},
+		{-1, "abc"},
+	}
+	for _, tt := range tests {
+		chunks, next, _ := run.outputSince(tt.since)
+		var got string
+		for _, c := range chunks {
+			got += c.Data
+		}
+		if got != tt.want || next != 3 {
+			t.Errorf("since %d: %q next %d, want %q next 3", tt.since, got, next, tt.want)
+		}
+	}
+
+	// Chunks beyond the output limit are dropped from the front; seqs keep
+	// counting
+	big := make([]byte, taskOutputLimit/2+1)
+	run.appendOutput("stdout", big)
+	run.appendOutput("stdout", big)
+	chunks, next, _ := run.outputSince(0)
+	if len(chunks) != 1 || chunks[0].Seq != 4 || next != 5 {
+		t.Errorf("after overflow: %d chunks from seq %d, next %d", len(chunks), chunks[0].Seq, next)
+	}
+}
+
+func TestTaskRunFlushesPartialOutput(t *testing.T) {
+	// The task exits half way through a character
+	run, err := startTask("", `printf 'ok\342\202'; printf 'bad' >&2; exit 3`, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		tasksMu.Lock()
+		delete(taskRuns, run.ID)
+		tasksMu.Unlock()
+	})
+	select {
+	case <-run.exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not exit")
+	}
+
+	got := map[string]string{}
+	chunks, _, done := run.outputSince(0)
+	for _, c := range chunks {
+		got[c.Stream] += c.Data
+	}
+	want := map[string]string{"stdout": "ok\xe2\x82", "stderr": "bad"}
+	if !done || !reflect.DeepEqual(got, want) {
+		t.Errorf("output %q done %v, want %q", got, done, want)
+	}
+	if info := run.info(); info.Status != taskFailed || *info.ExitCode != 3 {
+		t.Errorf("status %s code %d, want %s code 3", info.Status, *info.ExitCode, taskFailed)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/creack/pty"
+	"github.com/gorilla/websocket"
+)
+
+// terminalSubprotocol is negotiated via Sec-WebSocket-Protocol by clients that
+// speak the framed protocol. Everyone else gets the legacy behaviour: PTY
+// output as text frames and every inbound frame written verbatim to the PTY.
+//
+// Framed protocol:
+//   - binary frames carry raw terminal bytes in both directions
+//   - text frames carry JSON control messages (see terminalMessage)
+const terminalSubprotocol = "codenest.terminal.v1"
+
+const (
+	msgData   = "data"
+	msgResize = "resize"
+	msgSignal = "signal"
+	msgPing   = "ping"
+	msgPong   = "pong"
+	msgExit   = "exit"
+	msgError  = "error"
+)
+
+// terminalMessage is a control frame of the framed terminal protocol.
+type terminalMessage struct {
+	Type string `json:"type"`
+	// data: base64 payload; handy for clients that can't send binary frames
+	Data string `json:"data,omitempty"`
+	// resize
+	Cols uint16 `json:"cols,omitempty"`
+	Rows uint16 `json:"rows,omitempty"`
+	// signal: e.g. "SIGINT", "SIGTERM"
+	Signal string `json:"signal,omitempty"`
+	// exit
+	Code *int `json:"code,omitempty"`
+	// error
+	Message string `json:"message,omitempty"`
+}
+
+var terminalSignals = map[string]syscall.Signal{
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGTSTP": syscall.SIGTSTP,
+	"SIGCONT": syscall.SIGCONT,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// wantsFramedTerminal reports whether the client offered the framed subprotocol.
+func wantsFramedTerminal(r *http.Request) bool {
+	for _, p := range websocket.Subprotocols(r) {
+		if p == terminalSubprotocol {
+			return true
+		}
+	}
+	return false
+}
+
+// terminalConn serializes writes to a terminal websocket and hides the
+// difference between the legacy and framed protocols.
+type terminalConn struct {
+	conn   *websocket.Conn
+	framed bool
+	mu     sync.Mutex
+}
+
+func (tc *terminalConn) writeData(p []byte) error {
+	mt := websocket.TextMessage
+	if tc.framed {
+		mt = websocket.BinaryMessage
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.conn.WriteMessage(mt, p)
+}
+
+// writeControl sends a control message; it is a no-op for legacy clients.
+func (tc *terminalConn) writeControl(msg terminalMessage) error {
+	if !tc.framed {
+		return nil
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	return tc.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// handleInput applies one inbound websocket frame to the session.
+func (tc *terminalConn) handleInput(s *terminalSession, mt int, msg []byte) error {
+	if !tc.framed || mt == websocket.BinaryMessage {
+		return s.write(msg)
+	}
+
+	var ctrl terminalMessage
+	if err := json.Unmarshal(msg, &ctrl); err != nil {
+		return tc.writeControl(terminalMessage{Type: msgError, Message: "invalid control message"})
+	}
+	switch ctrl.Type {
+	case msgData:
+		data, err := base64.StdEncoding.DecodeString(ctrl.Data)
+		if err != nil {
+			return tc.writeControl(terminalMessage{Type: msgError, Message: "data must be base64"})
+		}
+		return s.write(data)
+	case msgResize:
+		if ctrl.Cols == 0 || ctrl.Rows == 0 {
+			return tc.writeControl(terminalMessage{Type: msgError, Message: "cols and rows required"})
+		}
+		return s.resize(ctrl.Cols, ctrl.Rows)
+	case msgSignal:
+		sig, ok := terminalSignals[ctrl.Signal]
+		if !ok {
+			return tc.writeControl(terminalMessage{Type: msgError, Message: fmt.Sprintf("unsupported signal %q", ctrl.Signal)})
+		}
+		if err := s.signal(sig); err != nil {
+			return tc.writeControl(terminalMessage{Type: msgError, Message: err.Error()})
+		}
+		return nil
+	case msgPing:
+		return tc.writeControl(terminalMessage{Type: msgPong})
+	default:
+		return tc.writeControl(terminalMessage{Type: msgError, Message: fmt.Sprintf("unknown message type %q", ctrl.Type)})
+	}
+}
+
+func (s *terminalSession) resize(cols, rows uint16) error {
+	return pty.Setsize(s.ptmx, &pty.Winsize{Cols: cols, Rows: rows})
+}
+
+// signal delivers sig to the terminal's foreground process group, falling
+// back to the shell itself.
+func (s *terminalSession) signal(sig syscall.Signal) error {
+	var pgrp int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, s.ptmx.Fd(), uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	if errno == 0 && pgrp > 0 {
+		return syscall.Kill(-int(pgrp), sig)
+	}
+	if s.cmd.Process == nil {
+		return errors.New("session has no process")
+	}
+	return s.cmd.Process.Signal(sig)
+}
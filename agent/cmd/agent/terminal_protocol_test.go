@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestTerminalControlMessages(t *testing.T) {
+	setTestReady(t)
+	conn := dialFramedTerminal(t)
+
+	tests := []struct {
+		name string
+		send terminalMessage
+		want terminalMessage
+	}{
+		{"ping", terminalMessage{Type: msgPing}, terminalMessage{Type: msgPong}},
+		{"unknown type", terminalMessage{Type: "bogus"}, terminalMessage{Type: msgError, Message: `unknown message type "bogus"`}},
+		{"bad base64", terminalMessage{Type: msgData, Data: "%%%"}, terminalMessage{Type: msgError, Message: "data must be base64"}},
+		{"resize without size", terminalMessage{Type: msgResize, Cols: 80}, terminalMessage{Type: msgError, Message: "cols and rows required"}},
+		{"unsupported signal", terminalMessage{Type: msgSignal, Signal: "SIGSEGV"}, terminalMessage{Type: msgError, Message: `unsupported signal "SIGSEGV"`}},
+	}
+	for _, tt := range tests {
+		sendControl(t, conn, tt.send)
+		got := readControl(t, conn)
+		if got.Type != tt.want.Type || got.Message != tt.want.Message {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("not json")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readControl(t, conn); got.Type != msgError || got.Message != "invalid control message" {
+		t.Errorf("invalid JSON: got %+v", got)
+	}
+}
+
+func TestTerminalResize(t *testing.T) {
+	setTestReady(t)
+	conn := dialFramedTerminal(t)
+
+	sendControl(t, conn, terminalMessage{Type: msgResize, Cols: 123, Rows: 45})
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte("stty size\n")); err != nil {
+		t.Fatal(err)
+	}
+	readFramedUntil(t, conn, "45 123")
+}
+
+func TestTerminalSignal(t *testing.T) {
+	setTestReady(t)
+	conn := dialFramedTerminal(t)
+
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte("echo started; sleep 30\n")); err != nil {
+		t.Fatal(err)
+	}
+	readFramedUntil(t, conn, "started\r\n")
+	// Give sleep a moment to become the foreground process
+	time.Sleep(200 * time.Millisecond)
+	sendControl(t, conn, terminalMessage{Type: msgSignal, Signal: "SIGINT"})
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte("echo status=$?\n")); err != nil {
+		t.Fatal(err)
+	}
+	readFramedUntil(t, conn, "status=130")
+}
+
+func TestTerminalExitFrame(t *testing.T) {
+	setTestReady(t)
+	conn := dialFramedTerminal(t)
+
+	// Input sent as a base64 data message reaches the shell like a binary frame
+	sendControl(t, conn, terminalMessage{Type: msgData, Data: base64.StdEncoding.EncodeToString([]byte("exit 3\n"))})
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		mt, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("no exit frame: %v", err)
+		}
+		if mt != websocket.TextMessage {
+			continue
+		}
+		var ctrl terminalMessage
+		if err := json.Unmarshal(msg, &ctrl); err != nil {
+			t.Fatalf("bad control frame %q: %v", msg, err)
+		}
+		if ctrl.Type != msgExit {
+			continue
+		}
+		if ctrl.Code == nil || *ctrl.Code != 3 {
+			t.Errorf("exit frame = %s, want code 3", msg)
+		}
+		return
+	}
+}
+
+func TestTerminalLegacyProtocol(t *testing.T) {
+	setTestReady(t)
+	name := "legacy-" + generateRequestID()
+	conn := dialTerminal(t, "session="+name, false)
+	defer conn.Close()
+	t.Cleanup(func() {
+		if s := findSession(name); s != nil {
+			s.kill()
+		}
+	})
+
+	// Legacy clients' frames, JSON or not, are written to the PTY verbatim
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`echo '{"type":"ping"}'`+"\n")); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var seen []byte
+	for !bytes.Contains(seen, []byte(`"ping"}`+"\r\n")) {
+		mt, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for echo: %v (got %q)", err, seen)
+		}
+		if mt != websocket.TextMessage {
+			t.Fatalf("legacy client got message type %d", mt)
+		}
+		seen = append(seen, msg...)
+	}
+}
+
+// dialFramedTerminal opens a framed connection to a fresh session that is
+// killed when the test ends.
+func dialFramedTerminal(t *testing.T) *websocket.Conn {
+	t.Helper()
+	name := "framed-" + generateRequestID()
+	conn := dialTerminal(t, "session="+name, true)
+	t.Cleanup(func() {
+		conn.Close()
+		if s := findSession(name); s != nil {
+			s.kill()
+		}
+	})
+	return conn
+}
+
+func sendControl(t *testing.T, conn *websocket.Conn, msg terminalMessage) {
+	t.Helper()
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readControl returns the next control frame, skipping terminal output.
+func readControl(t *testing.T, conn *websocket.Conn) terminalMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		mt, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for control frame: %v", err)
+		}
+		if mt == websocket.BinaryMessage {
+			continue
+		}
+		var ctrl terminalMessage
+		if err := json.Unmarshal(msg, &ctrl); err != nil {
+			t.Fatalf("bad control frame %q: %v", msg, err)
+		}
+		return ctrl
+	}
+}
+
+// readFramedUntil reads binary output frames until they contain want.
+func readFramedUntil(t *testing.T, conn *websocket.Conn, want string) {
+	t.Helper()
+	var seen []byte
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for !bytes.Contains(seen, []byte(want)) {
+		mt, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %q: %v (got %q)", want, err, seen)
+		}
+		if mt == websocket.BinaryMessage {
+			seen = append(seen, msg...)
+		}
+	}
+}
@@ -0,0 +1,585 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+const (
+	// Largest file /files/content returns inline; bigger ones go through
+	// /files/raw
+	maxContentSize = 10 << 20 // 10MB
+	// Largest file that can be uploaded, in one request or in chunks
+	maxUploadSize = 5 << 30 // 5GB
+	// Largest chunk of a resumable upload
+	maxUploadChunk = 64 << 20 // 64MB
+	// Resumable uploads not touched for this long are dropped
+	uploadExpiry = 24 * time.Hour
+)
+
+// Text encodings reported by /files/content and accepted by /files/save.
+// Binary files are returned as base64.
+const (
+	encodingUTF8    = "utf-8"
+	encodingUTF8BOM = "utf-8-bom"
+	encodingUTF16LE = "utf-16le"
+	encodingUTF16BE = "utf-16be"
+	encodingBase64  = "base64"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// detectEncoding tells text from binary content. A byte order mark decides
+// for UTF-8 and UTF-16; otherwise content is UTF-8 if it is valid UTF-8
+// without NUL bytes.
+func detectEncoding(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return encodingUTF8BOM
+	case bytes.HasPrefix(data, bomUTF16LE) && len(data)%2 == 0:
+		return encodingUTF16LE
+	case bytes.HasPrefix(data, bomUTF16BE) && len(data)%2 == 0:
+		return encodingUTF16BE
+	case bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data):
+		return encodingBase64
+	}
+	return encodingUTF8
+}
+
+// decodeContent renders file data in the given encoding for the editor:
+// text as a UTF-8 string, anything else as base64.
+func decodeContent(data []byte, enc string) string {
+	switch enc {
+	case encodingUTF8:
+		return string(data)
+	case encodingUTF8BOM:
+		return string(data[len(bomUTF8):])
+	case encodingUTF16LE, encodingUTF16BE:
+		units := make([]uint16, 0, len(data)/2-1)
+		for i := 2; i+1 < len(data); i += 2 {
+			if enc == encodingUTF16LE {
+				units = append(units, uint16(data[i])|uint16(data[i+1])<<8)
+			} else {
+				units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+			}
+		}
+		return string(utf16.Decode(units))
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// encodeContent turns what the editor sends back into file data, so a file
+// is saved in the encoding it was read in.
+func encodeContent(body []byte, enc string) ([]byte, error) {
+	switch enc {
+	case "", encodingUTF8:
+		return body, nil
+	case encodingUTF8BOM:
+		return append(append([]byte{}, bomUTF8...), body...), nil
+	case encodingUTF16LE, encodingUTF16BE:
+		if !utf8.Valid(body) {
+			return nil, errors.New("content is not valid UTF-8")
+		}
+		units := utf16.Encode([]rune(string(body)))
+		out := make([]byte, 0, 2+2*len(units))
+		if enc == encodingUTF16LE {
+			out = append(out, bomUTF16LE...)
+			for _, u := range units {
+				out = append(out, byte(u), byte(u>>8))
+			}
+		} else {
+			out = append(out, bomUTF16BE...)
+			for _, u := range units {
+				out = append(out, byte(u>>8), byte(u))
+			}
+		}
+		return out, nil
+	case encodingBase64:
+		return base64.StdEncoding.DecodeString(string(bytes.TrimSpace(body)))
+	}
+	return nil, fmt.Errorf("unknown encoding %q", enc)
+}
+
+// rawETag identifies a file version by size and modification time, so
+// large files needn't be hashed on every range request.
+func rawETag(info os.FileInfo) string {
+	return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
+
+// workspaceFile validates ?path= and returns its location on disk, or
+// writes the error response.
+func workspaceFile(w http.ResponseWriter, r *http.Request, path string) (string, bool) {
+	if path == "" {
+		http.Error(w, "path required", 400)
+		return "", false
+	}
+	fullPath, err := resolveWorkspacePath(path)
+	if err != nil {
+		pathError(w, r, path, err)
+		return "", false
+	}
+	return fullPath, true
+}
+
+// noDeadlines lifts the server's read and write timeouts for a request that
+// streams a large body.
+func noDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+}
+
+// fileRawHandler streams a file as is (GET/HEAD, with Range and
+// conditional requests) or replaces it with the request body (PUT).
+func fileRawHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		rawDownload(w, r)
+	case http.MethodPut:
+		rawUpload(w, r)
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+func rawDownload(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	fullPath, ok := workspaceFile(w, r, path)
+	if !ok {
+		return
+	}
+	serveWorkspaceFile(w, r, path, fullPath)
+}
+
+// serveWorkspaceFile streams a file that passed the path policy.
+func serveWorkspaceFile(w http.ResponseWriter, r *http.Request, path, fullPath string) {
+	f, err := os.Open(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "file not found", 404)
+		} else {
+			http.Error(w, err.Error(), 500)
+		}
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if info.IsDir() {
+		http.Error(w, "path is a directory", 400)
+		return
+	}
+	noDeadlines(w)
+
+	name := filepath.Base(path)
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "true" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, name))
+	// Workspace files are untrusted; never let them run as part of the IDE
+	w.Header().Set("Content-Security-Policy", "sandbox")
+
+	if r.URL.Query().Get("encoding") == encodingBase64 {
+		w.Header().Set("Content-Type", "text/plain; charset=us-ascii")
+		w.Header().Set("ETag", rawETag(info))
+		if r.Method == http.MethodHead {
+			return
+		}
+		enc := base64.NewEncoder(base64.StdEncoding, w)
+		if _, err := io.Copy(enc, f); err != nil {
+			logWithRequestID(r, "Download of %s aborted: %v", path, err)
+			return
+		}
+		enc.Close()
+		return
+	}
+
+	w.Header().Set("Content-Type", getMimeType(fullPath))
+	w.Header().Set("ETag", rawETag(info))
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// rawUpload streams the body into a temp file and renames it over the
+// target. If-Match takes the ETag /files/raw returned; If-None-Match: *
+// refuses to overwrite.
+func rawUpload(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	fullPath, ok := workspaceFile(w, r, path)
+	if !ok {
+		return
+	}
+	if r.ContentLength > maxUploadSize {
+		http.Error(w, "file too large", 413)
+		return
+	}
+	noDeadlines(w)
+
+	var body io.Reader = r.Body
+	if r.URL.Query().Get("encoding") == encodingBase64 {
+		body = base64.NewDecoder(base64.StdEncoding, r.Body)
+	}
+	tmp, err := tempFileFor(fullPath)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, io.LimitReader(body, maxUploadSize+1))
+	if err == nil && n > maxUploadSize {
+		err = errors.New("file too large")
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		logWithRequestID(r, "Upload of %s failed: %v", path, err)
+		http.Error(w, err.Error(), 400)
+		return
+	}
+
+	saveMu.Lock()
+	defer saveMu.Unlock()
+	if status, msg := checkRawPrecondition(r, fullPath); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	info, err := replaceFile(tmp.Name(), fullPath)
+	if err != nil {
+		logWithRequestID(r, "Failed to save upload %s: %v", path, err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	logWithRequestID(r, "Uploaded %s (%d bytes)", path, n)
+	w.Header().Set("ETag", rawETag(info))
+	writeFileInfo(w, path, info)
+}
+
+// checkRawPrecondition evaluates If-Match / If-None-Match against the
+// size-and-time ETag. It returns 0 when the write may go ahead.
+func checkRawPrecondition(r *http.Request, fullPath string) (int, string) {
+	ifMatch, ifNoneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+	if ifMatch == "" && ifNoneMatch == "" {
+		return 0, ""
+	}
+	info, err := os.Stat(fullPath)
+	exists := err == nil
+	if ifNoneMatch == "*" && exists {
+		return http.StatusPreconditionFailed, "file already exists"
+	}
+	if ifMatch != "" {
+		if !exists {
+			return http.StatusPreconditionFailed, "file no longer exists"
+		}
+		if ifMatch != "*" && !etagMatches(ifMatch, rawETag(info)) {
+			return http.StatusPreconditionFailed, "file changed on server"
+		}
+	}
+	return 0, ""
+}
+
+// tempFileFor creates a hidden temp file next to path, so it can be renamed
+// into place.
+func tempFileFor(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+}
+
+// replaceFile renames tmp over path, keeping the permissions of the file it
+// replaces.
+func replaceFile(tmp, path string) (os.FileInfo, error) {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		return nil, err
+	}
+	noteAtomicSave(tmp, path)
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+	return os.Stat(path)
+}
+
+func writeFileInfo(w http.ResponseWriter, path string, info os.FileInfo) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"path":    path,
+		"size":    info.Size(),
+		"modTime": info.ModTime().Unix(),
+		"etag":    rawETag(info),
+	})
+}
+
+// upload is a resumable upload in progress. Chunks are appended to a
+// hidden file next to the target, which replaces the target once all
+// bytes have arrived.
+type upload struct {
+	ID      string    `json:"id"`
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	Offset  int64     `json:"offset"`
+	Updated time.Time `json:"updated"`
+
+	mu   sync.Mutex
+	file string
+}
+
+var uploads = struct {
+	sync.Mutex
+	byID map[string]*upload
+}{byID: map[string]*upload{}}
+
+func findUpload(id string) *upload {
+	uploads.Lock()
+	defer uploads.Unlock()
+	return uploads.byID[id]
+}
+
+func dropUpload(u *upload) {
+	uploads.Lock()
+	delete(uploads.byID, u.ID)
+	uploads.Unlock()
+	os.Remove(u.file)
+}
+
+// expireUploads drops uploads abandoned for longer than uploadExpiry.
+func expireUploads() {
+	uploads.Lock()
+	var stale []*upload
+	for _, u := range uploads.byID {
+		u.mu.Lock()
+		if time.Since(u.Updated) > uploadExpiry {
+			stale = append(stale, u)
+		}
+		u.mu.Unlock()
+	}
+	uploads.Unlock()
+	for _, u := range stale {
+		dropUpload(u)
+	}
+}
+
+func (u *upload) snapshot() map[string]interface{} {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return map[string]interface{}{
+		"id":      u.ID,
+		"path":    u.Path,
+		"size":    u.Size,
+		"offset":  u.Offset,
+		"updated": u.Updated,
+	}
+}
+
+// fileUploadHandler runs resumable uploads:
+//
+//	POST   /files/upload {"path", "size"}   start, returns the upload id
+//	PUT    /files/upload?id=&offset=        append the chunk in the body
+//	GET    /files/upload?id=                the offset to resume from
+//	DELETE /files/upload?id=                abandon
+//
+// The chunk that completes the upload moves the file into place.
+func fileUploadHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method == http.MethodPost {
+		startUpload(w, r)
+		return
+	}
+
+	u := findUpload(r.URL.Query().Get("id"))
+	if u == nil {
+		http.Error(w, "upload not found", 404)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(u.snapshot())
+	case http.MethodPut:
+		appendUpload(w, r, u)
+	case http.MethodDelete:
+		dropUpload(u)
+		logWithRequestID(r, "Abandoned upload %s (%s)", u.ID, u.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", 405)
+	}
+}
+
+func startUpload(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Path string `json:"path"`
+		Size int64  `json:"size"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", 400)
+		return
+	}
+	fullPath, ok := workspaceFile(w, r, body.Path)
+	if !ok {
+		return
+	}
+	if body.Size < 0 || body.Size > maxUploadSize {
+		http.Error(w, "invalid size", 400)
+		return
+	}
+	if info, err := os.Stat(fullPath); err == nil && info.IsDir() {
+		http.Error(w, "path is a directory", 400)
+		return
+	}
+	expireUploads()
+
+	tmp, err := tempFileFor(fullPath)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	tmp.Close()
+	u := &upload{
+		ID:      generateRequestID(),
+		Path:    body.Path,
+		Size:    body.Size,
+		Updated: time.Now(),
+		file:    tmp.Name(),
+	}
+	uploads.Lock()
+	uploads.byID[u.ID] = u
+	uploads.Unlock()
+	logWithRequestID(r, "Started upload %s of %s (%d bytes)", u.ID, u.Path, u.Size)
+
+	if u.Size == 0 {
+		finishUpload(w, r, u)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(u.snapshot())
+}
+
+// appendUpload writes a chunk at ?offset=, which must be where the previous
+// chunk ended; a mismatch returns 409 with the offset to resume from.
+func appendUpload(w http.ResponseWriter, r *http.Request, u *upload) {
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil {
+		http.Error(w, "offset required", 400)
+		return
+	}
+	noDeadlines(w)
+
+	u.mu.Lock()
+	if offset != u.Offset {
+		u.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(u.snapshot())
+		return
+	}
+	var body io.Reader = r.Body
+	if r.URL.Query().Get("encoding") == encodingBase64 {
+		body = base64.NewDecoder(base64.StdEncoding, r.Body)
+	}
+	f, err := os.OpenFile(u.file, os.O_WRONLY, 0)
+	if err == nil {
+		_, err = f.Seek(offset, io.SeekStart)
+	}
+	var n int64
+	if err == nil {
+		// Bytes past the declared size are refused, not written
+		n, err = io.Copy(f, io.LimitReader(body, u.Size-offset+1))
+		if err == nil && offset+n > u.Size {
+			err = errors.New("chunk goes past the declared size")
+			n = 0
+		}
+	}
+	if f != nil {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		// Drop whatever part of the chunk was written; the client resends it
+		_ = os.Truncate(u.file, u.Offset)
+		u.mu.Unlock()
+		logWithRequestID(r, "Upload %s chunk at %d failed: %v", u.ID, offset, err)
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	u.Offset += n
+	u.Updated = time.Now()
+	done := u.Offset == u.Size
+	u.mu.Unlock()
+
+	if done {
+		finishUpload(w, r, u)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u.snapshot())
+}
+
+func finishUpload(w http.ResponseWriter, r *http.Request, u *upload) {
+	uploads.Lock()
+	delete(uploads.byID, u.ID)
+	uploads.Unlock()
+	defer os.Remove(u.file)
+
+	// Check the path again: a symlink may have appeared since the upload
+	// started
+	fullPath, err := resolveWorkspacePath(u.Path)
+	if err != nil {
+		pathError(w, r, u.Path, err)
+		return
+	}
+	if f, err := os.OpenFile(u.file, os.O_WRONLY, 0); err == nil {
+		f.Sync()
+		f.Close()
+	}
+	saveMu.Lock()
+	info, err := replaceFile(u.file, fullPath)
+	saveMu.Unlock()
+	if err != nil {
+		if errors.Is(err, syscall.ENOENT) {
+			err = errors.New("upload was abandoned")
+		}
+		logWithRequestID(r, "Failed to complete upload %s: %v", u.ID, err)
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	logWithRequestID(r, "Completed upload %s of %s (%d bytes)", u.ID, u.Path, info.Size())
+	w.Header().Set("ETag", rawETag(info))
+	writeFileInfo(w, u.Path, info)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"empty", nil, encodingUTF8},
+		{"ascii", []byte("package main\n"), encodingUTF8},
+		{"utf-8", []byte("héllo wörld"), encodingUTF8},
+		{"utf-8 bom", []byte("\xEF\xBB\xBFhi"), encodingUTF8BOM},
+		{"utf-16le", []byte{0xFF, 0xFE, 'h', 0, 'i', 0}, encodingUTF16LE},
+		{"utf-16be", []byte{0xFE, 0xFF, 0, 'h', 0, 'i'}, encodingUTF16BE},
+		{"utf-16 odd length", []byte{0xFF, 0xFE, 'h'}, encodingBase64},
+		{"nul byte", []byte("a\x00b"), encodingBase64},
+		{"invalid utf-8", []byte{0xC3, 0x28}, encodingBase64},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00"), encodingBase64},
+	}
+	for _, tt := range tests {
+		if got := detectEncoding(tt.data); got != tt.want {
+			t.Errorf("%s: detectEncoding = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeContentRoundTrip(t *testing.T) {
+	files := [][]byte{
+		[]byte("plain text\n"),
+		[]byte("\xEF\xBB\xBFwith bom"),
+		{0xFF, 0xFE, 'h', 0, 0xE9, 0, '!', 0},
+		{0xFE, 0xFF, 0, 'h', 0, 0xE9, 0xD8, 0x3D, 0xDE, 0x00},
+		{0x89, 'P', 'N', 'G', 0, 1, 2, 0xFF},
+	}
+	for _, data := range files {
+		enc := detectEncoding(data)
+		got, err := encodeContent([]byte(decodeContent(data, enc)), enc)
+		if err != nil {
+			t.Errorf("%s: encodeContent: %v", enc, err)
+			continue
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: round trip = %x, want %x", enc, got, data)
+		}
+	}
+}
+
+func TestEncodeContentErrors(t *testing.T) {
+	if _, err := encodeContent([]byte("not base64!"), encodingBase64); err == nil {
+		t.Error("invalid base64 should fail")
+	}
+	if _, err := encodeContent([]byte{0xC3, 0x28}, encodingUTF16LE); err == nil {
+		t.Error("invalid UTF-8 for a UTF-16 file should fail")
+	}
+	if _, err := encodeContent([]byte("x"), "latin1"); err == nil {
+		t.Error("unknown encoding should fail")
+	}
+}
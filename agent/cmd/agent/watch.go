@@ -0,0 +1,575 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Events on the same path within this window are coalesced
+	watchDebounce = 150 * time.Millisecond
+	// Pending batches buffered per subscriber before it is dropped
+	watchQueueSize = 64
+	// Upper bound on watched directories, well under the usual
+	// fs.inotify.max_user_watches so other tools still get some
+	maxWatchDirs = 8192
+
+	watchMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE |
+		syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+		syscall.IN_ATTRIB | syscall.IN_DELETE_SELF | syscall.IN_ONLYDIR
+)
+
+// fileEvent is a single change notification sent to watch subscribers.
+type fileEvent struct {
+	Op      string `json:"op"` // create | modify | delete | rename
+	Path    string `json:"path"`
+	OldPath string `json:"oldPath,omitempty"`
+	IsDir   bool   `json:"isDir"`
+
+	// Set on the move-out half of an atomic save that created the file
+	newFile bool
+}
+
+// fileWatcher streams inotify events for the whole workspace tree.
+type fileWatcher struct {
+	root string
+	fd   int
+	file *os.File // fd, pollable so close interrupts run
+	done chan struct{}
+
+	mu    sync.Mutex
+	dirs  map[int32]string // watch descriptor -> absolute dir
+	wds   map[string]int32 // absolute dir -> watch descriptor
+	subs  map[chan []fileEvent]struct{}
+	queue []fileEvent
+	timer *time.Timer
+	// IN_MOVED_FROM halves waiting for their IN_MOVED_TO, keyed by cookie
+	moves map[uint32]fileEvent
+	// Set once the watch limit has been hit and logged
+	limitHit bool
+	closed   bool
+}
+
+var (
+	watcherMu sync.Mutex
+	watcher   *fileWatcher
+)
+
+// startFileWatcher starts the workspace watcher once the repository exists.
+func startFileWatcher() {
+	watcherMu.Lock()
+	defer watcherMu.Unlock()
+	if watcher != nil {
+		return
+	}
+	fw, err := newFileWatcher("/workspace")
+	if err != nil {
+		log.Printf("Failed to start file watcher: %v", err)
+		return
+	}
+	watcher = fw
+	go fw.run()
+	log.Printf("File watcher started (%d directories)", len(fw.dirs))
+}
+
+// stopFileWatcher shuts the workspace watcher down, disconnecting its
+// subscribers.
+func stopFileWatcher() {
+	watcherMu.Lock()
+	fw := watcher
+	watcher = nil
+	watcherMu.Unlock()
+	if fw != nil {
+		fw.close()
+	}
+}
+
+func getFileWatcher() *fileWatcher {
+	watcherMu.Lock()
+	defer watcherMu.Unlock()
+	return watcher
+}
+
+func newFileWatcher(root string) (*fileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	fw := &fileWatcher{
+		root:  root,
+		fd:    fd,
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		done:  make(chan struct{}),
+		dirs:  map[int32]string{},
+		wds:   map[string]int32{},
+		subs:  map[chan []fileEvent]struct{}{},
+		moves: map[uint32]fileEvent{},
+	}
+	fw.mu.Lock()
+	fw.addTree(root)
+	fw.mu.Unlock()
+	return fw, nil
+}
+
+// isWatchExcluded mirrors fileListHandler, which hides .git.
+func isWatchExcluded(name string) bool {
+	return name == ".git"
+}
+
+// isAtomicTempName matches the temp files written by writeFileAtomic:
+// os.CreateTemp's ".<base>.tmp-<digits>".
+func isAtomicTempName(name string) bool {
+	i := strings.LastIndex(name, ".tmp-")
+	if !strings.HasPrefix(name, ".") || i < 2 {
+		return false
+	}
+	digits := name[i+len(".tmp-"):]
+	if digits == "" {
+		return false
+	}
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// atomicSaves records, per temp file, whether the file it is about to be
+// renamed over already exists. By the time the watcher sees the rename the
+// target has been replaced, so it can't tell a new file from a changed one.
+var atomicSaves sync.Map
+
+// noteAtomicSave is called just before renaming tmp over target.
+func noteAtomicSave(tmp, target string) {
+	_, err := os.Lstat(target)
+	atomicSaves.Store(tmp, err == nil)
+}
+
+// addTree watches dir and its subdirectories, skipping excluded, denied and
+// gitignored trees. It walks breadth first so ignored trees such as
+// node_modules are never read, with one git check-ignore per level.
+// Caller holds fw.mu.
+func (fw *fileWatcher) addTree(dir string) {
+	level := []string{dir}
+	if dir != fw.root {
+		level = watchableDirs(level)
+	}
+	for len(level) > 0 {
+		var next []string
+		for _, d := range level {
+			if !fw.addWatch(d) {
+				return
+			}
+			entries, err := os.ReadDir(d)
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				if e.IsDir() && !isWatchExcluded(e.Name()) {
+					next = append(next, filepath.Join(d, e.Name()))
+				}
+			}
+		}
+		level = watchableDirs(next)
+	}
+}
+
+// addWatch watches a single directory. It reports false once the watch
+// limit is reached. Caller holds fw.mu.
+func (fw *fileWatcher) addWatch(dir string) bool {
+	if len(fw.wds) >= maxWatchDirs {
+		fw.noteWatchLimit(fmt.Sprintf("%d directories", maxWatchDirs))
+		return false
+	}
+	wd, err := syscall.InotifyAddWatch(fw.fd, dir, watchMask)
+	if err == syscall.ENOSPC {
+		fw.noteWatchLimit("fs.inotify.max_user_watches")
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to watch %s: %v", dir, err)
+		return true
+	}
+	fw.dirs[int32(wd)] = dir
+	fw.wds[dir] = int32(wd)
+	return true
+}
+
+// noteWatchLimit logs the first time a directory couldn't be watched for
+// lack of watches; after that, new directories go unwatched silently.
+func (fw *fileWatcher) noteWatchLimit(limit string) {
+	if fw.limitHit {
+		return
+	}
+	fw.limitHit = true
+	log.Printf("File watcher limit reached (%s); changes in further directories will not be reported", limit)
+}
+
+// watchableDirs drops denied and gitignored directories.
+func watchableDirs(dirs []string) []string {
+	var candidates, relPaths []string
+	for _, d := range dirs {
+		rel := strings.TrimPrefix(d, "/workspace/")
+		if pathDenied(rel) {
+			continue
+		}
+		candidates = append(candidates, d)
+		relPaths = append(relPaths, rel+"/")
+	}
+	ignored := gitCheckIgnore(relPaths)
+	out := candidates[:0]
+	for i, d := range candidates {
+		if !ignored[relPaths[i]] {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// removeTree drops watches for dir and everything beneath it. Caller holds fw.mu.
+func (fw *fileWatcher) removeTree(dir string) {
+	for path, wd := range fw.wds {
+		if path == dir || strings.HasPrefix(path, dir+"/") {
+			_, _ = syscall.InotifyRmWatch(fw.fd, uint32(wd))
+			delete(fw.wds, path)
+			delete(fw.dirs, wd)
+		}
+	}
+}
+
+func (fw *fileWatcher) relPath(abs string) string {
+	return strings.TrimPrefix(abs, fw.root)
+}
+
+func (fw *fileWatcher) run() {
+	defer close(fw.done)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := fw.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				log.Printf("File watcher read error: %v", err)
+			}
+			return
+		}
+		fw.mu.Lock()
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(ev.Len)]
+			name := strings.TrimRight(string(nameBytes), "\x00")
+			fw.handle(ev, name)
+			offset += syscall.SizeofInotifyEvent + int(ev.Len)
+		}
+		fw.mu.Unlock()
+	}
+}
+
+// handle translates one raw inotify event. Caller holds fw.mu.
+func (fw *fileWatcher) handle(ev *syscall.InotifyEvent, name string) {
+	if ev.Mask&syscall.IN_Q_OVERFLOW != 0 {
+		log.Printf("File watcher queue overflow; some events were lost")
+		return
+	}
+	if ev.Mask&syscall.IN_IGNORED != 0 {
+		if dir, ok := fw.dirs[ev.Wd]; ok {
+			delete(fw.dirs, ev.Wd)
+			delete(fw.wds, dir)
+		}
+		return
+	}
+	dir, ok := fw.dirs[ev.Wd]
+	if !ok || name == "" || isWatchExcluded(name) {
+		return
+	}
+	abs := filepath.Join(dir, name)
+	isDir := ev.Mask&syscall.IN_ISDIR != 0
+	e := fileEvent{Path: fw.relPath(abs), IsDir: isDir}
+
+	// Temp files from atomic saves are invisible; their final rename into
+	// place is reported as a modification of the target, or its creation
+	// if it didn't exist.
+	if isAtomicTempName(name) {
+		if ev.Mask&syscall.IN_DELETE != 0 {
+			atomicSaves.Delete(abs)
+		}
+		if ev.Mask&syscall.IN_MOVED_FROM != 0 {
+			existed, known := atomicSaves.LoadAndDelete(abs)
+			fw.moves[ev.Cookie] = fileEvent{Op: "save", newFile: known && !existed.(bool)}
+			cookie := ev.Cookie
+			time.AfterFunc(watchDebounce, func() {
+				fw.mu.Lock()
+				delete(fw.moves, cookie)
+				fw.mu.Unlock()
+			})
+		}
+		return
+	}
+
+	switch {
+	case ev.Mask&syscall.IN_CREATE != 0:
+		if isDir {
+			fw.addTree(abs)
+		}
+		e.Op = "create"
+	case ev.Mask&syscall.IN_DELETE != 0:
+		e.Op = "delete"
+	case ev.Mask&syscall.IN_MOVED_FROM != 0:
+		if isDir {
+			fw.removeTree(abs)
+		}
+		e.Op = "delete"
+		fw.moves[ev.Cookie] = e
+		// An unpaired move-out (e.g. to outside the workspace) is a delete
+		cookie := ev.Cookie
+		time.AfterFunc(watchDebounce, func() {
+			fw.mu.Lock()
+			defer fw.mu.Unlock()
+			if from, ok := fw.moves[cookie]; ok {
+				delete(fw.moves, cookie)
+				fw.enqueue(from)
+			}
+		})
+		return
+	case ev.Mask&syscall.IN_MOVED_TO != 0:
+		if isDir {
+			fw.addTree(abs)
+		}
+		e.Op = "create"
+		if from, ok := fw.moves[ev.Cookie]; ok {
+			delete(fw.moves, ev.Cookie)
+			switch {
+			case from.Op != "save":
+				e.Op = "rename"
+				e.OldPath = from.Path
+			case !from.newFile:
+				e.Op = "modify"
+			}
+		}
+	case ev.Mask&(syscall.IN_MODIFY|syscall.IN_CLOSE_WRITE|syscall.IN_ATTRIB) != 0:
+		if isDir {
+			return
+		}
+		e.Op = "modify"
+	default:
+		return
+	}
+	fw.enqueue(e)
+}
+
+// enqueue adds an event to the pending batch and (re)arms the debounce
+// timer. Caller holds fw.mu.
+func (fw *fileWatcher) enqueue(e fileEvent) {
+	if fw.closed {
+		return
+	}
+	e, ok := visibleEvent(e)
+	if !ok {
+		return
+	}
+	fw.queue = append(fw.queue, e)
+	if fw.timer == nil {
+		fw.timer = time.AfterFunc(watchDebounce, fw.flush)
+	} else {
+		fw.timer.Reset(watchDebounce)
+	}
+}
+
+// visibleEvent hides denied paths from subscribers. A rename across the
+// denylist boundary shows up as a create or delete of the visible side.
+func visibleEvent(e fileEvent) (fileEvent, bool) {
+	denied := pathDenied(e.Path)
+	if e.OldPath == "" {
+		return e, !denied
+	}
+	oldDenied := pathDenied(e.OldPath)
+	switch {
+	case denied && oldDenied:
+		return e, false
+	case denied:
+		return fileEvent{Op: "delete", Path: e.OldPath, IsDir: e.IsDir}, true
+	case oldDenied:
+		return fileEvent{Op: "create", Path: e.Path, IsDir: e.IsDir}, true
+	}
+	return e, true
+}
+
+// flush coalesces the pending batch and delivers it to all subscribers.
+func (fw *fileWatcher) flush() {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	batch := coalesceEvents(fw.queue)
+	fw.queue = nil
+	if len(batch) == 0 {
+		return
+	}
+	for ch := range fw.subs {
+		select {
+		case ch <- batch:
+		default:
+			// Subscriber can't keep up; drop it
+			delete(fw.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// coalesceEvents collapses repeated events on the same path, keeping the
+// order in which paths were first seen.
+func coalesceEvents(events []fileEvent) []fileEvent {
+	index := map[string]int{}
+	var out []fileEvent
+	for _, e := range events {
+		i, seen := index[e.Path]
+		if !seen || e.Op == "rename" {
+			index[e.Path] = len(out)
+			out = append(out, e)
+			continue
+		}
+		prev := &out[i]
+		switch {
+		case prev.Op == "create" && e.Op == "modify":
+			// still a create
+		case prev.Op == "create" && e.Op == "delete":
+			prev.Op = "" // never existed as far as clients are concerned
+		case prev.Op == "delete" && e.Op == "create":
+			prev.Op = "modify"
+		case prev.Op == "" && e.Op == "create":
+			prev.Op = "create"
+		default:
+			prev.Op = e.Op
+		}
+		prev.IsDir = e.IsDir
+	}
+	filtered := out[:0]
+	for _, e := range out {
+		if e.Op != "" {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// close stops the watcher and waits for run to return.
+func (fw *fileWatcher) close() {
+	fw.mu.Lock()
+	fw.closed = true
+	fw.file.Close()
+	if fw.timer != nil {
+		fw.timer.Stop()
+	}
+	for ch := range fw.subs {
+		delete(fw.subs, ch)
+		close(ch)
+	}
+	fw.mu.Unlock()
+	<-fw.done
+}
+
+func (fw *fileWatcher) subscribe() chan []fileEvent {
+	ch := make(chan []fileEvent, watchQueueSize)
+	fw.mu.Lock()
+	fw.subs[ch] = struct{}{}
+	fw.mu.Unlock()
+	return ch
+}
+
+func (fw *fileWatcher) unsubscribe(ch chan []fileEvent) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if _, ok := fw.subs[ch]; ok {
+		delete(fw.subs, ch)
+		close(ch)
+	}
+}
+
+// fileWatchHandler streams batches of file events over a websocket.
+// An optional ?path= restricts events to a subtree.
+func fileWatchHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	prefix := ""
+	if p := r.URL.Query().Get("path"); p != "" {
+		if _, err := resolveWorkspacePath(p); err != nil {
+			pathError(w, r, p, err)
+			return
+		}
+		prefix = "/" + filepath.Clean(p)
+	}
+	fw := getFileWatcher()
+	if fw == nil {
+		http.Error(w, "file watcher unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logWithRequestID(r, "WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ch := fw.subscribe()
+	defer fw.unsubscribe(ch)
+	logWithRequestID(r, "File watch subscriber connected (path=%q)", prefix)
+
+	// Reader only exists to notice the client going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case batch, ok := <-ch:
+			if !ok {
+				_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"))
+				return
+			}
+			if prefix != "" {
+				batch = filterEvents(batch, prefix)
+				if len(batch) == 0 {
+					continue
+				}
+			}
+			if err := conn.WriteJSON(batch); err != nil {
+				logWithRequestID(r, "File watch write error: %v", err)
+				return
+			}
+		case <-closed:
+			logWithRequestID(r, "File watch subscriber disconnected")
+			return
+		}
+	}
+}
+
+func filterEvents(events []fileEvent, prefix string) []fileEvent {
+	under := func(p string) bool {
+		return p == prefix || strings.HasPrefix(p, prefix+"/")
+	}
+	var out []fileEvent
+	for _, e := range events {
+		if under(e.Path) || (e.OldPath != "" && under(e.OldPath)) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
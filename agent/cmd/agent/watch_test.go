@@ -0,0 +1,279 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCoalesceEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []fileEvent
+		want []fileEvent
+	}{
+		{
+			name: "repeated modifies",
+			in:   []fileEvent{{Op: "modify", Path: "/a"}, {Op: "modify", Path: "/a"}, {Op: "modify", Path: "/a"}},
+			want: []fileEvent{{Op: "modify", Path: "/a"}},
+		},
+		{
+			name: "create then modify",
+			in:   []fileEvent{{Op: "create", Path: "/a"}, {Op: "modify", Path: "/a"}},
+			want: []fileEvent{{Op: "create", Path: "/a"}},
+		},
+		{
+			name: "create then delete",
+			in:   []fileEvent{{Op: "create", Path: "/a"}, {Op: "modify", Path: "/a"}, {Op: "delete", Path: "/a"}},
+			want: nil,
+		},
+		{
+			name: "delete then create",
+			in:   []fileEvent{{Op: "delete", Path: "/a"}, {Op: "create", Path: "/a"}},
+			want: []fileEvent{{Op: "modify", Path: "/a"}},
+		},
+		{
+			name: "create delete create",
+			in:   []fileEvent{{Op: "create", Path: "/a"}, {Op: "delete", Path: "/a"}, {Op: "create", Path: "/a"}},
+			want: []fileEvent{{Op: "create", Path: "/a"}},
+		},
+		{
+			name: "keeps first-seen order",
+			in:   []fileEvent{{Op: "modify", Path: "/b"}, {Op: "create", Path: "/a"}, {Op: "modify", Path: "/b"}},
+			want: []fileEvent{{Op: "modify", Path: "/b"}, {Op: "create", Path: "/a"}},
+		},
+		{
+			name: "renames are not merged",
+			in:   []fileEvent{{Op: "modify", Path: "/b"}, {Op: "rename", Path: "/b", OldPath: "/a"}},
+			want: []fileEvent{{Op: "modify", Path: "/b"}, {Op: "rename", Path: "/b", OldPath: "/a"}},
+		},
+		{
+			name: "file replaced by directory",
+			in:   []fileEvent{{Op: "delete", Path: "/a"}, {Op: "create", Path: "/a", IsDir: true}},
+			want: []fileEvent{{Op: "modify", Path: "/a", IsDir: true}},
+		},
+	}
+	for _, tt := range tests {
+		got := coalesceEvents(tt.in)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsAtomicTempName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{".main.go.tmp-123456", true},
+		{".env.tmp-1", true},
+		{"main.go.tmp-123", false},
+		{".main.go.tmp-", false},
+		{".main.go.tmp-12a", false},
+		{".tmp-123", false},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		if got := isAtomicTempName(tt.name); got != tt.want {
+			t.Errorf("isAtomicTempName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVisibleEvent(t *testing.T) {
+	saved := cfg.FileDenylist
+	defer func() { cfg.FileDenylist = saved }()
+	cfg.FileDenylist = parseDenylist(defaultFileDenylist)
+
+	tests := []struct {
+		in     fileEvent
+		want   fileEvent
+		wantOK bool
+	}{
+		{fileEvent{Op: "modify", Path: "/main.go"}, fileEvent{Op: "modify", Path: "/main.go"}, true},
+		{fileEvent{Op: "modify", Path: "/.env"}, fileEvent{}, false},
+		{fileEvent{Op: "rename", Path: "/b.go", OldPath: "/a.go"}, fileEvent{Op: "rename", Path: "/b.go", OldPath: "/a.go"}, true},
+		{fileEvent{Op: "rename", Path: "/.env", OldPath: "/env.txt"}, fileEvent{Op: "delete", Path: "/env.txt"}, true},
+		{fileEvent{Op: "rename", Path: "/env.txt", OldPath: "/.env"}, fileEvent{Op: "create", Path: "/env.txt"}, true},
+		{fileEvent{Op: "rename", Path: "/.env", OldPath: "/key.pem"}, fileEvent{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := visibleEvent(tt.in)
+		if ok != tt.wantOK || (ok && got != tt.want) {
+			t.Errorf("visibleEvent(%+v) = %+v, %v, want %+v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFileWatcherDebounce(t *testing.T) {
+	fw, root := startTestWatcher(t)
+	ch := fw.subscribe()
+
+	path := filepath.Join(root, "busy.txt")
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []fileEvent{{Op: "create", Path: "/busy.txt"}}
+	if got := nextBatch(t, ch); !reflect.DeepEqual(got, want) {
+		t.Errorf("batch = %+v, want %+v", got, want)
+	}
+	expectNoBatch(t, ch)
+}
+
+func TestFileWatcherAtomicSave(t *testing.T) {
+	fw, root := startTestWatcher(t)
+	existing := filepath.Join(root, "existing.txt")
+	if err := os.WriteFile(existing, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ch := fw.subscribe()
+	// Let the write above flush before subscribing to what follows
+	time.Sleep(2 * watchDebounce)
+	drain(ch)
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"existing.txt", "modify"},
+		{"new.txt", "create"},
+	}
+	for _, tt := range tests {
+		if err := writeFileAtomic(filepath.Join(root, tt.file), []byte("data")); err != nil {
+			t.Fatal(err)
+		}
+		want := []fileEvent{{Op: tt.want, Path: "/" + tt.file}}
+		if got := nextBatch(t, ch); !reflect.DeepEqual(got, want) {
+			t.Errorf("atomic save of %s: batch = %+v, want %+v", tt.file, got, want)
+		}
+	}
+}
+
+func TestFileWatcherRename(t *testing.T) {
+	fw, root := startTestWatcher(t)
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ch := fw.subscribe()
+	time.Sleep(2 * watchDebounce)
+	drain(ch)
+
+	if err := os.Rename(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")); err != nil {
+		t.Fatal(err)
+	}
+	want := []fileEvent{{Op: "rename", Path: "/b.txt", OldPath: "/a.txt"}}
+	if got := nextBatch(t, ch); !reflect.DeepEqual(got, want) {
+		t.Errorf("batch = %+v, want %+v", got, want)
+	}
+
+	// A directory created later is watched too
+	mustMkdir(t, filepath.Join(root, "later"))
+	nextBatch(t, ch)
+	if err := os.WriteFile(filepath.Join(root, "later", "c.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	want = []fileEvent{{Op: "create", Path: "/later/c.txt"}}
+	if got := nextBatch(t, ch); !reflect.DeepEqual(got, want) {
+		t.Errorf("batch = %+v, want %+v", got, want)
+	}
+}
+
+func TestFileWatcherSkipsIgnoredDirs(t *testing.T) {
+	if _, err := os.Stat("/workspace/.git"); err != nil {
+		t.Skip("/workspace is not a git repository")
+	}
+	root := testWorkspaceDir(t)
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("node_modules/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"src/lib", "node_modules/pkg/lib", ".git/objects", ".ssh"} {
+		mustMkdir(t, filepath.Join(root, dir))
+	}
+
+	fw, _ := startTestWatcherIn(t, root)
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	for _, dir := range []string{"", "src", "src/lib"} {
+		if _, ok := fw.wds[filepath.Join(root, dir)]; !ok {
+			t.Errorf("%q is not watched", dir)
+		}
+	}
+	for _, dir := range []string{"node_modules", "node_modules/pkg", ".git", ".ssh"} {
+		if _, ok := fw.wds[filepath.Join(root, dir)]; ok {
+			t.Errorf("%q is watched", dir)
+		}
+	}
+}
+
+// startTestWatcher watches a fresh directory under /workspace.
+func startTestWatcher(t *testing.T) (*fileWatcher, string) {
+	t.Helper()
+	return startTestWatcherIn(t, testWorkspaceDir(t))
+}
+
+func startTestWatcherIn(t *testing.T, root string) (*fileWatcher, string) {
+	t.Helper()
+	saved := cfg.FileDenylist
+	cfg.FileDenylist = parseDenylist(defaultFileDenylist)
+	fw, err := newFileWatcher(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go fw.run()
+	t.Cleanup(func() {
+		fw.close()
+		cfg.FileDenylist = saved
+	})
+	return fw, root
+}
+
+// testWorkspaceDir creates a scratch directory inside /workspace, which the
+// agent's path handling assumes is the repository root.
+func testWorkspaceDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("/workspace", "agent-test-")
+	if err != nil {
+		t.Skipf("/workspace is not writable: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func nextBatch(t *testing.T, ch chan []fileEvent) []fileEvent {
+	t.Helper()
+	select {
+	case batch := <-ch:
+		return batch
+	case <-time.After(5 * time.Second):
+		t.Fatal("no events")
+		return nil
+	}
+}
+
+func expectNoBatch(t *testing.T, ch chan []fileEvent) {
+	t.Helper()
+	select {
+	case batch := <-ch:
+		t.Errorf("unexpected batch %+v", batch)
+	case <-time.After(3 * watchDebounce):
+	}
+}
+
+func drain(ch chan []fileEvent) {
+	for {
+		select {
+		case <-ch:
+		default:
+			return
+		}
+	}
+}
@@ -3,8 +3,13 @@ module github.com/Aadithya-J/code_nest/agent
 go 1.24.4
 
 require (
+	github.com/Aadithya-J/code_nest/origins v0.0.0-00010101000000-000000000000
 	github.com/creack/pty v1.1.21
+	github.com/golang-jwt/jwt/v5 v5.2.1
 	github.com/gorilla/websocket v1.5.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require golang.org/x/net v0.17.0 // indirect
+
+replace github.com/Aadithya-J/code_nest/origins => ../origins
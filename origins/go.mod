@@ -0,0 +1,3 @@
+module github.com/Aadithya-J/code_nest/origins
+
+go 1.24.4
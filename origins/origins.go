@@ -0,0 +1,73 @@
+// Package origins parses and matches the ALLOWED_ORIGINS patterns shared by
+// the gateway and the workspace agents.
+package origins
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrWildcard is returned for a bare "*" pattern. Both the gateway and the
+// agents allow credentials, so every allowed origin must be named.
+var ErrWildcard = errors.New(`"*" is not allowed: credentials are enabled, list the origins instead`)
+
+// Parse splits a comma-separated ALLOWED_ORIGINS list. Patterns are
+// "scheme://host[:port]"; "https://*.codenest.dev" allows any subdomain of
+// codenest.dev (not the domain itself) and "http://localhost:*" any port.
+func Parse(s string) ([]string, error) {
+	var patterns []string
+	for _, o := range strings.Split(s, ",") {
+		o = strings.TrimSuffix(strings.TrimSpace(o), "/")
+		if o == "" {
+			continue
+		}
+		if o == "*" {
+			return nil, ErrWildcard
+		}
+		patterns = append(patterns, o)
+	}
+	return patterns, nil
+}
+
+// Allowed reports whether origin matches one of the patterns.
+func Allowed(patterns []string, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if Match(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether an origin fits a pattern. A bare "*" never matches.
+func Match(pattern, origin string) bool {
+	pScheme, pHost, ok := strings.Cut(strings.ToLower(pattern), "://")
+	if !ok {
+		return false
+	}
+	oScheme, oHost, ok := strings.Cut(strings.ToLower(origin), "://")
+	if !ok || pScheme != oScheme {
+		return false
+	}
+	pName, pPort := splitHost(pHost)
+	oName, oPort := splitHost(oHost)
+	if pPort != "*" && pPort != oPort {
+		return false
+	}
+	if suffix, ok := strings.CutPrefix(pName, "*"); ok {
+		return strings.HasPrefix(suffix, ".") && len(oName) > len(suffix) && strings.HasSuffix(oName, suffix)
+	}
+	return pName == oName
+}
+
+// splitHost splits "host:port" (or "[::1]:port"); port is "" when the
+// origin uses the scheme's default.
+func splitHost(host string) (string, string) {
+	if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+		return host[:i], host[i+1:]
+	}
+	return host, ""
+}
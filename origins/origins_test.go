@@ -0,0 +1,84 @@
+package origins
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+		err  error
+	}{
+		{"", nil, nil},
+		{"http://localhost:5173", []string{"http://localhost:5173"}, nil},
+		{" https://a.dev/ , ,http://localhost:* ", []string{"https://a.dev", "http://localhost:*"}, nil},
+		{"*", nil, ErrWildcard},
+		{"https://a.dev, *", nil, ErrWildcard},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, tt.err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, origin string
+		want            bool
+	}{
+		// Exact
+		{"https://codenest.dev", "https://codenest.dev", true},
+		{"https://codenest.dev", "https://CodeNest.dev", true},
+		{"https://codenest.dev", "https://evil.dev", false},
+		// Scheme
+		{"https://codenest.dev", "http://codenest.dev", false},
+		{"http://localhost:*", "https://localhost:5173", false},
+		{"codenest.dev", "https://codenest.dev", false},
+		{"https://codenest.dev", "codenest.dev", false},
+		// Port
+		{"http://localhost:5173", "http://localhost:5173", true},
+		{"http://localhost:5173", "http://localhost:3000", false},
+		{"http://localhost:5173", "http://localhost", false},
+		{"http://localhost", "http://localhost:5173", false},
+		{"http://localhost:*", "http://localhost:3000", true},
+		{"http://localhost:*", "http://localhost", true},
+		{"http://[::1]:*", "http://[::1]:8080", true},
+		{"http://[::1]", "http://[::1]:8080", false},
+		// Wildcard subdomains
+		{"https://*.codenest.dev", "https://ws-1.codenest.dev", true},
+		{"https://*.codenest.dev", "https://a.b.codenest.dev", true},
+		{"https://*.codenest.dev", "https://codenest.dev", false},
+		{"https://*.codenest.dev", "https://evilcodenest.dev", false},
+		{"https://*.codenest.dev", "https://codenest.dev.evil.com", false},
+		{"https://*codenest.dev", "https://evilcodenest.dev", false},
+		{"https://*.codenest.dev:*", "https://ws-1.codenest.dev:8443", true},
+		// A bare "*" is never a wildcard
+		{"*", "https://evil.dev", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.origin); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	patterns := []string{"https://*.codenest.dev", "http://localhost:*"}
+	if !Allowed(patterns, "http://localhost:5173") {
+		t.Error("localhost should be allowed")
+	}
+	if Allowed(patterns, "https://evil.dev") {
+		t.Error("evil.dev should not be allowed")
+	}
+	if Allowed(patterns, "") {
+		t.Error("an empty origin should not be allowed")
+	}
+}
@@ -737,6 +737,110 @@ func (x *ValidateTokenResponse) GetError() string {
 	return ""
 }
 
+type IssueWorkspaceTokenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	WorkspaceId   string                 `protobuf:"bytes,2,opt,name=workspace_id,json=workspaceId,proto3" json:"workspace_id,omitempty"` // atlas id
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IssueWorkspaceTokenRequest) Reset() {
+	*x = IssueWorkspaceTokenRequest{}
+	mi := &file_proto_auth_service_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IssueWorkspaceTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IssueWorkspaceTokenRequest) ProtoMessage() {}
+
+func (x *IssueWorkspaceTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_auth_service_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IssueWorkspaceTokenRequest.ProtoReflect.Descriptor instead.
+func (*IssueWorkspaceTokenRequest) Descriptor() ([]byte, []int) {
+	return file_proto_auth_service_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *IssueWorkspaceTokenRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *IssueWorkspaceTokenRequest) GetWorkspaceId() string {
+	if x != nil {
+		return x.WorkspaceId
+	}
+	return ""
+}
+
+type IssueWorkspaceTokenResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	ExpiresAt     int64                  `protobuf:"varint,2,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IssueWorkspaceTokenResponse) Reset() {
+	*x = IssueWorkspaceTokenResponse{}
+	mi := &file_proto_auth_service_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IssueWorkspaceTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IssueWorkspaceTokenResponse) ProtoMessage() {}
+
+func (x *IssueWorkspaceTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_auth_service_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IssueWorkspaceTokenResponse.ProtoReflect.Descriptor instead.
+func (*IssueWorkspaceTokenResponse) Descriptor() ([]byte, []int) {
+	return file_proto_auth_service_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *IssueWorkspaceTokenResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *IssueWorkspaceTokenResponse) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
 var File_proto_auth_service_proto protoreflect.FileDescriptor
 
 const file_proto_auth_service_proto_rawDesc = "" +
@@ -778,7 +882,14 @@ const file_proto_auth_service_proto_rawDesc = "" +
 	"\x15ValidateTokenResponse\x12\x14\n" +
 	"\x05valid\x18\x01 \x01(\bR\x05valid\x12\x17\n" +
 	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x14\n" +
-	"\x05error\x18\x03 \x01(\tR\x05error2\xb4\x05\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"X\n" +
+	"\x1aIssueWorkspaceTokenRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12!\n" +
+	"\fworkspace_id\x18\x02 \x01(\tR\vworkspaceId\"R\n" +
+	"\x1bIssueWorkspaceTokenResponse\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x02 \x01(\x03R\texpiresAt2\x90\x06\n" +
 	"\vAuthService\x121\n" +
 	"\x06Signup\x12\x13.auth.SignupRequest\x1a\x12.auth.AuthResponse\x12/\n" +
 	"\x05Login\x12\x12.auth.LoginRequest\x1a\x12.auth.AuthResponse\x12M\n" +
@@ -788,7 +899,8 @@ const file_proto_auth_service_proto_rawDesc = "" +
 	"\x14HandleGitHubCallback\x12!.auth.HandleGitHubCallbackRequest\x1a\x12.auth.AuthResponse\x12]\n" +
 	"\x14GetGitHubAccessToken\x12!.auth.GetGitHubAccessTokenRequest\x1a\".auth.GetGitHubAccessTokenResponse\x12T\n" +
 	"\x11GenerateRepoToken\x12\x1e.auth.GenerateRepoTokenRequest\x1a\x1f.auth.GenerateRepoTokenResponse\x12H\n" +
-	"\rValidateToken\x12\x1a.auth.ValidateTokenRequest\x1a\x1b.auth.ValidateTokenResponseB-Z+github.com/Aadithya-J/code_nest/proto;protob\x06proto3"
+	"\rValidateToken\x12\x1a.auth.ValidateTokenRequest\x1a\x1b.auth.ValidateTokenResponse\x12Z\n" +
+	"\x13IssueWorkspaceToken\x12 .auth.IssueWorkspaceTokenRequest\x1a!.auth.IssueWorkspaceTokenResponseB-Z+github.com/Aadithya-J/code_nest/proto;protob\x06proto3"
 
 var (
 	file_proto_auth_service_proto_rawDescOnce sync.Once
@@ -802,7 +914,7 @@ func file_proto_auth_service_proto_rawDescGZIP() []byte {
 	return file_proto_auth_service_proto_rawDescData
 }
 
-var file_proto_auth_service_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_proto_auth_service_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_proto_auth_service_proto_goTypes = []any{
 	(*SignupRequest)(nil),                // 0: auth.SignupRequest
 	(*LoginRequest)(nil),                 // 1: auth.LoginRequest
@@ -819,6 +931,8 @@ var file_proto_auth_service_proto_goTypes = []any{
 	(*GenerateRepoTokenResponse)(nil),    // 12: auth.GenerateRepoTokenResponse
 	(*ValidateTokenRequest)(nil),         // 13: auth.ValidateTokenRequest
 	(*ValidateTokenResponse)(nil),        // 14: auth.ValidateTokenResponse
+	(*IssueWorkspaceTokenRequest)(nil),   // 15: auth.IssueWorkspaceTokenRequest
+	(*IssueWorkspaceTokenResponse)(nil),  // 16: auth.IssueWorkspaceTokenResponse
 }
 var file_proto_auth_service_proto_depIdxs = []int32{
 	0,  // 0: auth.AuthService.Signup:input_type -> auth.SignupRequest
@@ -830,17 +944,19 @@ var file_proto_auth_service_proto_depIdxs = []int32{
 	9,  // 6: auth.AuthService.GetGitHubAccessToken:input_type -> auth.GetGitHubAccessTokenRequest
 	11, // 7: auth.AuthService.GenerateRepoToken:input_type -> auth.GenerateRepoTokenRequest
 	13, // 8: auth.AuthService.ValidateToken:input_type -> auth.ValidateTokenRequest
-	2,  // 9: auth.AuthService.Signup:output_type -> auth.AuthResponse
-	2,  // 10: auth.AuthService.Login:output_type -> auth.AuthResponse
-	2,  // 11: auth.AuthService.HandleGoogleCallback:output_type -> auth.AuthResponse
-	4,  // 12: auth.AuthService.GetGoogleAuthURL:output_type -> auth.GetGoogleAuthURLResponse
-	7,  // 13: auth.AuthService.GetGitHubAuthURL:output_type -> auth.GetGitHubAuthURLResponse
-	2,  // 14: auth.AuthService.HandleGitHubCallback:output_type -> auth.AuthResponse
-	10, // 15: auth.AuthService.GetGitHubAccessToken:output_type -> auth.GetGitHubAccessTokenResponse
-	12, // 16: auth.AuthService.GenerateRepoToken:output_type -> auth.GenerateRepoTokenResponse
-	14, // 17: auth.AuthService.ValidateToken:output_type -> auth.ValidateTokenResponse
-	9,  // [9:18] is the sub-list for method output_type
-	0,  // [0:9] is the sub-list for method input_type
+	15, // 9: auth.AuthService.IssueWorkspaceToken:input_type -> auth.IssueWorkspaceTokenRequest
+	2,  // 10: auth.AuthService.Signup:output_type -> auth.AuthResponse
+	2,  // 11: auth.AuthService.Login:output_type -> auth.AuthResponse
+	2,  // 12: auth.AuthService.HandleGoogleCallback:output_type -> auth.AuthResponse
+	4,  // 13: auth.AuthService.GetGoogleAuthURL:output_type -> auth.GetGoogleAuthURLResponse
+	7,  // 14: auth.AuthService.GetGitHubAuthURL:output_type -> auth.GetGitHubAuthURLResponse
+	2,  // 15: auth.AuthService.HandleGitHubCallback:output_type -> auth.AuthResponse
+	10, // 16: auth.AuthService.GetGitHubAccessToken:output_type -> auth.GetGitHubAccessTokenResponse
+	12, // 17: auth.AuthService.GenerateRepoToken:output_type -> auth.GenerateRepoTokenResponse
+	14, // 18: auth.AuthService.ValidateToken:output_type -> auth.ValidateTokenResponse
+	16, // 19: auth.AuthService.IssueWorkspaceToken:output_type -> auth.IssueWorkspaceTokenResponse
+	10, // [10:20] is the sub-list for method output_type
+	0,  // [0:10] is the sub-list for method input_type
 	0,  // [0:0] is the sub-list for extension type_name
 	0,  // [0:0] is the sub-list for extension extendee
 	0,  // [0:0] is the sub-list for field type_name
@@ -857,7 +973,7 @@ func file_proto_auth_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_auth_service_proto_rawDesc), len(file_proto_auth_service_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   15,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
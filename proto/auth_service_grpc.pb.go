@@ -28,6 +28,7 @@ const (
 	AuthService_GetGitHubAccessToken_FullMethodName = "/auth.AuthService/GetGitHubAccessToken"
 	AuthService_GenerateRepoToken_FullMethodName    = "/auth.AuthService/GenerateRepoToken"
 	AuthService_ValidateToken_FullMethodName        = "/auth.AuthService/ValidateToken"
+	AuthService_IssueWorkspaceToken_FullMethodName  = "/auth.AuthService/IssueWorkspaceToken"
 )
 
 // AuthServiceClient is the client API for AuthService service.
@@ -43,6 +44,7 @@ type AuthServiceClient interface {
 	GetGitHubAccessToken(ctx context.Context, in *GetGitHubAccessTokenRequest, opts ...grpc.CallOption) (*GetGitHubAccessTokenResponse, error)
 	GenerateRepoToken(ctx context.Context, in *GenerateRepoTokenRequest, opts ...grpc.CallOption) (*GenerateRepoTokenResponse, error)
 	ValidateToken(ctx context.Context, in *ValidateTokenRequest, opts ...grpc.CallOption) (*ValidateTokenResponse, error)
+	IssueWorkspaceToken(ctx context.Context, in *IssueWorkspaceTokenRequest, opts ...grpc.CallOption) (*IssueWorkspaceTokenResponse, error)
 }
 
 type authServiceClient struct {
@@ -143,6 +145,16 @@ func (c *authServiceClient) ValidateToken(ctx context.Context, in *ValidateToken
 	return out, nil
 }
 
+func (c *authServiceClient) IssueWorkspaceToken(ctx context.Context, in *IssueWorkspaceTokenRequest, opts ...grpc.CallOption) (*IssueWorkspaceTokenResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IssueWorkspaceTokenResponse)
+	err := c.cc.Invoke(ctx, AuthService_IssueWorkspaceToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AuthServiceServer is the server API for AuthService service.
 // All implementations must embed UnimplementedAuthServiceServer
 // for forward compatibility.
@@ -156,6 +168,7 @@ type AuthServiceServer interface {
 	GetGitHubAccessToken(context.Context, *GetGitHubAccessTokenRequest) (*GetGitHubAccessTokenResponse, error)
 	GenerateRepoToken(context.Context, *GenerateRepoTokenRequest) (*GenerateRepoTokenResponse, error)
 	ValidateToken(context.Context, *ValidateTokenRequest) (*ValidateTokenResponse, error)
+	IssueWorkspaceToken(context.Context, *IssueWorkspaceTokenRequest) (*IssueWorkspaceTokenResponse, error)
 	mustEmbedUnimplementedAuthServiceServer()
 }
 
@@ -193,6 +206,9 @@ func (UnimplementedAuthServiceServer) GenerateRepoToken(context.Context, *Genera
 func (UnimplementedAuthServiceServer) ValidateToken(context.Context, *ValidateTokenRequest) (*ValidateTokenResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ValidateToken not implemented")
 }
+func (UnimplementedAuthServiceServer) IssueWorkspaceToken(context.Context, *IssueWorkspaceTokenRequest) (*IssueWorkspaceTokenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IssueWorkspaceToken not implemented")
+}
 func (UnimplementedAuthServiceServer) mustEmbedUnimplementedAuthServiceServer() {}
 func (UnimplementedAuthServiceServer) testEmbeddedByValue()                     {}
 
@@ -376,6 +392,24 @@ func _AuthService_ValidateToken_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AuthService_IssueWorkspaceToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IssueWorkspaceTokenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).IssueWorkspaceToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_IssueWorkspaceToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).IssueWorkspaceToken(ctx, req.(*IssueWorkspaceTokenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AuthService_ServiceDesc is the grpc.ServiceDesc for AuthService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -419,6 +453,10 @@ var AuthService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ValidateToken",
 			Handler:    _AuthService_ValidateToken_Handler,
 		},
+		{
+			MethodName: "IssueWorkspaceToken",
+			Handler:    _AuthService_IssueWorkspaceToken_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/auth_service.proto",
@@ -29,6 +29,7 @@ const (
 	ProjectStatus_STARTING                   ProjectStatus = 2
 	ProjectStatus_RUNNING                    ProjectStatus = 3
 	ProjectStatus_ERROR                      ProjectStatus = 4
+	ProjectStatus_SETUP_FAILED               ProjectStatus = 5 // repository cloned but the project's setup commands failed
 )
 
 // Enum value maps for ProjectStatus.
@@ -39,6 +40,7 @@ var (
 		2: "STARTING",
 		3: "RUNNING",
 		4: "ERROR",
+		5: "SETUP_FAILED",
 	}
 	ProjectStatus_value = map[string]int32{
 		"PROJECT_STATUS_UNSPECIFIED": 0,
@@ -46,6 +48,7 @@ var (
 		"STARTING":                   2,
 		"RUNNING":                    3,
 		"ERROR":                      4,
+		"SETUP_FAILED":               5,
 	}
 )
 
@@ -76,18 +79,91 @@ func (ProjectStatus) EnumDescriptor() ([]byte, []int) {
 	return file_proto_project_proto_rawDescGZIP(), []int{0}
 }
 
+// CloneOptions controls how the agent gets the repository into the
+// workspace.
+type CloneOptions struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Depth            int32                  `protobuf:"varint,1,opt,name=depth,proto3" json:"depth,omitempty"`                                               // history depth; 0 clones everything
+	Filter           string                 `protobuf:"bytes,2,opt,name=filter,proto3" json:"filter,omitempty"`                                              // partial clone filter: "blob:none", "tree:0" or "blob:limit=<n>"
+	SparsePaths      []string               `protobuf:"bytes,3,rep,name=sparse_paths,json=sparsePaths,proto3" json:"sparse_paths,omitempty"`                 // only check out these directories
+	PersistWorkspace bool                   `protobuf:"varint,4,opt,name=persist_workspace,json=persistWorkspace,proto3" json:"persist_workspace,omitempty"` // keep /workspace on a volume and fetch instead of re-cloning
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *CloneOptions) Reset() {
+	*x = CloneOptions{}
+	mi := &file_proto_project_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloneOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloneOptions) ProtoMessage() {}
+
+func (x *CloneOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloneOptions.ProtoReflect.Descriptor instead.
+func (*CloneOptions) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CloneOptions) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *CloneOptions) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+func (x *CloneOptions) GetSparsePaths() []string {
+	if x != nil {
+		return x.SparsePaths
+	}
+	return nil
+}
+
+func (x *CloneOptions) GetPersistWorkspace() bool {
+	if x != nil {
+		return x.PersistWorkspace
+	}
+	return false
+}
+
 type CreateProjectRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	RepoUrl       string                 `protobuf:"bytes,3,opt,name=repo_url,json=repoUrl,proto3" json:"repo_url,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	UserId         string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Name           string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	RepoUrl        string                 `protobuf:"bytes,3,opt,name=repo_url,json=repoUrl,proto3" json:"repo_url,omitempty"`
+	AutosavePolicy string                 `protobuf:"bytes,4,opt,name=autosave_policy,json=autosavePolicy,proto3" json:"autosave_policy,omitempty"` // off | shadow | snapshot | wip; empty means shadow
+	DefaultBranch  string                 `protobuf:"bytes,5,opt,name=default_branch,json=defaultBranch,proto3" json:"default_branch,omitempty"`    // empty means the repository's default branch
+	Clone          *CloneOptions          `protobuf:"bytes,6,opt,name=clone,proto3" json:"clone,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *CreateProjectRequest) Reset() {
 	*x = CreateProjectRequest{}
-	mi := &file_proto_project_proto_msgTypes[0]
+	mi := &file_proto_project_proto_msgTypes[1]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -99,7 +175,7 @@ func (x *CreateProjectRequest) String() string {
 func (*CreateProjectRequest) ProtoMessage() {}
 
 func (x *CreateProjectRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[0]
+	mi := &file_proto_project_proto_msgTypes[1]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -112,7 +188,7 @@ func (x *CreateProjectRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateProjectRequest.ProtoReflect.Descriptor instead.
 func (*CreateProjectRequest) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{0}
+	return file_proto_project_proto_rawDescGZIP(), []int{1}
 }
 
 func (x *CreateProjectRequest) GetUserId() string {
@@ -136,6 +212,27 @@ func (x *CreateProjectRequest) GetRepoUrl() string {
 	return ""
 }
 
+func (x *CreateProjectRequest) GetAutosavePolicy() string {
+	if x != nil {
+		return x.AutosavePolicy
+	}
+	return ""
+}
+
+func (x *CreateProjectRequest) GetDefaultBranch() string {
+	if x != nil {
+		return x.DefaultBranch
+	}
+	return ""
+}
+
+func (x *CreateProjectRequest) GetClone() *CloneOptions {
+	if x != nil {
+		return x.Clone
+	}
+	return nil
+}
+
 type CreateProjectResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ProjectId     string                 `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
@@ -145,7 +242,7 @@ type CreateProjectResponse struct {
 
 func (x *CreateProjectResponse) Reset() {
 	*x = CreateProjectResponse{}
-	mi := &file_proto_project_proto_msgTypes[1]
+	mi := &file_proto_project_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -157,7 +254,7 @@ func (x *CreateProjectResponse) String() string {
 func (*CreateProjectResponse) ProtoMessage() {}
 
 func (x *CreateProjectResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[1]
+	mi := &file_proto_project_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -170,7 +267,7 @@ func (x *CreateProjectResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateProjectResponse.ProtoReflect.Descriptor instead.
 func (*CreateProjectResponse) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{1}
+	return file_proto_project_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *CreateProjectResponse) GetProjectId() string {
@@ -184,13 +281,15 @@ type StartWorkspaceRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ProjectId     string                 `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"` // UUID
 	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Ref           string                 `protobuf:"bytes,3,opt,name=ref,proto3" json:"ref,omitempty"`                                     // branch, tag or commit to check out; defaults to the project's default branch
+	PullRequest   int32                  `protobuf:"varint,4,opt,name=pull_request,json=pullRequest,proto3" json:"pull_request,omitempty"` // check out this pull request instead of ref
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *StartWorkspaceRequest) Reset() {
 	*x = StartWorkspaceRequest{}
-	mi := &file_proto_project_proto_msgTypes[2]
+	mi := &file_proto_project_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -202,7 +301,7 @@ func (x *StartWorkspaceRequest) String() string {
 func (*StartWorkspaceRequest) ProtoMessage() {}
 
 func (x *StartWorkspaceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[2]
+	mi := &file_proto_project_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -215,7 +314,7 @@ func (x *StartWorkspaceRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StartWorkspaceRequest.ProtoReflect.Descriptor instead.
 func (*StartWorkspaceRequest) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{2}
+	return file_proto_project_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *StartWorkspaceRequest) GetProjectId() string {
@@ -232,6 +331,20 @@ func (x *StartWorkspaceRequest) GetUserId() string {
 	return ""
 }
 
+func (x *StartWorkspaceRequest) GetRef() string {
+	if x != nil {
+		return x.Ref
+	}
+	return ""
+}
+
+func (x *StartWorkspaceRequest) GetPullRequest() int32 {
+	if x != nil {
+		return x.PullRequest
+	}
+	return 0
+}
+
 type StartWorkspaceResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
@@ -243,7 +356,7 @@ type StartWorkspaceResponse struct {
 
 func (x *StartWorkspaceResponse) Reset() {
 	*x = StartWorkspaceResponse{}
-	mi := &file_proto_project_proto_msgTypes[3]
+	mi := &file_proto_project_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -255,7 +368,7 @@ func (x *StartWorkspaceResponse) String() string {
 func (*StartWorkspaceResponse) ProtoMessage() {}
 
 func (x *StartWorkspaceResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[3]
+	mi := &file_proto_project_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -268,7 +381,7 @@ func (x *StartWorkspaceResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StartWorkspaceResponse.ProtoReflect.Descriptor instead.
 func (*StartWorkspaceResponse) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{3}
+	return file_proto_project_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *StartWorkspaceResponse) GetOk() bool {
@@ -302,7 +415,7 @@ type StopWorkspaceRequest struct {
 
 func (x *StopWorkspaceRequest) Reset() {
 	*x = StopWorkspaceRequest{}
-	mi := &file_proto_project_proto_msgTypes[4]
+	mi := &file_proto_project_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -314,7 +427,7 @@ func (x *StopWorkspaceRequest) String() string {
 func (*StopWorkspaceRequest) ProtoMessage() {}
 
 func (x *StopWorkspaceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[4]
+	mi := &file_proto_project_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -327,7 +440,7 @@ func (x *StopWorkspaceRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StopWorkspaceRequest.ProtoReflect.Descriptor instead.
 func (*StopWorkspaceRequest) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{4}
+	return file_proto_project_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *StopWorkspaceRequest) GetProjectId() string {
@@ -353,7 +466,7 @@ type StopWorkspaceResponse struct {
 
 func (x *StopWorkspaceResponse) Reset() {
 	*x = StopWorkspaceResponse{}
-	mi := &file_proto_project_proto_msgTypes[5]
+	mi := &file_proto_project_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -365,7 +478,7 @@ func (x *StopWorkspaceResponse) String() string {
 func (*StopWorkspaceResponse) ProtoMessage() {}
 
 func (x *StopWorkspaceResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[5]
+	mi := &file_proto_project_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -378,7 +491,7 @@ func (x *StopWorkspaceResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StopWorkspaceResponse.ProtoReflect.Descriptor instead.
 func (*StopWorkspaceResponse) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{5}
+	return file_proto_project_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *StopWorkspaceResponse) GetOk() bool {
@@ -399,7 +512,7 @@ type WebhookUpdateRequest struct {
 
 func (x *WebhookUpdateRequest) Reset() {
 	*x = WebhookUpdateRequest{}
-	mi := &file_proto_project_proto_msgTypes[6]
+	mi := &file_proto_project_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -411,7 +524,7 @@ func (x *WebhookUpdateRequest) String() string {
 func (*WebhookUpdateRequest) ProtoMessage() {}
 
 func (x *WebhookUpdateRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[6]
+	mi := &file_proto_project_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -424,7 +537,7 @@ func (x *WebhookUpdateRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WebhookUpdateRequest.ProtoReflect.Descriptor instead.
 func (*WebhookUpdateRequest) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{6}
+	return file_proto_project_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *WebhookUpdateRequest) GetAtlasId() string {
@@ -457,7 +570,7 @@ type WebhookUpdateResponse struct {
 
 func (x *WebhookUpdateResponse) Reset() {
 	*x = WebhookUpdateResponse{}
-	mi := &file_proto_project_proto_msgTypes[7]
+	mi := &file_proto_project_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -469,7 +582,7 @@ func (x *WebhookUpdateResponse) String() string {
 func (*WebhookUpdateResponse) ProtoMessage() {}
 
 func (x *WebhookUpdateResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[7]
+	mi := &file_proto_project_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -482,7 +595,7 @@ func (x *WebhookUpdateResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WebhookUpdateResponse.ProtoReflect.Descriptor instead.
 func (*WebhookUpdateResponse) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{7}
+	return file_proto_project_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *WebhookUpdateResponse) GetOk() bool {
@@ -503,7 +616,7 @@ type VerifyAndCompleteRequest struct {
 
 func (x *VerifyAndCompleteRequest) Reset() {
 	*x = VerifyAndCompleteRequest{}
-	mi := &file_proto_project_proto_msgTypes[8]
+	mi := &file_proto_project_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -515,7 +628,7 @@ func (x *VerifyAndCompleteRequest) String() string {
 func (*VerifyAndCompleteRequest) ProtoMessage() {}
 
 func (x *VerifyAndCompleteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[8]
+	mi := &file_proto_project_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -528,7 +641,7 @@ func (x *VerifyAndCompleteRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use VerifyAndCompleteRequest.ProtoReflect.Descriptor instead.
 func (*VerifyAndCompleteRequest) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{8}
+	return file_proto_project_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *VerifyAndCompleteRequest) GetAtlasId() string {
@@ -562,7 +675,7 @@ type VerifyAndCompleteResponse struct {
 
 func (x *VerifyAndCompleteResponse) Reset() {
 	*x = VerifyAndCompleteResponse{}
-	mi := &file_proto_project_proto_msgTypes[9]
+	mi := &file_proto_project_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -574,7 +687,7 @@ func (x *VerifyAndCompleteResponse) String() string {
 func (*VerifyAndCompleteResponse) ProtoMessage() {}
 
 func (x *VerifyAndCompleteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[9]
+	mi := &file_proto_project_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -587,7 +700,7 @@ func (x *VerifyAndCompleteResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use VerifyAndCompleteResponse.ProtoReflect.Descriptor instead.
 func (*VerifyAndCompleteResponse) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{9}
+	return file_proto_project_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *VerifyAndCompleteResponse) GetOk() bool {
@@ -614,7 +727,7 @@ type IsOwnerRequest struct {
 
 func (x *IsOwnerRequest) Reset() {
 	*x = IsOwnerRequest{}
-	mi := &file_proto_project_proto_msgTypes[10]
+	mi := &file_proto_project_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -626,7 +739,7 @@ func (x *IsOwnerRequest) String() string {
 func (*IsOwnerRequest) ProtoMessage() {}
 
 func (x *IsOwnerRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[10]
+	mi := &file_proto_project_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -639,7 +752,7 @@ func (x *IsOwnerRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IsOwnerRequest.ProtoReflect.Descriptor instead.
 func (*IsOwnerRequest) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{10}
+	return file_proto_project_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *IsOwnerRequest) GetUserId() string {
@@ -665,7 +778,7 @@ type IsOwnerResponse struct {
 
 func (x *IsOwnerResponse) Reset() {
 	*x = IsOwnerResponse{}
-	mi := &file_proto_project_proto_msgTypes[11]
+	mi := &file_proto_project_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -677,7 +790,7 @@ func (x *IsOwnerResponse) String() string {
 func (*IsOwnerResponse) ProtoMessage() {}
 
 func (x *IsOwnerResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_project_proto_msgTypes[11]
+	mi := &file_proto_project_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -690,7 +803,7 @@ func (x *IsOwnerResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IsOwnerResponse.ProtoReflect.Descriptor instead.
 func (*IsOwnerResponse) Descriptor() ([]byte, []int) {
-	return file_proto_project_proto_rawDescGZIP(), []int{11}
+	return file_proto_project_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *IsOwnerResponse) GetIsOwner() bool {
@@ -700,64 +813,1625 @@ func (x *IsOwnerResponse) GetIsOwner() bool {
 	return false
 }
 
-var File_proto_project_proto protoreflect.FileDescriptor
+type SetAutosavePolicyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProjectId     string                 `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Policy        string                 `protobuf:"bytes,3,opt,name=policy,proto3" json:"policy,omitempty"` // off | shadow | snapshot | wip
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_proto_project_proto_rawDesc = "" +
-	"\n" +
-	"\x13proto/project.proto\x12\aproject\"^\n" +
-	"\x14CreateProjectRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x12\n" +
-	"\x04name\x18\x02 \x01(\tR\x04name\x12\x19\n" +
-	"\brepo_url\x18\x03 \x01(\tR\arepoUrl\"6\n" +
-	"\x15CreateProjectResponse\x12\x1d\n" +
-	"\n" +
-	"project_id\x18\x01 \x01(\tR\tprojectId\"O\n" +
-	"\x15StartWorkspaceRequest\x12\x1d\n" +
-	"\n" +
-	"project_id\x18\x01 \x01(\tR\tprojectId\x12\x17\n" +
-	"\auser_id\x18\x02 \x01(\tR\x06userId\"s\n" +
-	"\x16StartWorkspaceResponse\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok\x12.\n" +
-	"\x06status\x18\x02 \x01(\x0e2\x16.project.ProjectStatusR\x06status\x12\x19\n" +
-	"\batlas_id\x18\x03 \x01(\tR\aatlasId\"P\n" +
-	"\x14StopWorkspaceRequest\x12\x1d\n" +
-	"\n" +
-	"project_id\x18\x01 \x01(\tR\tprojectId\x12\x19\n" +
-	"\batlas_id\x18\x02 \x01(\tR\aatlasId\"'\n" +
-	"\x15StopWorkspaceResponse\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok\"p\n" +
-	"\x14WebhookUpdateRequest\x12\x19\n" +
-	"\batlas_id\x18\x01 \x01(\tR\aatlasId\x12\x16\n" +
-	"\x06status\x18\x02 \x01(\tR\x06status\x12%\n" +
-	"\x0ecallback_token\x18\x03 \x01(\tR\rcallbackToken\"'\n" +
-	"\x15WebhookUpdateResponse\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok\"t\n" +
-	"\x18VerifyAndCompleteRequest\x12\x19\n" +
-	"\batlas_id\x18\x01 \x01(\tR\aatlasId\x12%\n" +
-	"\x0ecallback_token\x18\x02 \x01(\tR\rcallbackToken\x12\x16\n" +
-	"\x06status\x18\x03 \x01(\tR\x06status\"[\n" +
-	"\x19VerifyAndCompleteResponse\x12\x0e\n" +
-	"\x02ok\x18\x01 \x01(\bR\x02ok\x12.\n" +
-	"\x06status\x18\x02 \x01(\x0e2\x16.project.ProjectStatusR\x06status\"H\n" +
-	"\x0eIsOwnerRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1d\n" +
-	"\n" +
-	"project_id\x18\x02 \x01(\tR\tprojectId\",\n" +
-	"\x0fIsOwnerResponse\x12\x19\n" +
-	"\bis_owner\x18\x01 \x01(\bR\aisOwner*b\n" +
-	"\rProjectStatus\x12\x1e\n" +
-	"\x1aPROJECT_STATUS_UNSPECIFIED\x10\x00\x12\v\n" +
-	"\aSTOPPED\x10\x01\x12\f\n" +
-	"\bSTARTING\x10\x02\x12\v\n" +
-	"\aRUNNING\x10\x03\x12\t\n" +
-	"\x05ERROR\x10\x042\xed\x03\n" +
-	"\x0eProjectService\x12N\n" +
-	"\rCreateProject\x12\x1d.project.CreateProjectRequest\x1a\x1e.project.CreateProjectResponse\x12Q\n" +
-	"\x0eStartWorkspace\x12\x1e.project.StartWorkspaceRequest\x1a\x1f.project.StartWorkspaceResponse\x12N\n" +
-	"\rStopWorkspace\x12\x1d.project.StopWorkspaceRequest\x1a\x1e.project.StopWorkspaceResponse\x12N\n" +
-	"\rWebhookUpdate\x12\x1d.project.WebhookUpdateRequest\x1a\x1e.project.WebhookUpdateResponse\x12Z\n" +
-	"\x11VerifyAndComplete\x12!.project.VerifyAndCompleteRequest\x1a\".project.VerifyAndCompleteResponse\x12<\n" +
-	"\aIsOwner\x12\x17.project.IsOwnerRequest\x1a\x18.project.IsOwnerResponseB-Z+github.com/Aadithya-J/code_nest/proto;protob\x06proto3"
+func (x *SetAutosavePolicyRequest) Reset() {
+	*x = SetAutosavePolicyRequest{}
+	mi := &file_proto_project_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetAutosavePolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetAutosavePolicyRequest) ProtoMessage() {}
+
+func (x *SetAutosavePolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetAutosavePolicyRequest.ProtoReflect.Descriptor instead.
+func (*SetAutosavePolicyRequest) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SetAutosavePolicyRequest) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *SetAutosavePolicyRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetAutosavePolicyRequest) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+type SetAutosavePolicyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	Policy        string                 `protobuf:"bytes,2,opt,name=policy,proto3" json:"policy,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetAutosavePolicyResponse) Reset() {
+	*x = SetAutosavePolicyResponse{}
+	mi := &file_proto_project_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetAutosavePolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetAutosavePolicyResponse) ProtoMessage() {}
+
+func (x *SetAutosavePolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetAutosavePolicyResponse.ProtoReflect.Descriptor instead.
+func (*SetAutosavePolicyResponse) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SetAutosavePolicyResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *SetAutosavePolicyResponse) GetPolicy() string {
+	if x != nil {
+		return x.Policy
+	}
+	return ""
+}
+
+// SyncResult records where the agent pushed a workspace's commits when it
+// shut down.
+type SyncResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`                        // PUSHED | RECOVERY | FAILED | SKIPPED
+	Branch        string                 `protobuf:"bytes,2,opt,name=branch,proto3" json:"branch,omitempty"`                        // local branch, empty when HEAD was detached
+	RemoteRef     string                 `protobuf:"bytes,3,opt,name=remote_ref,json=remoteRef,proto3" json:"remote_ref,omitempty"` // e.g. "origin/main" or "origin/codenest/recovery-ws-{uuid}"
+	Commit        string                 `protobuf:"bytes,4,opt,name=commit,proto3" json:"commit,omitempty"`
+	Message       string                 `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	ReportedAt    int64                  `protobuf:"varint,6,opt,name=reported_at,json=reportedAt,proto3" json:"reported_at,omitempty"` // unix seconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SyncResult) Reset() {
+	*x = SyncResult{}
+	mi := &file_proto_project_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SyncResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SyncResult) ProtoMessage() {}
+
+func (x *SyncResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SyncResult.ProtoReflect.Descriptor instead.
+func (*SyncResult) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SyncResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *SyncResult) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *SyncResult) GetRemoteRef() string {
+	if x != nil {
+		return x.RemoteRef
+	}
+	return ""
+}
+
+func (x *SyncResult) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *SyncResult) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SyncResult) GetReportedAt() int64 {
+	if x != nil {
+		return x.ReportedAt
+	}
+	return 0
+}
+
+type ReportSyncRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AtlasId       string                 `protobuf:"bytes,1,opt,name=atlas_id,json=atlasId,proto3" json:"atlas_id,omitempty"`
+	CallbackToken string                 `protobuf:"bytes,2,opt,name=callback_token,json=callbackToken,proto3" json:"callback_token,omitempty"`
+	Result        *SyncResult            `protobuf:"bytes,3,opt,name=result,proto3" json:"result,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportSyncRequest) Reset() {
+	*x = ReportSyncRequest{}
+	mi := &file_proto_project_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportSyncRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportSyncRequest) ProtoMessage() {}
+
+func (x *ReportSyncRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportSyncRequest.ProtoReflect.Descriptor instead.
+func (*ReportSyncRequest) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ReportSyncRequest) GetAtlasId() string {
+	if x != nil {
+		return x.AtlasId
+	}
+	return ""
+}
+
+func (x *ReportSyncRequest) GetCallbackToken() string {
+	if x != nil {
+		return x.CallbackToken
+	}
+	return ""
+}
+
+func (x *ReportSyncRequest) GetResult() *SyncResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type ReportSyncResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportSyncResponse) Reset() {
+	*x = ReportSyncResponse{}
+	mi := &file_proto_project_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportSyncResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportSyncResponse) ProtoMessage() {}
+
+func (x *ReportSyncResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportSyncResponse.ProtoReflect.Descriptor instead.
+func (*ReportSyncResponse) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ReportSyncResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+type GetLastSyncRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProjectId     string                 `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLastSyncRequest) Reset() {
+	*x = GetLastSyncRequest{}
+	mi := &file_proto_project_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLastSyncRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLastSyncRequest) ProtoMessage() {}
+
+func (x *GetLastSyncRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLastSyncRequest.ProtoReflect.Descriptor instead.
+func (*GetLastSyncRequest) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GetLastSyncRequest) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *GetLastSyncRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type GetLastSyncResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        *SyncResult            `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"` // unset if the workspace never reported
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLastSyncResponse) Reset() {
+	*x = GetLastSyncResponse{}
+	mi := &file_proto_project_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLastSyncResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLastSyncResponse) ProtoMessage() {}
+
+func (x *GetLastSyncResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLastSyncResponse.ProtoReflect.Descriptor instead.
+func (*GetLastSyncResponse) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GetLastSyncResponse) GetResult() *SyncResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+type SetCloneOptionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProjectId     string                 `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Clone         *CloneOptions          `protobuf:"bytes,3,opt,name=clone,proto3" json:"clone,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetCloneOptionsRequest) Reset() {
+	*x = SetCloneOptionsRequest{}
+	mi := &file_proto_project_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetCloneOptionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetCloneOptionsRequest) ProtoMessage() {}
+
+func (x *SetCloneOptionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetCloneOptionsRequest.ProtoReflect.Descriptor instead.
+func (*SetCloneOptionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SetCloneOptionsRequest) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *SetCloneOptionsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetCloneOptionsRequest) GetClone() *CloneOptions {
+	if x != nil {
+		return x.Clone
+	}
+	return nil
+}
+
+type SetCloneOptionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetCloneOptionsResponse) Reset() {
+	*x = SetCloneOptionsResponse{}
+	mi := &file_proto_project_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetCloneOptionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetCloneOptionsResponse) ProtoMessage() {}
+
+func (x *SetCloneOptionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetCloneOptionsResponse.ProtoReflect.Descriptor instead.
+func (*SetCloneOptionsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *SetCloneOptionsResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+// CloneProgress is the agent's view of an in-flight clone.
+type CloneProgress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Phase         string                 `protobuf:"bytes,1,opt,name=phase,proto3" json:"phase,omitempty"`                           // starting | counting | compressing | receiving | resolving | checkout | done | error
+	Percent       int32                  `protobuf:"varint,2,opt,name=percent,proto3" json:"percent,omitempty"`                      // within the phase
+	Overall       int32                  `protobuf:"varint,3,opt,name=overall,proto3" json:"overall,omitempty"`                      // 0-100 estimate across the whole clone
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`                       // error detail
+	UpdatedAt     int64                  `protobuf:"varint,5,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"` // unix seconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloneProgress) Reset() {
+	*x = CloneProgress{}
+	mi := &file_proto_project_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloneProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloneProgress) ProtoMessage() {}
+
+func (x *CloneProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloneProgress.ProtoReflect.Descriptor instead.
+func (*CloneProgress) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *CloneProgress) GetPhase() string {
+	if x != nil {
+		return x.Phase
+	}
+	return ""
+}
+
+func (x *CloneProgress) GetPercent() int32 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *CloneProgress) GetOverall() int32 {
+	if x != nil {
+		return x.Overall
+	}
+	return 0
+}
+
+func (x *CloneProgress) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CloneProgress) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+type ReportProgressRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AtlasId       string                 `protobuf:"bytes,1,opt,name=atlas_id,json=atlasId,proto3" json:"atlas_id,omitempty"`
+	CallbackToken string                 `protobuf:"bytes,2,opt,name=callback_token,json=callbackToken,proto3" json:"callback_token,omitempty"`
+	Progress      *CloneProgress         `protobuf:"bytes,3,opt,name=progress,proto3" json:"progress,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportProgressRequest) Reset() {
+	*x = ReportProgressRequest{}
+	mi := &file_proto_project_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportProgressRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportProgressRequest) ProtoMessage() {}
+
+func (x *ReportProgressRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportProgressRequest.ProtoReflect.Descriptor instead.
+func (*ReportProgressRequest) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ReportProgressRequest) GetAtlasId() string {
+	if x != nil {
+		return x.AtlasId
+	}
+	return ""
+}
+
+func (x *ReportProgressRequest) GetCallbackToken() string {
+	if x != nil {
+		return x.CallbackToken
+	}
+	return ""
+}
+
+func (x *ReportProgressRequest) GetProgress() *CloneProgress {
+	if x != nil {
+		return x.Progress
+	}
+	return nil
+}
+
+type ReportProgressResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportProgressResponse) Reset() {
+	*x = ReportProgressResponse{}
+	mi := &file_proto_project_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportProgressResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportProgressResponse) ProtoMessage() {}
+
+func (x *ReportProgressResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportProgressResponse.ProtoReflect.Descriptor instead.
+func (*ReportProgressResponse) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ReportProgressResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+type GetCloneProgressRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProjectId     string                 `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCloneProgressRequest) Reset() {
+	*x = GetCloneProgressRequest{}
+	mi := &file_proto_project_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCloneProgressRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCloneProgressRequest) ProtoMessage() {}
+
+func (x *GetCloneProgressRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCloneProgressRequest.ProtoReflect.Descriptor instead.
+func (*GetCloneProgressRequest) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetCloneProgressRequest) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *GetCloneProgressRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type GetCloneProgressResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Progress      *CloneProgress         `protobuf:"bytes,1,opt,name=progress,proto3" json:"progress,omitempty"` // unset if nothing was reported since the last start
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCloneProgressResponse) Reset() {
+	*x = GetCloneProgressResponse{}
+	mi := &file_proto_project_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCloneProgressResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCloneProgressResponse) ProtoMessage() {}
+
+func (x *GetCloneProgressResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCloneProgressResponse.ProtoReflect.Descriptor instead.
+func (*GetCloneProgressResponse) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *GetCloneProgressResponse) GetProgress() *CloneProgress {
+	if x != nil {
+		return x.Progress
+	}
+	return nil
+}
+
+// PortInfo is a port a workspace serves on.
+type PortInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Port          int32                  `protobuf:"varint,1,opt,name=port,proto3" json:"port,omitempty"`
+	Label         string                 `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	Protocol      string                 `protobuf:"bytes,3,opt,name=protocol,proto3" json:"protocol,omitempty"` // http | https
+	Public        bool                   `protobuf:"varint,4,opt,name=public,proto3" json:"public,omitempty"`    // reachable without signing in
+	Open          bool                   `protobuf:"varint,5,opt,name=open,proto3" json:"open,omitempty"`        // something is listening on it right now
+	Process       string                 `protobuf:"bytes,6,opt,name=process,proto3" json:"process,omitempty"`
+	UpdatedAt     int64                  `protobuf:"varint,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"` // unix seconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PortInfo) Reset() {
+	*x = PortInfo{}
+	mi := &file_proto_project_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortInfo) ProtoMessage() {}
+
+func (x *PortInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortInfo.ProtoReflect.Descriptor instead.
+func (*PortInfo) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *PortInfo) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *PortInfo) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *PortInfo) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *PortInfo) GetPublic() bool {
+	if x != nil {
+		return x.Public
+	}
+	return false
+}
+
+func (x *PortInfo) GetOpen() bool {
+	if x != nil {
+		return x.Open
+	}
+	return false
+}
+
+func (x *PortInfo) GetProcess() string {
+	if x != nil {
+		return x.Process
+	}
+	return ""
+}
+
+func (x *PortInfo) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+type ReportPortRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AtlasId       string                 `protobuf:"bytes,1,opt,name=atlas_id,json=atlasId,proto3" json:"atlas_id,omitempty"`
+	CallbackToken string                 `protobuf:"bytes,2,opt,name=callback_token,json=callbackToken,proto3" json:"callback_token,omitempty"`
+	Port          int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	Open          bool                   `protobuf:"varint,4,opt,name=open,proto3" json:"open,omitempty"` // false when the port closed
+	Process       string                 `protobuf:"bytes,5,opt,name=process,proto3" json:"process,omitempty"`
+	Label         string                 `protobuf:"bytes,6,opt,name=label,proto3" json:"label,omitempty"` // from the workspace config, used until the owner sets one
+	Protocol      string                 `protobuf:"bytes,7,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportPortRequest) Reset() {
+	*x = ReportPortRequest{}
+	mi := &file_proto_project_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportPortRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportPortRequest) ProtoMessage() {}
+
+func (x *ReportPortRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportPortRequest.ProtoReflect.Descriptor instead.
+func (*ReportPortRequest) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ReportPortRequest) GetAtlasId() string {
+	if x != nil {
+		return x.AtlasId
+	}
+	return ""
+}
+
+func (x *ReportPortRequest) GetCallbackToken() string {
+	if x != nil {
+		return x.CallbackToken
+	}
+	return ""
+}
+
+func (x *ReportPortRequest) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *ReportPortRequest) GetOpen() bool {
+	if x != nil {
+		return x.Open
+	}
+	return false
+}
+
+func (x *ReportPortRequest) GetProcess() string {
+	if x != nil {
+		return x.Process
+	}
+	return ""
+}
+
+func (x *ReportPortRequest) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *ReportPortRequest) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+type ReportPortResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ok            bool                   `protobuf:"varint,1,opt,name=ok,proto3" json:"ok,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportPortResponse) Reset() {
+	*x = ReportPortResponse{}
+	mi := &file_proto_project_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportPortResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportPortResponse) ProtoMessage() {}
+
+func (x *ReportPortResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportPortResponse.ProtoReflect.Descriptor instead.
+func (*ReportPortResponse) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ReportPortResponse) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+type ListPortsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProjectId     string                 `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPortsRequest) Reset() {
+	*x = ListPortsRequest{}
+	mi := &file_proto_project_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPortsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPortsRequest) ProtoMessage() {}
+
+func (x *ListPortsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPortsRequest.ProtoReflect.Descriptor instead.
+func (*ListPortsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ListPortsRequest) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *ListPortsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type ListPortsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ports         []*PortInfo            `protobuf:"bytes,1,rep,name=ports,proto3" json:"ports,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPortsResponse) Reset() {
+	*x = ListPortsResponse{}
+	mi := &file_proto_project_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPortsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPortsResponse) ProtoMessage() {}
+
+func (x *ListPortsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPortsResponse.ProtoReflect.Descriptor instead.
+func (*ListPortsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ListPortsResponse) GetPorts() []*PortInfo {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+type SetPortVisibilityRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProjectId     string                 `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Port          int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	Public        bool                   `protobuf:"varint,4,opt,name=public,proto3" json:"public,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPortVisibilityRequest) Reset() {
+	*x = SetPortVisibilityRequest{}
+	mi := &file_proto_project_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPortVisibilityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPortVisibilityRequest) ProtoMessage() {}
+
+func (x *SetPortVisibilityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPortVisibilityRequest.ProtoReflect.Descriptor instead.
+func (*SetPortVisibilityRequest) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *SetPortVisibilityRequest) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *SetPortVisibilityRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetPortVisibilityRequest) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *SetPortVisibilityRequest) GetPublic() bool {
+	if x != nil {
+		return x.Public
+	}
+	return false
+}
+
+type SetPortVisibilityResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Port          *PortInfo              `protobuf:"bytes,1,opt,name=port,proto3" json:"port,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetPortVisibilityResponse) Reset() {
+	*x = SetPortVisibilityResponse{}
+	mi := &file_proto_project_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetPortVisibilityResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetPortVisibilityResponse) ProtoMessage() {}
+
+func (x *SetPortVisibilityResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetPortVisibilityResponse.ProtoReflect.Descriptor instead.
+func (*SetPortVisibilityResponse) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *SetPortVisibilityResponse) GetPort() *PortInfo {
+	if x != nil {
+		return x.Port
+	}
+	return nil
+}
+
+type UpdatePortRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProjectId     string                 `protobuf:"bytes,1,opt,name=project_id,json=projectId,proto3" json:"project_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Port          int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	Label         string                 `protobuf:"bytes,4,opt,name=label,proto3" json:"label,omitempty"`
+	Protocol      string                 `protobuf:"bytes,5,opt,name=protocol,proto3" json:"protocol,omitempty"` // empty keeps the current protocol
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePortRequest) Reset() {
+	*x = UpdatePortRequest{}
+	mi := &file_proto_project_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePortRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePortRequest) ProtoMessage() {}
+
+func (x *UpdatePortRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePortRequest.ProtoReflect.Descriptor instead.
+func (*UpdatePortRequest) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *UpdatePortRequest) GetProjectId() string {
+	if x != nil {
+		return x.ProjectId
+	}
+	return ""
+}
+
+func (x *UpdatePortRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *UpdatePortRequest) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *UpdatePortRequest) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *UpdatePortRequest) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+type UpdatePortResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Port          *PortInfo              `protobuf:"bytes,1,opt,name=port,proto3" json:"port,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdatePortResponse) Reset() {
+	*x = UpdatePortResponse{}
+	mi := &file_proto_project_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdatePortResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdatePortResponse) ProtoMessage() {}
+
+func (x *UpdatePortResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdatePortResponse.ProtoReflect.Descriptor instead.
+func (*UpdatePortResponse) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *UpdatePortResponse) GetPort() *PortInfo {
+	if x != nil {
+		return x.Port
+	}
+	return nil
+}
+
+type IsPortPublicRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AtlasId       string                 `protobuf:"bytes,1,opt,name=atlas_id,json=atlasId,proto3" json:"atlas_id,omitempty"`
+	Port          int32                  `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IsPortPublicRequest) Reset() {
+	*x = IsPortPublicRequest{}
+	mi := &file_proto_project_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IsPortPublicRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IsPortPublicRequest) ProtoMessage() {}
+
+func (x *IsPortPublicRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IsPortPublicRequest.ProtoReflect.Descriptor instead.
+func (*IsPortPublicRequest) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *IsPortPublicRequest) GetAtlasId() string {
+	if x != nil {
+		return x.AtlasId
+	}
+	return ""
+}
+
+func (x *IsPortPublicRequest) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+type IsPortPublicResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Public        bool                   `protobuf:"varint,1,opt,name=public,proto3" json:"public,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IsPortPublicResponse) Reset() {
+	*x = IsPortPublicResponse{}
+	mi := &file_proto_project_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IsPortPublicResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IsPortPublicResponse) ProtoMessage() {}
+
+func (x *IsPortPublicResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_project_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IsPortPublicResponse.ProtoReflect.Descriptor instead.
+func (*IsPortPublicResponse) Descriptor() ([]byte, []int) {
+	return file_proto_project_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *IsPortPublicResponse) GetPublic() bool {
+	if x != nil {
+		return x.Public
+	}
+	return false
+}
+
+var File_proto_project_proto protoreflect.FileDescriptor
+
+const file_proto_project_proto_rawDesc = "" +
+	"\n" +
+	"\x13proto/project.proto\x12\aproject\"\x8c\x01\n" +
+	"\fCloneOptions\x12\x14\n" +
+	"\x05depth\x18\x01 \x01(\x05R\x05depth\x12\x16\n" +
+	"\x06filter\x18\x02 \x01(\tR\x06filter\x12!\n" +
+	"\fsparse_paths\x18\x03 \x03(\tR\vsparsePaths\x12+\n" +
+	"\x11persist_workspace\x18\x04 \x01(\bR\x10persistWorkspace\"\xdb\x01\n" +
+	"\x14CreateProjectRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x19\n" +
+	"\brepo_url\x18\x03 \x01(\tR\arepoUrl\x12'\n" +
+	"\x0fautosave_policy\x18\x04 \x01(\tR\x0eautosavePolicy\x12%\n" +
+	"\x0edefault_branch\x18\x05 \x01(\tR\rdefaultBranch\x12+\n" +
+	"\x05clone\x18\x06 \x01(\v2\x15.project.CloneOptionsR\x05clone\"6\n" +
+	"\x15CreateProjectResponse\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\tR\tprojectId\"\x84\x01\n" +
+	"\x15StartWorkspaceRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\tR\tprojectId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x10\n" +
+	"\x03ref\x18\x03 \x01(\tR\x03ref\x12!\n" +
+	"\fpull_request\x18\x04 \x01(\x05R\vpullRequest\"s\n" +
+	"\x16StartWorkspaceResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12.\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x16.project.ProjectStatusR\x06status\x12\x19\n" +
+	"\batlas_id\x18\x03 \x01(\tR\aatlasId\"P\n" +
+	"\x14StopWorkspaceRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\tR\tprojectId\x12\x19\n" +
+	"\batlas_id\x18\x02 \x01(\tR\aatlasId\"'\n" +
+	"\x15StopWorkspaceResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"p\n" +
+	"\x14WebhookUpdateRequest\x12\x19\n" +
+	"\batlas_id\x18\x01 \x01(\tR\aatlasId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12%\n" +
+	"\x0ecallback_token\x18\x03 \x01(\tR\rcallbackToken\"'\n" +
+	"\x15WebhookUpdateResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"t\n" +
+	"\x18VerifyAndCompleteRequest\x12\x19\n" +
+	"\batlas_id\x18\x01 \x01(\tR\aatlasId\x12%\n" +
+	"\x0ecallback_token\x18\x02 \x01(\tR\rcallbackToken\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\"[\n" +
+	"\x19VerifyAndCompleteResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12.\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x16.project.ProjectStatusR\x06status\"H\n" +
+	"\x0eIsOwnerRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x02 \x01(\tR\tprojectId\",\n" +
+	"\x0fIsOwnerResponse\x12\x19\n" +
+	"\bis_owner\x18\x01 \x01(\bR\aisOwner\"j\n" +
+	"\x18SetAutosavePolicyRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\tR\tprojectId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06policy\x18\x03 \x01(\tR\x06policy\"C\n" +
+	"\x19SetAutosavePolicyResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\x12\x16\n" +
+	"\x06policy\x18\x02 \x01(\tR\x06policy\"\xae\x01\n" +
+	"\n" +
+	"SyncResult\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x16\n" +
+	"\x06branch\x18\x02 \x01(\tR\x06branch\x12\x1d\n" +
+	"\n" +
+	"remote_ref\x18\x03 \x01(\tR\tremoteRef\x12\x16\n" +
+	"\x06commit\x18\x04 \x01(\tR\x06commit\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\x12\x1f\n" +
+	"\vreported_at\x18\x06 \x01(\x03R\n" +
+	"reportedAt\"\x82\x01\n" +
+	"\x11ReportSyncRequest\x12\x19\n" +
+	"\batlas_id\x18\x01 \x01(\tR\aatlasId\x12%\n" +
+	"\x0ecallback_token\x18\x02 \x01(\tR\rcallbackToken\x12+\n" +
+	"\x06result\x18\x03 \x01(\v2\x13.project.SyncResultR\x06result\"$\n" +
+	"\x12ReportSyncResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"L\n" +
+	"\x12GetLastSyncRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\tR\tprojectId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\"B\n" +
+	"\x13GetLastSyncResponse\x12+\n" +
+	"\x06result\x18\x01 \x01(\v2\x13.project.SyncResultR\x06result\"}\n" +
+	"\x16SetCloneOptionsRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\tR\tprojectId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12+\n" +
+	"\x05clone\x18\x03 \x01(\v2\x15.project.CloneOptionsR\x05clone\")\n" +
+	"\x17SetCloneOptionsResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"\x92\x01\n" +
+	"\rCloneProgress\x12\x14\n" +
+	"\x05phase\x18\x01 \x01(\tR\x05phase\x12\x18\n" +
+	"\apercent\x18\x02 \x01(\x05R\apercent\x12\x18\n" +
+	"\aoverall\x18\x03 \x01(\x05R\aoverall\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\x05 \x01(\x03R\tupdatedAt\"\x8d\x01\n" +
+	"\x15ReportProgressRequest\x12\x19\n" +
+	"\batlas_id\x18\x01 \x01(\tR\aatlasId\x12%\n" +
+	"\x0ecallback_token\x18\x02 \x01(\tR\rcallbackToken\x122\n" +
+	"\bprogress\x18\x03 \x01(\v2\x16.project.CloneProgressR\bprogress\"(\n" +
+	"\x16ReportProgressResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"Q\n" +
+	"\x17GetCloneProgressRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\tR\tprojectId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\"N\n" +
+	"\x18GetCloneProgressResponse\x122\n" +
+	"\bprogress\x18\x01 \x01(\v2\x16.project.CloneProgressR\bprogress\"\xb5\x01\n" +
+	"\bPortInfo\x12\x12\n" +
+	"\x04port\x18\x01 \x01(\x05R\x04port\x12\x14\n" +
+	"\x05label\x18\x02 \x01(\tR\x05label\x12\x1a\n" +
+	"\bprotocol\x18\x03 \x01(\tR\bprotocol\x12\x16\n" +
+	"\x06public\x18\x04 \x01(\bR\x06public\x12\x12\n" +
+	"\x04open\x18\x05 \x01(\bR\x04open\x12\x18\n" +
+	"\aprocess\x18\x06 \x01(\tR\aprocess\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\a \x01(\x03R\tupdatedAt\"\xc9\x01\n" +
+	"\x11ReportPortRequest\x12\x19\n" +
+	"\batlas_id\x18\x01 \x01(\tR\aatlasId\x12%\n" +
+	"\x0ecallback_token\x18\x02 \x01(\tR\rcallbackToken\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\x12\x12\n" +
+	"\x04open\x18\x04 \x01(\bR\x04open\x12\x18\n" +
+	"\aprocess\x18\x05 \x01(\tR\aprocess\x12\x14\n" +
+	"\x05label\x18\x06 \x01(\tR\x05label\x12\x1a\n" +
+	"\bprotocol\x18\a \x01(\tR\bprotocol\"$\n" +
+	"\x12ReportPortResponse\x12\x0e\n" +
+	"\x02ok\x18\x01 \x01(\bR\x02ok\"J\n" +
+	"\x10ListPortsRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\tR\tprojectId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\"<\n" +
+	"\x11ListPortsResponse\x12'\n" +
+	"\x05ports\x18\x01 \x03(\v2\x11.project.PortInfoR\x05ports\"~\n" +
+	"\x18SetPortVisibilityRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\tR\tprojectId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\x12\x16\n" +
+	"\x06public\x18\x04 \x01(\bR\x06public\"B\n" +
+	"\x19SetPortVisibilityResponse\x12%\n" +
+	"\x04port\x18\x01 \x01(\v2\x11.project.PortInfoR\x04port\"\x91\x01\n" +
+	"\x11UpdatePortRequest\x12\x1d\n" +
+	"\n" +
+	"project_id\x18\x01 \x01(\tR\tprojectId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\x12\x14\n" +
+	"\x05label\x18\x04 \x01(\tR\x05label\x12\x1a\n" +
+	"\bprotocol\x18\x05 \x01(\tR\bprotocol\";\n" +
+	"\x12UpdatePortResponse\x12%\n" +
+	"\x04port\x18\x01 \x01(\v2\x11.project.PortInfoR\x04port\"D\n" +
+	"\x13IsPortPublicRequest\x12\x19\n" +
+	"\batlas_id\x18\x01 \x01(\tR\aatlasId\x12\x12\n" +
+	"\x04port\x18\x02 \x01(\x05R\x04port\".\n" +
+	"\x14IsPortPublicResponse\x12\x16\n" +
+	"\x06public\x18\x01 \x01(\bR\x06public*t\n" +
+	"\rProjectStatus\x12\x1e\n" +
+	"\x1aPROJECT_STATUS_UNSPECIFIED\x10\x00\x12\v\n" +
+	"\aSTOPPED\x10\x01\x12\f\n" +
+	"\bSTARTING\x10\x02\x12\v\n" +
+	"\aRUNNING\x10\x03\x12\t\n" +
+	"\x05ERROR\x10\x04\x12\x10\n" +
+	"\fSETUP_FAILED\x10\x052\xd7\n" +
+	"\n" +
+	"\x0eProjectService\x12N\n" +
+	"\rCreateProject\x12\x1d.project.CreateProjectRequest\x1a\x1e.project.CreateProjectResponse\x12Q\n" +
+	"\x0eStartWorkspace\x12\x1e.project.StartWorkspaceRequest\x1a\x1f.project.StartWorkspaceResponse\x12N\n" +
+	"\rStopWorkspace\x12\x1d.project.StopWorkspaceRequest\x1a\x1e.project.StopWorkspaceResponse\x12N\n" +
+	"\rWebhookUpdate\x12\x1d.project.WebhookUpdateRequest\x1a\x1e.project.WebhookUpdateResponse\x12Z\n" +
+	"\x11VerifyAndComplete\x12!.project.VerifyAndCompleteRequest\x1a\".project.VerifyAndCompleteResponse\x12<\n" +
+	"\aIsOwner\x12\x17.project.IsOwnerRequest\x1a\x18.project.IsOwnerResponse\x12Z\n" +
+	"\x11SetAutosavePolicy\x12!.project.SetAutosavePolicyRequest\x1a\".project.SetAutosavePolicyResponse\x12E\n" +
+	"\n" +
+	"ReportSync\x12\x1a.project.ReportSyncRequest\x1a\x1b.project.ReportSyncResponse\x12H\n" +
+	"\vGetLastSync\x12\x1b.project.GetLastSyncRequest\x1a\x1c.project.GetLastSyncResponse\x12T\n" +
+	"\x0fSetCloneOptions\x12\x1f.project.SetCloneOptionsRequest\x1a .project.SetCloneOptionsResponse\x12Q\n" +
+	"\x0eReportProgress\x12\x1e.project.ReportProgressRequest\x1a\x1f.project.ReportProgressResponse\x12W\n" +
+	"\x10GetCloneProgress\x12 .project.GetCloneProgressRequest\x1a!.project.GetCloneProgressResponse\x12E\n" +
+	"\n" +
+	"ReportPort\x12\x1a.project.ReportPortRequest\x1a\x1b.project.ReportPortResponse\x12B\n" +
+	"\tListPorts\x12\x19.project.ListPortsRequest\x1a\x1a.project.ListPortsResponse\x12Z\n" +
+	"\x11SetPortVisibility\x12!.project.SetPortVisibilityRequest\x1a\".project.SetPortVisibilityResponse\x12E\n" +
+	"\n" +
+	"UpdatePort\x12\x1a.project.UpdatePortRequest\x1a\x1b.project.UpdatePortResponse\x12K\n" +
+	"\fIsPortPublic\x12\x1c.project.IsPortPublicRequest\x1a\x1d.project.IsPortPublicResponseB-Z+github.com/Aadithya-J/code_nest/proto;protob\x06proto3"
 
 var (
 	file_proto_project_proto_rawDescOnce sync.Once
@@ -772,42 +2446,99 @@ func file_proto_project_proto_rawDescGZIP() []byte {
 }
 
 var file_proto_project_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_proto_project_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_proto_project_proto_msgTypes = make([]protoimpl.MessageInfo, 38)
 var file_proto_project_proto_goTypes = []any{
 	(ProjectStatus)(0),                // 0: project.ProjectStatus
-	(*CreateProjectRequest)(nil),      // 1: project.CreateProjectRequest
-	(*CreateProjectResponse)(nil),     // 2: project.CreateProjectResponse
-	(*StartWorkspaceRequest)(nil),     // 3: project.StartWorkspaceRequest
-	(*StartWorkspaceResponse)(nil),    // 4: project.StartWorkspaceResponse
-	(*StopWorkspaceRequest)(nil),      // 5: project.StopWorkspaceRequest
-	(*StopWorkspaceResponse)(nil),     // 6: project.StopWorkspaceResponse
-	(*WebhookUpdateRequest)(nil),      // 7: project.WebhookUpdateRequest
-	(*WebhookUpdateResponse)(nil),     // 8: project.WebhookUpdateResponse
-	(*VerifyAndCompleteRequest)(nil),  // 9: project.VerifyAndCompleteRequest
-	(*VerifyAndCompleteResponse)(nil), // 10: project.VerifyAndCompleteResponse
-	(*IsOwnerRequest)(nil),            // 11: project.IsOwnerRequest
-	(*IsOwnerResponse)(nil),           // 12: project.IsOwnerResponse
+	(*CloneOptions)(nil),              // 1: project.CloneOptions
+	(*CreateProjectRequest)(nil),      // 2: project.CreateProjectRequest
+	(*CreateProjectResponse)(nil),     // 3: project.CreateProjectResponse
+	(*StartWorkspaceRequest)(nil),     // 4: project.StartWorkspaceRequest
+	(*StartWorkspaceResponse)(nil),    // 5: project.StartWorkspaceResponse
+	(*StopWorkspaceRequest)(nil),      // 6: project.StopWorkspaceRequest
+	(*StopWorkspaceResponse)(nil),     // 7: project.StopWorkspaceResponse
+	(*WebhookUpdateRequest)(nil),      // 8: project.WebhookUpdateRequest
+	(*WebhookUpdateResponse)(nil),     // 9: project.WebhookUpdateResponse
+	(*VerifyAndCompleteRequest)(nil),  // 10: project.VerifyAndCompleteRequest
+	(*VerifyAndCompleteResponse)(nil), // 11: project.VerifyAndCompleteResponse
+	(*IsOwnerRequest)(nil),            // 12: project.IsOwnerRequest
+	(*IsOwnerResponse)(nil),           // 13: project.IsOwnerResponse
+	(*SetAutosavePolicyRequest)(nil),  // 14: project.SetAutosavePolicyRequest
+	(*SetAutosavePolicyResponse)(nil), // 15: project.SetAutosavePolicyResponse
+	(*SyncResult)(nil),                // 16: project.SyncResult
+	(*ReportSyncRequest)(nil),         // 17: project.ReportSyncRequest
+	(*ReportSyncResponse)(nil),        // 18: project.ReportSyncResponse
+	(*GetLastSyncRequest)(nil),        // 19: project.GetLastSyncRequest
+	(*GetLastSyncResponse)(nil),       // 20: project.GetLastSyncResponse
+	(*SetCloneOptionsRequest)(nil),    // 21: project.SetCloneOptionsRequest
+	(*SetCloneOptionsResponse)(nil),   // 22: project.SetCloneOptionsResponse
+	(*CloneProgress)(nil),             // 23: project.CloneProgress
+	(*ReportProgressRequest)(nil),     // 24: project.ReportProgressRequest
+	(*ReportProgressResponse)(nil),    // 25: project.ReportProgressResponse
+	(*GetCloneProgressRequest)(nil),   // 26: project.GetCloneProgressRequest
+	(*GetCloneProgressResponse)(nil),  // 27: project.GetCloneProgressResponse
+	(*PortInfo)(nil),                  // 28: project.PortInfo
+	(*ReportPortRequest)(nil),         // 29: project.ReportPortRequest
+	(*ReportPortResponse)(nil),        // 30: project.ReportPortResponse
+	(*ListPortsRequest)(nil),          // 31: project.ListPortsRequest
+	(*ListPortsResponse)(nil),         // 32: project.ListPortsResponse
+	(*SetPortVisibilityRequest)(nil),  // 33: project.SetPortVisibilityRequest
+	(*SetPortVisibilityResponse)(nil), // 34: project.SetPortVisibilityResponse
+	(*UpdatePortRequest)(nil),         // 35: project.UpdatePortRequest
+	(*UpdatePortResponse)(nil),        // 36: project.UpdatePortResponse
+	(*IsPortPublicRequest)(nil),       // 37: project.IsPortPublicRequest
+	(*IsPortPublicResponse)(nil),      // 38: project.IsPortPublicResponse
 }
 var file_proto_project_proto_depIdxs = []int32{
-	0,  // 0: project.StartWorkspaceResponse.status:type_name -> project.ProjectStatus
-	0,  // 1: project.VerifyAndCompleteResponse.status:type_name -> project.ProjectStatus
-	1,  // 2: project.ProjectService.CreateProject:input_type -> project.CreateProjectRequest
-	3,  // 3: project.ProjectService.StartWorkspace:input_type -> project.StartWorkspaceRequest
-	5,  // 4: project.ProjectService.StopWorkspace:input_type -> project.StopWorkspaceRequest
-	7,  // 5: project.ProjectService.WebhookUpdate:input_type -> project.WebhookUpdateRequest
-	9,  // 6: project.ProjectService.VerifyAndComplete:input_type -> project.VerifyAndCompleteRequest
-	11, // 7: project.ProjectService.IsOwner:input_type -> project.IsOwnerRequest
-	2,  // 8: project.ProjectService.CreateProject:output_type -> project.CreateProjectResponse
-	4,  // 9: project.ProjectService.StartWorkspace:output_type -> project.StartWorkspaceResponse
-	6,  // 10: project.ProjectService.StopWorkspace:output_type -> project.StopWorkspaceResponse
-	8,  // 11: project.ProjectService.WebhookUpdate:output_type -> project.WebhookUpdateResponse
-	10, // 12: project.ProjectService.VerifyAndComplete:output_type -> project.VerifyAndCompleteResponse
-	12, // 13: project.ProjectService.IsOwner:output_type -> project.IsOwnerResponse
-	8,  // [8:14] is the sub-list for method output_type
-	2,  // [2:8] is the sub-list for method input_type
-	2,  // [2:2] is the sub-list for extension type_name
-	2,  // [2:2] is the sub-list for extension extendee
-	0,  // [0:2] is the sub-list for field type_name
+	1,  // 0: project.CreateProjectRequest.clone:type_name -> project.CloneOptions
+	0,  // 1: project.StartWorkspaceResponse.status:type_name -> project.ProjectStatus
+	0,  // 2: project.VerifyAndCompleteResponse.status:type_name -> project.ProjectStatus
+	16, // 3: project.ReportSyncRequest.result:type_name -> project.SyncResult
+	16, // 4: project.GetLastSyncResponse.result:type_name -> project.SyncResult
+	1,  // 5: project.SetCloneOptionsRequest.clone:type_name -> project.CloneOptions
+	23, // 6: project.ReportProgressRequest.progress:type_name -> project.CloneProgress
+	23, // 7: project.GetCloneProgressResponse.progress:type_name -> project.CloneProgress
+	28, // 8: project.ListPortsResponse.ports:type_name -> project.PortInfo
+	28, // 9: project.SetPortVisibilityResponse.port:type_name -> project.PortInfo
+	28, // 10: project.UpdatePortResponse.port:type_name -> project.PortInfo
+	2,  // 11: project.ProjectService.CreateProject:input_type -> project.CreateProjectRequest
+	4,  // 12: project.ProjectService.StartWorkspace:input_type -> project.StartWorkspaceRequest
+	6,  // 13: project.ProjectService.StopWorkspace:input_type -> project.StopWorkspaceRequest
+	8,  // 14: project.ProjectService.WebhookUpdate:input_type -> project.WebhookUpdateRequest
+	10, // 15: project.ProjectService.VerifyAndComplete:input_type -> project.VerifyAndCompleteRequest
+	12, // 16: project.ProjectService.IsOwner:input_type -> project.IsOwnerRequest
+	14, // 17: project.ProjectService.SetAutosavePolicy:input_type -> project.SetAutosavePolicyRequest
+	17, // 18: project.ProjectService.ReportSync:input_type -> project.ReportSyncRequest
+	19, // 19: project.ProjectService.GetLastSync:input_type -> project.GetLastSyncRequest
+	21, // 20: project.ProjectService.SetCloneOptions:input_type -> project.SetCloneOptionsRequest
+	24, // 21: project.ProjectService.ReportProgress:input_type -> project.ReportProgressRequest
+	26, // 22: project.ProjectService.GetCloneProgress:input_type -> project.GetCloneProgressRequest
+	29, // 23: project.ProjectService.ReportPort:input_type -> project.ReportPortRequest
+	31, // 24: project.ProjectService.ListPorts:input_type -> project.ListPortsRequest
+	33, // 25: project.ProjectService.SetPortVisibility:input_type -> project.SetPortVisibilityRequest
+	35, // 26: project.ProjectService.UpdatePort:input_type -> project.UpdatePortRequest
+	37, // 27: project.ProjectService.IsPortPublic:input_type -> project.IsPortPublicRequest
+	3,  // 28: project.ProjectService.CreateProject:output_type -> project.CreateProjectResponse
+	5,  // 29: project.ProjectService.StartWorkspace:output_type -> project.StartWorkspaceResponse
+	7,  // 30: project.ProjectService.StopWorkspace:output_type -> project.StopWorkspaceResponse
+	9,  // 31: project.ProjectService.WebhookUpdate:output_type -> project.WebhookUpdateResponse
+	11, // 32: project.ProjectService.VerifyAndComplete:output_type -> project.VerifyAndCompleteResponse
+	13, // 33: project.ProjectService.IsOwner:output_type -> project.IsOwnerResponse
+	15, // 34: project.ProjectService.SetAutosavePolicy:output_type -> project.SetAutosavePolicyResponse
+	18, // 35: project.ProjectService.ReportSync:output_type -> project.ReportSyncResponse
+	20, // 36: project.ProjectService.GetLastSync:output_type -> project.GetLastSyncResponse
+	22, // 37: project.ProjectService.SetCloneOptions:output_type -> project.SetCloneOptionsResponse
+	25, // 38: project.ProjectService.ReportProgress:output_type -> project.ReportProgressResponse
+	27, // 39: project.ProjectService.GetCloneProgress:output_type -> project.GetCloneProgressResponse
+	30, // 40: project.ProjectService.ReportPort:output_type -> project.ReportPortResponse
+	32, // 41: project.ProjectService.ListPorts:output_type -> project.ListPortsResponse
+	34, // 42: project.ProjectService.SetPortVisibility:output_type -> project.SetPortVisibilityResponse
+	36, // 43: project.ProjectService.UpdatePort:output_type -> project.UpdatePortResponse
+	38, // 44: project.ProjectService.IsPortPublic:output_type -> project.IsPortPublicResponse
+	28, // [28:45] is the sub-list for method output_type
+	11, // [11:28] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_proto_project_proto_init() }
@@ -821,7 +2552,7 @@ func file_proto_project_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_project_proto_rawDesc), len(file_proto_project_proto_rawDesc)),
 			NumEnums:      1,
-			NumMessages:   12,
+			NumMessages:   38,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
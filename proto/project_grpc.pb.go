@@ -25,6 +25,17 @@ const (
 	ProjectService_WebhookUpdate_FullMethodName     = "/project.ProjectService/WebhookUpdate"
 	ProjectService_VerifyAndComplete_FullMethodName = "/project.ProjectService/VerifyAndComplete"
 	ProjectService_IsOwner_FullMethodName           = "/project.ProjectService/IsOwner"
+	ProjectService_SetAutosavePolicy_FullMethodName = "/project.ProjectService/SetAutosavePolicy"
+	ProjectService_ReportSync_FullMethodName        = "/project.ProjectService/ReportSync"
+	ProjectService_GetLastSync_FullMethodName       = "/project.ProjectService/GetLastSync"
+	ProjectService_SetCloneOptions_FullMethodName   = "/project.ProjectService/SetCloneOptions"
+	ProjectService_ReportProgress_FullMethodName    = "/project.ProjectService/ReportProgress"
+	ProjectService_GetCloneProgress_FullMethodName  = "/project.ProjectService/GetCloneProgress"
+	ProjectService_ReportPort_FullMethodName        = "/project.ProjectService/ReportPort"
+	ProjectService_ListPorts_FullMethodName         = "/project.ProjectService/ListPorts"
+	ProjectService_SetPortVisibility_FullMethodName = "/project.ProjectService/SetPortVisibility"
+	ProjectService_UpdatePort_FullMethodName        = "/project.ProjectService/UpdatePort"
+	ProjectService_IsPortPublic_FullMethodName      = "/project.ProjectService/IsPortPublic"
 )
 
 // ProjectServiceClient is the client API for ProjectService service.
@@ -37,6 +48,17 @@ type ProjectServiceClient interface {
 	WebhookUpdate(ctx context.Context, in *WebhookUpdateRequest, opts ...grpc.CallOption) (*WebhookUpdateResponse, error)
 	VerifyAndComplete(ctx context.Context, in *VerifyAndCompleteRequest, opts ...grpc.CallOption) (*VerifyAndCompleteResponse, error)
 	IsOwner(ctx context.Context, in *IsOwnerRequest, opts ...grpc.CallOption) (*IsOwnerResponse, error)
+	SetAutosavePolicy(ctx context.Context, in *SetAutosavePolicyRequest, opts ...grpc.CallOption) (*SetAutosavePolicyResponse, error)
+	ReportSync(ctx context.Context, in *ReportSyncRequest, opts ...grpc.CallOption) (*ReportSyncResponse, error)
+	GetLastSync(ctx context.Context, in *GetLastSyncRequest, opts ...grpc.CallOption) (*GetLastSyncResponse, error)
+	SetCloneOptions(ctx context.Context, in *SetCloneOptionsRequest, opts ...grpc.CallOption) (*SetCloneOptionsResponse, error)
+	ReportProgress(ctx context.Context, in *ReportProgressRequest, opts ...grpc.CallOption) (*ReportProgressResponse, error)
+	GetCloneProgress(ctx context.Context, in *GetCloneProgressRequest, opts ...grpc.CallOption) (*GetCloneProgressResponse, error)
+	ReportPort(ctx context.Context, in *ReportPortRequest, opts ...grpc.CallOption) (*ReportPortResponse, error)
+	ListPorts(ctx context.Context, in *ListPortsRequest, opts ...grpc.CallOption) (*ListPortsResponse, error)
+	SetPortVisibility(ctx context.Context, in *SetPortVisibilityRequest, opts ...grpc.CallOption) (*SetPortVisibilityResponse, error)
+	UpdatePort(ctx context.Context, in *UpdatePortRequest, opts ...grpc.CallOption) (*UpdatePortResponse, error)
+	IsPortPublic(ctx context.Context, in *IsPortPublicRequest, opts ...grpc.CallOption) (*IsPortPublicResponse, error)
 }
 
 type projectServiceClient struct {
@@ -107,6 +129,116 @@ func (c *projectServiceClient) IsOwner(ctx context.Context, in *IsOwnerRequest,
 	return out, nil
 }
 
+func (c *projectServiceClient) SetAutosavePolicy(ctx context.Context, in *SetAutosavePolicyRequest, opts ...grpc.CallOption) (*SetAutosavePolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetAutosavePolicyResponse)
+	err := c.cc.Invoke(ctx, ProjectService_SetAutosavePolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projectServiceClient) ReportSync(ctx context.Context, in *ReportSyncRequest, opts ...grpc.CallOption) (*ReportSyncResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportSyncResponse)
+	err := c.cc.Invoke(ctx, ProjectService_ReportSync_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projectServiceClient) GetLastSync(ctx context.Context, in *GetLastSyncRequest, opts ...grpc.CallOption) (*GetLastSyncResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetLastSyncResponse)
+	err := c.cc.Invoke(ctx, ProjectService_GetLastSync_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projectServiceClient) SetCloneOptions(ctx context.Context, in *SetCloneOptionsRequest, opts ...grpc.CallOption) (*SetCloneOptionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetCloneOptionsResponse)
+	err := c.cc.Invoke(ctx, ProjectService_SetCloneOptions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projectServiceClient) ReportProgress(ctx context.Context, in *ReportProgressRequest, opts ...grpc.CallOption) (*ReportProgressResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportProgressResponse)
+	err := c.cc.Invoke(ctx, ProjectService_ReportProgress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projectServiceClient) GetCloneProgress(ctx context.Context, in *GetCloneProgressRequest, opts ...grpc.CallOption) (*GetCloneProgressResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCloneProgressResponse)
+	err := c.cc.Invoke(ctx, ProjectService_GetCloneProgress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projectServiceClient) ReportPort(ctx context.Context, in *ReportPortRequest, opts ...grpc.CallOption) (*ReportPortResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportPortResponse)
+	err := c.cc.Invoke(ctx, ProjectService_ReportPort_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projectServiceClient) ListPorts(ctx context.Context, in *ListPortsRequest, opts ...grpc.CallOption) (*ListPortsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPortsResponse)
+	err := c.cc.Invoke(ctx, ProjectService_ListPorts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projectServiceClient) SetPortVisibility(ctx context.Context, in *SetPortVisibilityRequest, opts ...grpc.CallOption) (*SetPortVisibilityResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetPortVisibilityResponse)
+	err := c.cc.Invoke(ctx, ProjectService_SetPortVisibility_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projectServiceClient) UpdatePort(ctx context.Context, in *UpdatePortRequest, opts ...grpc.CallOption) (*UpdatePortResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdatePortResponse)
+	err := c.cc.Invoke(ctx, ProjectService_UpdatePort_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *projectServiceClient) IsPortPublic(ctx context.Context, in *IsPortPublicRequest, opts ...grpc.CallOption) (*IsPortPublicResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IsPortPublicResponse)
+	err := c.cc.Invoke(ctx, ProjectService_IsPortPublic_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ProjectServiceServer is the server API for ProjectService service.
 // All implementations must embed UnimplementedProjectServiceServer
 // for forward compatibility.
@@ -117,6 +249,17 @@ type ProjectServiceServer interface {
 	WebhookUpdate(context.Context, *WebhookUpdateRequest) (*WebhookUpdateResponse, error)
 	VerifyAndComplete(context.Context, *VerifyAndCompleteRequest) (*VerifyAndCompleteResponse, error)
 	IsOwner(context.Context, *IsOwnerRequest) (*IsOwnerResponse, error)
+	SetAutosavePolicy(context.Context, *SetAutosavePolicyRequest) (*SetAutosavePolicyResponse, error)
+	ReportSync(context.Context, *ReportSyncRequest) (*ReportSyncResponse, error)
+	GetLastSync(context.Context, *GetLastSyncRequest) (*GetLastSyncResponse, error)
+	SetCloneOptions(context.Context, *SetCloneOptionsRequest) (*SetCloneOptionsResponse, error)
+	ReportProgress(context.Context, *ReportProgressRequest) (*ReportProgressResponse, error)
+	GetCloneProgress(context.Context, *GetCloneProgressRequest) (*GetCloneProgressResponse, error)
+	ReportPort(context.Context, *ReportPortRequest) (*ReportPortResponse, error)
+	ListPorts(context.Context, *ListPortsRequest) (*ListPortsResponse, error)
+	SetPortVisibility(context.Context, *SetPortVisibilityRequest) (*SetPortVisibilityResponse, error)
+	UpdatePort(context.Context, *UpdatePortRequest) (*UpdatePortResponse, error)
+	IsPortPublic(context.Context, *IsPortPublicRequest) (*IsPortPublicResponse, error)
 	mustEmbedUnimplementedProjectServiceServer()
 }
 
@@ -145,6 +288,39 @@ func (UnimplementedProjectServiceServer) VerifyAndComplete(context.Context, *Ver
 func (UnimplementedProjectServiceServer) IsOwner(context.Context, *IsOwnerRequest) (*IsOwnerResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method IsOwner not implemented")
 }
+func (UnimplementedProjectServiceServer) SetAutosavePolicy(context.Context, *SetAutosavePolicyRequest) (*SetAutosavePolicyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetAutosavePolicy not implemented")
+}
+func (UnimplementedProjectServiceServer) ReportSync(context.Context, *ReportSyncRequest) (*ReportSyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportSync not implemented")
+}
+func (UnimplementedProjectServiceServer) GetLastSync(context.Context, *GetLastSyncRequest) (*GetLastSyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLastSync not implemented")
+}
+func (UnimplementedProjectServiceServer) SetCloneOptions(context.Context, *SetCloneOptionsRequest) (*SetCloneOptionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetCloneOptions not implemented")
+}
+func (UnimplementedProjectServiceServer) ReportProgress(context.Context, *ReportProgressRequest) (*ReportProgressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportProgress not implemented")
+}
+func (UnimplementedProjectServiceServer) GetCloneProgress(context.Context, *GetCloneProgressRequest) (*GetCloneProgressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCloneProgress not implemented")
+}
+func (UnimplementedProjectServiceServer) ReportPort(context.Context, *ReportPortRequest) (*ReportPortResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportPort not implemented")
+}
+func (UnimplementedProjectServiceServer) ListPorts(context.Context, *ListPortsRequest) (*ListPortsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPorts not implemented")
+}
+func (UnimplementedProjectServiceServer) SetPortVisibility(context.Context, *SetPortVisibilityRequest) (*SetPortVisibilityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPortVisibility not implemented")
+}
+func (UnimplementedProjectServiceServer) UpdatePort(context.Context, *UpdatePortRequest) (*UpdatePortResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePort not implemented")
+}
+func (UnimplementedProjectServiceServer) IsPortPublic(context.Context, *IsPortPublicRequest) (*IsPortPublicResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsPortPublic not implemented")
+}
 func (UnimplementedProjectServiceServer) mustEmbedUnimplementedProjectServiceServer() {}
 func (UnimplementedProjectServiceServer) testEmbeddedByValue()                        {}
 
@@ -274,6 +450,204 @@ func _ProjectService_IsOwner_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ProjectService_SetAutosavePolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAutosavePolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjectServiceServer).SetAutosavePolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProjectService_SetAutosavePolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjectServiceServer).SetAutosavePolicy(ctx, req.(*SetAutosavePolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjectService_ReportSync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportSyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjectServiceServer).ReportSync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProjectService_ReportSync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjectServiceServer).ReportSync(ctx, req.(*ReportSyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjectService_GetLastSync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLastSyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjectServiceServer).GetLastSync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProjectService_GetLastSync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjectServiceServer).GetLastSync(ctx, req.(*GetLastSyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjectService_SetCloneOptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetCloneOptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjectServiceServer).SetCloneOptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProjectService_SetCloneOptions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjectServiceServer).SetCloneOptions(ctx, req.(*SetCloneOptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjectService_ReportProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportProgressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjectServiceServer).ReportProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProjectService_ReportProgress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjectServiceServer).ReportProgress(ctx, req.(*ReportProgressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjectService_GetCloneProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCloneProgressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjectServiceServer).GetCloneProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProjectService_GetCloneProgress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjectServiceServer).GetCloneProgress(ctx, req.(*GetCloneProgressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjectService_ReportPort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportPortRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjectServiceServer).ReportPort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProjectService_ReportPort_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjectServiceServer).ReportPort(ctx, req.(*ReportPortRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjectService_ListPorts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPortsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjectServiceServer).ListPorts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProjectService_ListPorts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjectServiceServer).ListPorts(ctx, req.(*ListPortsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjectService_SetPortVisibility_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPortVisibilityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjectServiceServer).SetPortVisibility(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProjectService_SetPortVisibility_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjectServiceServer).SetPortVisibility(ctx, req.(*SetPortVisibilityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjectService_UpdatePort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePortRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjectServiceServer).UpdatePort(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProjectService_UpdatePort_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjectServiceServer).UpdatePort(ctx, req.(*UpdatePortRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProjectService_IsPortPublic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IsPortPublicRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProjectServiceServer).IsPortPublic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProjectService_IsPortPublic_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProjectServiceServer).IsPortPublic(ctx, req.(*IsPortPublicRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ProjectService_ServiceDesc is the grpc.ServiceDesc for ProjectService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -305,6 +679,50 @@ var ProjectService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "IsOwner",
 			Handler:    _ProjectService_IsOwner_Handler,
 		},
+		{
+			MethodName: "SetAutosavePolicy",
+			Handler:    _ProjectService_SetAutosavePolicy_Handler,
+		},
+		{
+			MethodName: "ReportSync",
+			Handler:    _ProjectService_ReportSync_Handler,
+		},
+		{
+			MethodName: "GetLastSync",
+			Handler:    _ProjectService_GetLastSync_Handler,
+		},
+		{
+			MethodName: "SetCloneOptions",
+			Handler:    _ProjectService_SetCloneOptions_Handler,
+		},
+		{
+			MethodName: "ReportProgress",
+			Handler:    _ProjectService_ReportProgress_Handler,
+		},
+		{
+			MethodName: "GetCloneProgress",
+			Handler:    _ProjectService_GetCloneProgress_Handler,
+		},
+		{
+			MethodName: "ReportPort",
+			Handler:    _ProjectService_ReportPort_Handler,
+		},
+		{
+			MethodName: "ListPorts",
+			Handler:    _ProjectService_ListPorts_Handler,
+		},
+		{
+			MethodName: "SetPortVisibility",
+			Handler:    _ProjectService_SetPortVisibility_Handler,
+		},
+		{
+			MethodName: "UpdatePort",
+			Handler:    _ProjectService_UpdatePort_Handler,
+		},
+		{
+			MethodName: "IsPortPublic",
+			Handler:    _ProjectService_IsPortPublic_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/project.proto",
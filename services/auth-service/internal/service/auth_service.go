@@ -28,6 +28,12 @@ import (
 	"gopkg.in/square/go-jose.v2"
 )
 
+const (
+	// Audience of tokens that only grant access to one workspace's agent
+	workspaceAudience = "workspace"
+	workspaceTokenTTL = 10 * time.Minute
+)
+
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 	Get(url string) (*http.Response, error)
@@ -358,6 +364,14 @@ func (s *AuthService) ValidateToken(ctx context.Context, req *proto.ValidateToke
 		}, nil
 	}
 
+	// Workspace tokens are handed to agents; they must not work as a login
+	if aud, _ := claims.GetAudience(); len(aud) > 0 {
+		return &proto.ValidateTokenResponse{
+			Valid: false,
+			Error: "invalid token",
+		}, nil
+	}
+
 	sub, _ := claims["sub"].(string)
 	return &proto.ValidateTokenResponse{
 		Valid:  true,
@@ -365,6 +379,31 @@ func (s *AuthService) ValidateToken(ctx context.Context, req *proto.ValidateToke
 	}, nil
 }
 
+// IssueWorkspaceToken signs a short-lived token that lets a user reach one
+// workspace's agent. Agents verify it against the JWKS.
+func (s *AuthService) IssueWorkspaceToken(ctx context.Context, req *proto.IssueWorkspaceTokenRequest) (*proto.IssueWorkspaceTokenResponse, error) {
+	if req.GetUserId() == "" || req.GetWorkspaceId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id and workspace_id required")
+	}
+	now := time.Now()
+	expiresAt := now.Add(workspaceTokenTTL)
+	claims := jwt.MapClaims{
+		"sub": req.GetUserId(),
+		"aud": workspaceAudience,
+		"ws":  req.GetWorkspaceId(),
+		"iat": now.Unix(),
+		"exp": expiresAt.Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "1"
+
+	signed, err := token.SignedString(s.privateKey)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "sign token: %v", err)
+	}
+	return &proto.IssueWorkspaceTokenResponse{Token: signed, ExpiresAt: expiresAt.Unix()}, nil
+}
+
 func (s *AuthService) generateGitHubAppJWT() (string, error) {
 	block, _ := pem.Decode(s.githubPrivateKey)
 	if block == nil {
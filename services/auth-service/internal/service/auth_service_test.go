@@ -6,6 +6,7 @@ import (
 	"crypto/rsa"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
@@ -88,3 +89,36 @@ func TestAuthService_ValidateToken(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthService_IssueWorkspaceToken(t *testing.T) {
+	service := &AuthService{}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	service.privateKey = privateKey
+
+	resp, err := service.IssueWorkspaceToken(context.Background(), &proto.IssueWorkspaceTokenRequest{
+		UserId:      "user-123",
+		WorkspaceId: "ws-abc",
+	})
+	require.NoError(t, err)
+	assert.Greater(t, resp.ExpiresAt, time.Now().Unix())
+
+	parsed, err := jwt.Parse(resp.Token, func(token *jwt.Token) (interface{}, error) {
+		return &privateKey.PublicKey, nil
+	}, jwt.WithAudience("workspace"))
+	require.NoError(t, err)
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	require.True(t, ok)
+	assert.Equal(t, "user-123", claims["sub"])
+	assert.Equal(t, "ws-abc", claims["ws"])
+
+	// A workspace token is not a login token
+	validated, err := service.ValidateToken(context.Background(), &proto.ValidateTokenRequest{Token: resp.Token})
+	require.NoError(t, err)
+	assert.False(t, validated.Valid)
+
+	_, err = service.IssueWorkspaceToken(context.Background(), &proto.IssueWorkspaceTokenRequest{UserId: "user-123"})
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ module github.com/Aadithya-J/code_nest/services/gateway
 go 1.24.4
 
 require (
+	github.com/Aadithya-J/code_nest/origins v0.0.0-00010101000000-000000000000
 	github.com/Aadithya-J/code_nest/proto v0.0.0-00010101000000-000000000000
 	github.com/gin-contrib/cors v1.7.5
 	github.com/gin-gonic/gin v1.10.1
@@ -49,3 +50,5 @@ require (
 )
 
 replace github.com/Aadithya-J/code_nest/proto => ../../proto
+
+replace github.com/Aadithya-J/code_nest/origins => ../../origins
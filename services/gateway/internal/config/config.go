@@ -4,13 +4,14 @@ import (
 	"log"
 	"os"
 
+	"github.com/Aadithya-J/code_nest/origins"
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	Port         string
 	AuthRPCURL   string
-	AllowOrigins string
+	AllowOrigins []string
 	RedisAddr    string
 	ProjectRPC   string
 }
@@ -18,10 +19,16 @@ type Config struct {
 func Load() Config {
 	_ = godotenv.Load()
 
+	// Agents read the same variable
+	allowOrigins, err := origins.Parse(getEnv("ALLOWED_ORIGINS", "http://localhost:5173"))
+	if err != nil {
+		log.Fatalf("ALLOWED_ORIGINS: %v", err)
+	}
+
 	cfg := Config{
 		Port:         getEnv("PORT", "3000"),
 		AuthRPCURL:   getEnv("AUTH_RPC_URL", "auth-service:50051"),
-		AllowOrigins: getEnv("ALLOWED_ORIGINS", "http://localhost:5173"),
+		AllowOrigins: allowOrigins,
 		RedisAddr:    getEnv("REDIS_ADDR", "redis:6379"),
 		ProjectRPC:   getEnv("PROJECT_RPC_URL", "project-service:50052"),
 	}
@@ -37,3 +44,9 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// OriginAllowed reports whether origin matches one of the configured
+// patterns.
+func (c Config) OriginAllowed(origin string) bool {
+	return origins.Allowed(c.AllowOrigins, origin)
+}
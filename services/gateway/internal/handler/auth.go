@@ -2,7 +2,9 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"strconv"
 	"strings"
@@ -24,6 +26,7 @@ type AuthClient interface {
 	HandleGitHubCallback(context.Context, *proto.HandleGitHubCallbackRequest) (*proto.AuthResponse, error)
 	ValidateToken(context.Context, string) (*proto.ValidateTokenResponse, error)
 	GetGitHubAccessToken(ctx context.Context, req *proto.GetGitHubAccessTokenRequest) (*proto.GetGitHubAccessTokenResponse, error)
+	IssueWorkspaceToken(ctx context.Context, req *proto.IssueWorkspaceTokenRequest) (*proto.IssueWorkspaceTokenResponse, error)
 }
 
 type ProjectClient interface {
@@ -31,6 +34,17 @@ type ProjectClient interface {
 	StartWorkspace(ctx context.Context, req *proto.StartWorkspaceRequest) (*proto.StartWorkspaceResponse, error)
 	VerifyAndComplete(ctx context.Context, req *proto.VerifyAndCompleteRequest) (*proto.VerifyAndCompleteResponse, error)
 	IsOwner(ctx context.Context, req *proto.IsOwnerRequest) (*proto.IsOwnerResponse, error)
+	SetAutosavePolicy(ctx context.Context, req *proto.SetAutosavePolicyRequest) (*proto.SetAutosavePolicyResponse, error)
+	ReportSync(ctx context.Context, req *proto.ReportSyncRequest) (*proto.ReportSyncResponse, error)
+	GetLastSync(ctx context.Context, req *proto.GetLastSyncRequest) (*proto.GetLastSyncResponse, error)
+	SetCloneOptions(ctx context.Context, req *proto.SetCloneOptionsRequest) (*proto.SetCloneOptionsResponse, error)
+	ReportProgress(ctx context.Context, req *proto.ReportProgressRequest) (*proto.ReportProgressResponse, error)
+	GetCloneProgress(ctx context.Context, req *proto.GetCloneProgressRequest) (*proto.GetCloneProgressResponse, error)
+	ReportPort(ctx context.Context, req *proto.ReportPortRequest) (*proto.ReportPortResponse, error)
+	ListPorts(ctx context.Context, req *proto.ListPortsRequest) (*proto.ListPortsResponse, error)
+	SetPortVisibility(ctx context.Context, req *proto.SetPortVisibilityRequest) (*proto.SetPortVisibilityResponse, error)
+	UpdatePort(ctx context.Context, req *proto.UpdatePortRequest) (*proto.UpdatePortResponse, error)
+	IsPortPublic(ctx context.Context, req *proto.IsPortPublicRequest) (*proto.IsPortPublicResponse, error)
 }
 
 type Handler struct {
@@ -76,7 +90,18 @@ func (h *Handler) Register(r *gin.Engine) {
 		api.GET("/auth/github/callback", h.HandleGitHubCallback)
 		api.POST("/projects", h.CreateProject)
 		api.POST("/projects/:id/start", h.StartWorkspace)
+		api.POST("/workspaces/:atlasId/token", h.IssueWorkspaceToken)
+		api.PUT("/projects/:id/autosave", h.SetAutosavePolicy)
+		api.GET("/projects/:id/sync", h.GetLastSync)
+		api.PUT("/projects/:id/clone", h.SetCloneOptions)
+		api.GET("/projects/:id/progress", h.GetCloneProgress)
+		api.GET("/projects/:id/ports", h.ListPorts)
+		api.PUT("/projects/:id/ports/:port", h.UpdatePort)
+		api.PUT("/projects/:id/ports/:port/visibility", h.SetPortVisibility)
 		api.POST("/internal/webhook", h.HandleWebhookInternal)
+		api.POST("/internal/sync", h.HandleSyncInternal)
+		api.POST("/internal/progress", h.HandleProgressInternal)
+		api.POST("/internal/ports", h.HandlePortInternal)
 	}
 
 	r.GET("/auth/verify", h.VerifyRequest)
@@ -125,8 +150,11 @@ func (h *Handler) CreateProject(c *gin.Context) {
 	}
 
 	var body struct {
-		Name    string `json:"name" binding:"required"`
-		RepoURL string `json:"repoUrl" binding:"required"`
+		Name           string            `json:"name" binding:"required"`
+		RepoURL        string            `json:"repoUrl" binding:"required"`
+		AutosavePolicy string            `json:"autosavePolicy"` // off | shadow | snapshot | wip
+		DefaultBranch  string            `json:"defaultBranch"`
+		Clone          *cloneOptionsBody `json:"clone"`
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		h.errorResponse(c, 400, "Invalid request format", err)
@@ -134,9 +162,12 @@ func (h *Handler) CreateProject(c *gin.Context) {
 	}
 
 	resp, err := h.project.CreateProject(c.Request.Context(), &proto.CreateProjectRequest{
-		UserId:  authResp.GetUserId(),
-		Name:    body.Name,
-		RepoUrl: body.RepoURL,
+		UserId:         authResp.GetUserId(),
+		Name:           body.Name,
+		RepoUrl:        body.RepoURL,
+		AutosavePolicy: body.AutosavePolicy,
+		DefaultBranch:  body.DefaultBranch,
+		Clone:          body.Clone.toProto(),
 	})
 	if err != nil {
 		h.errorResponse(c, 500, "Internal server error", err)
@@ -170,9 +201,21 @@ func (h *Handler) StartWorkspace(c *gin.Context) {
 		return
 	}
 
+	// Optional body selecting what to check out
+	var body struct {
+		Ref         string `json:"ref"`         // branch, tag or commit
+		PullRequest int32  `json:"pullRequest"` // takes precedence over ref
+	}
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		h.errorResponse(c, 400, "Invalid request format", err)
+		return
+	}
+
 	resp, err := h.project.StartWorkspace(c.Request.Context(), &proto.StartWorkspaceRequest{
-		ProjectId: projectID,
-		UserId:    userID,
+		ProjectId:   projectID,
+		UserId:      userID,
+		Ref:         body.Ref,
+		PullRequest: body.PullRequest,
 	})
 	if err != nil {
 		h.errorResponse(c, 500, "Internal server error", err)
@@ -190,6 +233,98 @@ func (h *Handler) StartWorkspace(c *gin.Context) {
 	})
 }
 
+// cloneOptionsBody is the JSON form of proto.CloneOptions.
+type cloneOptionsBody struct {
+	Depth            int32    `json:"depth"`
+	Filter           string   `json:"filter"` // blob:none, tree:0 or blob:limit=<n>
+	SparsePaths      []string `json:"sparsePaths"`
+	PersistWorkspace bool     `json:"persistWorkspace"`
+}
+
+func (b *cloneOptionsBody) toProto() *proto.CloneOptions {
+	if b == nil {
+		return nil
+	}
+	return &proto.CloneOptions{
+		Depth:            b.Depth,
+		Filter:           b.Filter,
+		SparsePaths:      b.SparsePaths,
+		PersistWorkspace: b.PersistWorkspace,
+	}
+}
+
+// SetCloneOptions changes how the workspace clones the repository on start.
+func (h *Handler) SetCloneOptions(c *gin.Context) {
+	if h.project == nil {
+		h.errorResponse(c, 500, "Service unavailable", nil)
+		return
+	}
+	token := bearer(c.GetHeader("Authorization"))
+	if token == "" {
+		c.Status(401)
+		return
+	}
+	authResp, err := h.auth.ValidateToken(c.Request.Context(), token)
+	if err != nil || !authResp.GetValid() {
+		c.Status(401)
+		return
+	}
+
+	var body cloneOptionsBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.errorResponse(c, 400, "Invalid request format", err)
+		return
+	}
+
+	resp, err := h.project.SetCloneOptions(c.Request.Context(), &proto.SetCloneOptionsRequest{
+		ProjectId: c.Param("id"),
+		UserId:    authResp.GetUserId(),
+		Clone:     body.toProto(),
+	})
+	if err != nil {
+		h.errorResponse(c, 500, "Internal server error", err)
+		return
+	}
+	c.JSON(200, gin.H{"ok": resp.GetOk()})
+}
+
+// SetAutosavePolicy changes how the workspace agent saves uncommitted work.
+func (h *Handler) SetAutosavePolicy(c *gin.Context) {
+	if h.project == nil {
+		h.errorResponse(c, 500, "Service unavailable", nil)
+		return
+	}
+	token := bearer(c.GetHeader("Authorization"))
+	if token == "" {
+		c.Status(401)
+		return
+	}
+	authResp, err := h.auth.ValidateToken(c.Request.Context(), token)
+	if err != nil || !authResp.GetValid() {
+		c.Status(401)
+		return
+	}
+
+	var body struct {
+		Policy string `json:"policy" binding:"required,oneof=off shadow snapshot wip"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.errorResponse(c, 400, "Invalid request format", err)
+		return
+	}
+
+	resp, err := h.project.SetAutosavePolicy(c.Request.Context(), &proto.SetAutosavePolicyRequest{
+		ProjectId: c.Param("id"),
+		UserId:    authResp.GetUserId(),
+		Policy:    body.Policy,
+	})
+	if err != nil {
+		h.errorResponse(c, 500, "Internal server error", err)
+		return
+	}
+	c.JSON(200, gin.H{"ok": resp.GetOk(), "policy": resp.GetPolicy()})
+}
+
 func (h *Handler) Login(c *gin.Context) {
 	var body struct {
 		Email    string `json:"email" binding:"required,email"`
@@ -324,7 +459,7 @@ func (h *Handler) HandleWebhookInternal(c *gin.Context) {
 	token := c.GetHeader("Authorization")
 	var body struct {
 		ID     string `json:"id" binding:"required"`     // atlas id
-		Status string `json:"status" binding:"required"` // READY or ERROR
+		Status string `json:"status" binding:"required"` // READY, ERROR or SETUP_FAILED
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		h.errorResponse(c, 400, "Invalid request format", err)
@@ -346,7 +481,178 @@ func (h *Handler) HandleWebhookInternal(c *gin.Context) {
 	c.JSON(200, gin.H{"ok": true, "status": resp.GetStatus().String()})
 }
 
+// HandleSyncInternal forwards the agent's shutdown push outcome to project-service.
+func (h *Handler) HandleSyncInternal(c *gin.Context) {
+	if h.project == nil {
+		h.errorResponse(c, 500, "Service unavailable", nil)
+		return
+	}
+	token := c.GetHeader("Authorization")
+	var body struct {
+		ID        string `json:"id" binding:"required"`     // atlas id
+		Status    string `json:"status" binding:"required"` // PUSHED, RECOVERY, FAILED or SKIPPED
+		Branch    string `json:"branch"`
+		RemoteRef string `json:"remoteRef"`
+		Commit    string `json:"commit"`
+		Message   string `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.errorResponse(c, 400, "Invalid request format", err)
+		return
+	}
+	if token == "" {
+		h.errorResponse(c, 400, "Authorization required", nil)
+		return
+	}
+	resp, err := h.project.ReportSync(c.Request.Context(), &proto.ReportSyncRequest{
+		AtlasId:       body.ID,
+		CallbackToken: token,
+		Result: &proto.SyncResult{
+			Status:    body.Status,
+			Branch:    body.Branch,
+			RemoteRef: body.RemoteRef,
+			Commit:    body.Commit,
+			Message:   body.Message,
+		},
+	})
+	if err != nil || !resp.GetOk() {
+		h.errorResponse(c, 403, "Forbidden", err)
+		return
+	}
+	c.JSON(200, gin.H{"ok": true})
+}
+
+// GetLastSync tells the UI where the last session's commits were pushed.
+func (h *Handler) GetLastSync(c *gin.Context) {
+	if h.project == nil {
+		h.errorResponse(c, 500, "Service unavailable", nil)
+		return
+	}
+	token := bearer(c.GetHeader("Authorization"))
+	if token == "" {
+		c.Status(401)
+		return
+	}
+	authResp, err := h.auth.ValidateToken(c.Request.Context(), token)
+	if err != nil || !authResp.GetValid() {
+		c.Status(401)
+		return
+	}
+
+	resp, err := h.project.GetLastSync(c.Request.Context(), &proto.GetLastSyncRequest{
+		ProjectId: c.Param("id"),
+		UserId:    authResp.GetUserId(),
+	})
+	if err != nil {
+		h.errorResponse(c, 500, "Internal server error", err)
+		return
+	}
+	result := resp.GetResult()
+	if result == nil {
+		c.JSON(200, gin.H{"sync": nil})
+		return
+	}
+	c.JSON(200, gin.H{"sync": gin.H{
+		"status":     result.GetStatus(),
+		"branch":     result.GetBranch(),
+		"remoteRef":  result.GetRemoteRef(),
+		"commit":     result.GetCommit(),
+		"message":    result.GetMessage(),
+		"reportedAt": time.Unix(result.GetReportedAt(), 0).UTC().Format(time.RFC3339),
+	}})
+}
+
+// HandleProgressInternal forwards clone progress from a starting agent to project-service.
+func (h *Handler) HandleProgressInternal(c *gin.Context) {
+	if h.project == nil {
+		h.errorResponse(c, 500, "Service unavailable", nil)
+		return
+	}
+	token := c.GetHeader("Authorization")
+	var body struct {
+		ID      string `json:"id" binding:"required"` // atlas id
+		Phase   string `json:"phase" binding:"required"`
+		Percent int32  `json:"percent"`
+		Overall int32  `json:"overall"`
+		Message string `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.errorResponse(c, 400, "Invalid request format", err)
+		return
+	}
+	if token == "" {
+		h.errorResponse(c, 400, "Authorization required", nil)
+		return
+	}
+	resp, err := h.project.ReportProgress(c.Request.Context(), &proto.ReportProgressRequest{
+		AtlasId:       body.ID,
+		CallbackToken: token,
+		Progress: &proto.CloneProgress{
+			Phase:   body.Phase,
+			Percent: body.Percent,
+			Overall: body.Overall,
+			Message: body.Message,
+		},
+	})
+	if err != nil || !resp.GetOk() {
+		h.errorResponse(c, 403, "Forbidden", err)
+		return
+	}
+	c.JSON(200, gin.H{"ok": true})
+}
+
+// GetCloneProgress lets the UI show a progress bar while a workspace starts.
+func (h *Handler) GetCloneProgress(c *gin.Context) {
+	if h.project == nil {
+		h.errorResponse(c, 500, "Service unavailable", nil)
+		return
+	}
+	token := bearer(c.GetHeader("Authorization"))
+	if token == "" {
+		c.Status(401)
+		return
+	}
+	authResp, err := h.auth.ValidateToken(c.Request.Context(), token)
+	if err != nil || !authResp.GetValid() {
+		c.Status(401)
+		return
+	}
+
+	resp, err := h.project.GetCloneProgress(c.Request.Context(), &proto.GetCloneProgressRequest{
+		ProjectId: c.Param("id"),
+		UserId:    authResp.GetUserId(),
+	})
+	if err != nil {
+		h.errorResponse(c, 500, "Internal server error", err)
+		return
+	}
+	progress := resp.GetProgress()
+	if progress == nil {
+		c.JSON(200, gin.H{"progress": nil})
+		return
+	}
+	c.JSON(200, gin.H{"progress": gin.H{
+		"phase":     progress.GetPhase(),
+		"percent":   progress.GetPercent(),
+		"overall":   progress.GetOverall(),
+		"message":   progress.GetMessage(),
+		"updatedAt": time.Unix(progress.GetUpdatedAt(), 0).UTC().Format(time.RFC3339),
+	}})
+}
+
 func (h *Handler) VerifyRequest(c *gin.Context) {
+	atlasID, port := parseAtlasID(c.GetHeader("X-Forwarded-Host"))
+	if atlasID != "" {
+		c.Header("X-Workspace-Port", strconv.Itoa(port))
+	}
+
+	// Ports the owner shared are open to anyone with the link
+	if h.isPublicPort(c, atlasID, port) {
+		c.Header("X-Port-Visibility", "public")
+		c.Status(200)
+		return
+	}
+
 	token := bearer(c.GetHeader("Authorization"))
 	if token == "" {
 		token = c.GetHeader("X-Forwarded-Access-Token")
@@ -369,18 +675,17 @@ func (h *Handler) VerifyRequest(c *gin.Context) {
 
 	userID := resp.GetUserId()
 
-	host := c.GetHeader("X-Forwarded-Host")
-	atlasID := parseAtlasID(host)
 	if atlasID == "" {
 		c.Status(403)
 		return
 	}
 
+	// Private ports, and always the agent port, are for the owner only
+	c.Header("X-Port-Visibility", "private")
 	cacheKey := "auth_decision:" + userID + ":" + atlasID
 	if h.redis != nil {
 		if allowed, _ := h.redis.Get(c.Request.Context(), cacheKey).Result(); allowed == "1" {
-			c.Header("X-User-Id", userID)
-			c.Status(200)
+			h.verifiedOwner(c, userID, atlasID, port)
 			return
 		}
 	}
@@ -402,33 +707,120 @@ func (h *Handler) VerifyRequest(c *gin.Context) {
 		h.redis.Set(c.Request.Context(), cacheKey, "1", 30*time.Second)
 	}
 
+	h.verifiedOwner(c, userID, atlasID, port)
+}
+
+// verifiedOwner lets the owner's request through. Requests for the agent
+// also get a workspace token, which the proxy passes on for the agent to
+// verify itself.
+func (h *Handler) verifiedOwner(c *gin.Context, userID, atlasID string, port int) {
+	if port == agentPort {
+		token, err := h.workspaceToken(c.Request.Context(), userID, atlasID)
+		if err != nil {
+			c.Status(500)
+			return
+		}
+		c.Header("X-Workspace-Token", token)
+	}
 	c.Header("X-User-Id", userID)
 	c.Status(200)
 }
 
-func parseAtlasID(host string) string {
-	parts := strings.Split(host, ".")
-	if len(parts) == 0 {
-		return ""
+// IssueWorkspaceToken gives the owner a token for their workspace's agent.
+// The IDE sends it as a bearer token, or as ?access_token= on WebSockets,
+// so the agent can be reached without relying on the proxy to forward the
+// X-Workspace-Token header from /auth/verify.
+func (h *Handler) IssueWorkspaceToken(c *gin.Context) {
+	if h.project == nil {
+		h.errorResponse(c, 500, "Service unavailable", nil)
+		return
+	}
+	token := bearer(c.GetHeader("Authorization"))
+	if token == "" {
+		c.Status(401)
+		return
+	}
+	authResp, err := h.auth.ValidateToken(c.Request.Context(), token)
+	if err != nil || !authResp.GetValid() {
+		c.Status(401)
+		return
 	}
-	sub := parts[0]
 
-	// Handle standard ws- prefix
-	if strings.HasPrefix(sub, "ws-") {
-		return sub
+	atlasID := c.Param("atlasId")
+	own, err := h.project.IsOwner(c.Request.Context(), &proto.IsOwnerRequest{
+		UserId:    authResp.GetUserId(),
+		ProjectId: atlasID,
+	})
+	if err != nil {
+		h.rpcErrorResponse(c, err)
+		return
+	}
+	if !own.GetIsOwner() {
+		c.Status(403)
+		return
 	}
 
-	// Handle legacy 3000-ws- prefix (for backward compatibility)
-	if strings.HasPrefix(sub, "3000-ws-") {
-		return "ws-" + strings.TrimPrefix(sub, "3000-ws-")
+	wsToken, err := h.workspaceToken(c.Request.Context(), authResp.GetUserId(), atlasID)
+	if err != nil {
+		h.errorResponse(c, 500, "Failed to issue workspace token", err)
+		return
 	}
+	c.JSON(200, gin.H{"token": wsToken})
+}
 
-	// Try to extract any ws- pattern from the subdomain
-	if idx := strings.Index(sub, "ws-"); idx != -1 {
-		return sub[idx:]
+// workspaceToken returns a token scoped to one workspace, reusing a cached
+// one until shortly before it expires.
+func (h *Handler) workspaceToken(ctx context.Context, userID, atlasID string) (string, error) {
+	cacheKey := "workspace_token:" + userID + ":" + atlasID
+	if h.redis != nil {
+		if token, err := h.redis.Get(ctx, cacheKey).Result(); err == nil && token != "" {
+			return token, nil
+		}
 	}
+	resp, err := h.auth.IssueWorkspaceToken(ctx, &proto.IssueWorkspaceTokenRequest{
+		UserId:      userID,
+		WorkspaceId: atlasID,
+	})
+	if err != nil {
+		return "", err
+	}
+	// Leave the agent a margin to accept it
+	if ttl := time.Until(time.Unix(resp.GetExpiresAt(), 0)) - 2*time.Minute; h.redis != nil && ttl > 0 {
+		h.redis.Set(ctx, cacheKey, resp.GetToken(), ttl)
+	}
+	return resp.GetToken(), nil
+}
 
-	return ""
+// legacyAgentPort is the port prefix agent URLs used before ports were
+// routed by hostname. "3000-ws-<uuid>" still reaches the agent until
+// clients have moved to the bare "ws-<uuid>" form.
+//
+// Deprecated: remove once no clients build 3000-ws- URLs.
+const legacyAgentPort = 3000
+
+// parseAtlasID splits a workspace hostname into the atlas id and the port it
+// routes to. "<port>-ws-<uuid>.<domain>" addresses a port in the workspace;
+// a bare "ws-<uuid>.<domain>", or the legacy "3000-ws-<uuid>.<domain>", is
+// the agent. It returns "" for anything else.
+func parseAtlasID(host string) (string, int) {
+	sub := strings.Split(host, ".")[0]
+
+	if strings.HasPrefix(sub, "ws-") {
+		return sub, agentPort
+	}
+
+	prefix, id, ok := strings.Cut(sub, "-ws-")
+	if !ok || id == "" {
+		return "", 0
+	}
+	port, err := strconv.Atoi(prefix)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0
+	}
+	if port == legacyAgentPort {
+		port = agentPort
+	}
+	return "ws-" + id, port
 }
 
 func bearer(h string) string {
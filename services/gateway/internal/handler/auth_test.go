@@ -0,0 +1,33 @@
+package handler
+
+import "testing"
+
+func TestParseAtlasID(t *testing.T) {
+	tests := []struct {
+		host string
+		id   string
+		port int
+	}{
+		{"ws-abc123.codenest.dev", "ws-abc123", agentPort},
+		{"ws-abc123.localhost:3000", "ws-abc123", agentPort},
+		{"5173-ws-abc123.codenest.dev", "ws-abc123", 5173},
+		{"8080-ws-a-b.codenest.dev", "ws-a-b", 8080},
+		{"1-ws-x", "ws-x", 1},
+		{"65535-ws-x.codenest.dev", "ws-x", 65535},
+		// The old agent hostname still reaches the agent
+		{"3000-ws-abc123.codenest.dev", "ws-abc123", agentPort},
+		{"codenest.dev", "", 0},
+		{"api.codenest.dev", "", 0},
+		{"0-ws-x.codenest.dev", "", 0},
+		{"65536-ws-x.codenest.dev", "", 0},
+		{"abc-ws-x.codenest.dev", "", 0},
+		{"5173-ws-.codenest.dev", "", 0},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		id, port := parseAtlasID(tt.host)
+		if id != tt.id || port != tt.port {
+			t.Errorf("parseAtlasID(%q) = %q, %d, want %q, %d", tt.host, id, port, tt.id, tt.port)
+		}
+	}
+}
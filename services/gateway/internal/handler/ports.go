@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Aadithya-J/code_nest/proto"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// How long VerifyRequest caches a port's visibility. Sharing or unsharing a
+// port takes effect within this time.
+const portVisibilityTTL = 10 * time.Second
+
+// agentPort is where the agent's API listens in every workspace. It is
+// never public, whatever project-service says.
+const agentPort = 9000
+
+func portJSON(p *proto.PortInfo) gin.H {
+	return gin.H{
+		"port":      p.GetPort(),
+		"label":     p.GetLabel(),
+		"protocol":  p.GetProtocol(),
+		"public":    p.GetPublic(),
+		"open":      p.GetOpen(),
+		"process":   p.GetProcess(),
+		"updatedAt": time.Unix(p.GetUpdatedAt(), 0).UTC().Format(time.RFC3339),
+	}
+}
+
+// rpcErrorResponse answers a failed project-service call with the HTTP
+// status matching its gRPC code.
+func (h *Handler) rpcErrorResponse(c *gin.Context, err error) {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		h.errorResponse(c, 400, "Invalid request", err)
+	case codes.Unauthenticated:
+		h.errorResponse(c, 401, "Unauthorized", err)
+	case codes.PermissionDenied:
+		h.errorResponse(c, 403, "Forbidden", err)
+	case codes.NotFound:
+		h.errorResponse(c, 404, "Not found", err)
+	case codes.Unavailable, codes.DeadlineExceeded:
+		h.errorResponse(c, 503, "Service unavailable", err)
+	default:
+		h.errorResponse(c, 500, "Internal server error", err)
+	}
+}
+
+// HandlePortInternal forwards a port opening or closing reported by the agent to project-service.
+func (h *Handler) HandlePortInternal(c *gin.Context) {
+	if h.project == nil {
+		h.errorResponse(c, 500, "Service unavailable", nil)
+		return
+	}
+	token := c.GetHeader("Authorization")
+	var body struct {
+		ID       string `json:"id" binding:"required"` // atlas id
+		Port     int32  `json:"port" binding:"required,min=1,max=65535"`
+		Open     bool   `json:"open"`
+		Process  string `json:"process"`
+		Label    string `json:"label"`
+		Protocol string `json:"protocol"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.errorResponse(c, 400, "Invalid request format", err)
+		return
+	}
+	if token == "" {
+		h.errorResponse(c, 400, "Authorization required", nil)
+		return
+	}
+	resp, err := h.project.ReportPort(c.Request.Context(), &proto.ReportPortRequest{
+		AtlasId:       body.ID,
+		CallbackToken: token,
+		Port:          body.Port,
+		Open:          body.Open,
+		Process:       body.Process,
+		Label:         body.Label,
+		Protocol:      body.Protocol,
+	})
+	if err != nil {
+		h.rpcErrorResponse(c, err)
+		return
+	}
+	if !resp.GetOk() {
+		h.errorResponse(c, 403, "Forbidden", nil)
+		return
+	}
+	c.JSON(200, gin.H{"ok": true})
+}
+
+// ListPorts returns the ports of a project's workspace and whether each is shared.
+func (h *Handler) ListPorts(c *gin.Context) {
+	if h.project == nil {
+		h.errorResponse(c, 500, "Service unavailable", nil)
+		return
+	}
+	token := bearer(c.GetHeader("Authorization"))
+	if token == "" {
+		c.Status(401)
+		return
+	}
+	authResp, err := h.auth.ValidateToken(c.Request.Context(), token)
+	if err != nil || !authResp.GetValid() {
+		c.Status(401)
+		return
+	}
+
+	resp, err := h.project.ListPorts(c.Request.Context(), &proto.ListPortsRequest{
+		ProjectId: c.Param("id"),
+		UserId:    authResp.GetUserId(),
+	})
+	if err != nil {
+		h.rpcErrorResponse(c, err)
+		return
+	}
+	ports := make([]gin.H, 0, len(resp.GetPorts()))
+	for _, p := range resp.GetPorts() {
+		ports = append(ports, portJSON(p))
+	}
+	c.JSON(200, gin.H{"ports": ports})
+}
+
+// SetPortVisibility shares a port publicly or makes it private again.
+func (h *Handler) SetPortVisibility(c *gin.Context) {
+	if h.project == nil {
+		h.errorResponse(c, 500, "Service unavailable", nil)
+		return
+	}
+	token := bearer(c.GetHeader("Authorization"))
+	if token == "" {
+		c.Status(401)
+		return
+	}
+	authResp, err := h.auth.ValidateToken(c.Request.Context(), token)
+	if err != nil || !authResp.GetValid() {
+		c.Status(401)
+		return
+	}
+
+	port, err := strconv.Atoi(c.Param("port"))
+	if err != nil {
+		h.errorResponse(c, 400, "Invalid port", err)
+		return
+	}
+	var body struct {
+		Public *bool `json:"public" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.errorResponse(c, 400, "Invalid request format", err)
+		return
+	}
+
+	resp, err := h.project.SetPortVisibility(c.Request.Context(), &proto.SetPortVisibilityRequest{
+		ProjectId: c.Param("id"),
+		UserId:    authResp.GetUserId(),
+		Port:      int32(port),
+		Public:    *body.Public,
+	})
+	if err != nil {
+		h.rpcErrorResponse(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"port": portJSON(resp.GetPort())})
+}
+
+// UpdatePort sets a port's label and protocol.
+func (h *Handler) UpdatePort(c *gin.Context) {
+	if h.project == nil {
+		h.errorResponse(c, 500, "Service unavailable", nil)
+		return
+	}
+	token := bearer(c.GetHeader("Authorization"))
+	if token == "" {
+		c.Status(401)
+		return
+	}
+	authResp, err := h.auth.ValidateToken(c.Request.Context(), token)
+	if err != nil || !authResp.GetValid() {
+		c.Status(401)
+		return
+	}
+
+	port, err := strconv.Atoi(c.Param("port"))
+	if err != nil {
+		h.errorResponse(c, 400, "Invalid port", err)
+		return
+	}
+	var body struct {
+		Label    string `json:"label" binding:"max=100"`
+		Protocol string `json:"protocol" binding:"omitempty,oneof=http https"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		h.errorResponse(c, 400, "Invalid request format", err)
+		return
+	}
+
+	resp, err := h.project.UpdatePort(c.Request.Context(), &proto.UpdatePortRequest{
+		ProjectId: c.Param("id"),
+		UserId:    authResp.GetUserId(),
+		Port:      int32(port),
+		Label:     body.Label,
+		Protocol:  body.Protocol,
+	})
+	if err != nil {
+		h.rpcErrorResponse(c, err)
+		return
+	}
+	c.JSON(200, gin.H{"port": portJSON(resp.GetPort())})
+}
+
+// isPublicPort reports whether the owner shared the port a forwarded
+// request is for, so it can be served without signing in.
+func (h *Handler) isPublicPort(c *gin.Context, atlasID string, port int) bool {
+	if atlasID == "" || port == agentPort || h.project == nil {
+		return false
+	}
+	cacheKey := "port_public:" + atlasID + ":" + strconv.Itoa(port)
+	if h.redis != nil {
+		if v, err := h.redis.Get(c.Request.Context(), cacheKey).Result(); err == nil {
+			return v == "1"
+		}
+	}
+	resp, err := h.project.IsPortPublic(c.Request.Context(), &proto.IsPortPublicRequest{
+		AtlasId: atlasID,
+		Port:    int32(port),
+	})
+	if err != nil {
+		return false
+	}
+	if h.redis != nil {
+		v := "0"
+		if resp.GetPublic() {
+			v = "1"
+		}
+		h.redis.Set(c.Request.Context(), cacheKey, v, portVisibilityTTL)
+	}
+	return resp.GetPublic()
+}
@@ -88,6 +88,10 @@ func (c *AuthClient) GetGitHubAccessToken(ctx context.Context, req *proto.GetGit
 	return c.Client.GetGitHubAccessToken(ctx, req)
 }
 
+func (c *AuthClient) IssueWorkspaceToken(ctx context.Context, req *proto.IssueWorkspaceTokenRequest) (*proto.IssueWorkspaceTokenResponse, error) {
+	return c.Client.IssueWorkspaceToken(ctx, req)
+}
+
 func (c *AuthClient) GenerateRepoToken(ctx context.Context, req *proto.GenerateRepoTokenRequest) (*proto.GenerateRepoTokenResponse, error) {
 	return c.Client.GenerateRepoToken(ctx, req)
 }
@@ -79,3 +79,47 @@ func (c *ProjectClient) VerifyAndComplete(ctx context.Context, req *proto.Verify
 func (c *ProjectClient) WebhookUpdate(ctx context.Context, req *proto.WebhookUpdateRequest) (*proto.WebhookUpdateResponse, error) {
 	return c.Client.WebhookUpdate(ctx, req)
 }
+
+func (c *ProjectClient) SetAutosavePolicy(ctx context.Context, req *proto.SetAutosavePolicyRequest) (*proto.SetAutosavePolicyResponse, error) {
+	return c.Client.SetAutosavePolicy(ctx, req)
+}
+
+func (c *ProjectClient) ReportSync(ctx context.Context, req *proto.ReportSyncRequest) (*proto.ReportSyncResponse, error) {
+	return c.Client.ReportSync(ctx, req)
+}
+
+func (c *ProjectClient) GetLastSync(ctx context.Context, req *proto.GetLastSyncRequest) (*proto.GetLastSyncResponse, error) {
+	return c.Client.GetLastSync(ctx, req)
+}
+
+func (c *ProjectClient) SetCloneOptions(ctx context.Context, req *proto.SetCloneOptionsRequest) (*proto.SetCloneOptionsResponse, error) {
+	return c.Client.SetCloneOptions(ctx, req)
+}
+
+func (c *ProjectClient) ReportProgress(ctx context.Context, req *proto.ReportProgressRequest) (*proto.ReportProgressResponse, error) {
+	return c.Client.ReportProgress(ctx, req)
+}
+
+func (c *ProjectClient) GetCloneProgress(ctx context.Context, req *proto.GetCloneProgressRequest) (*proto.GetCloneProgressResponse, error) {
+	return c.Client.GetCloneProgress(ctx, req)
+}
+
+func (c *ProjectClient) ReportPort(ctx context.Context, req *proto.ReportPortRequest) (*proto.ReportPortResponse, error) {
+	return c.Client.ReportPort(ctx, req)
+}
+
+func (c *ProjectClient) ListPorts(ctx context.Context, req *proto.ListPortsRequest) (*proto.ListPortsResponse, error) {
+	return c.Client.ListPorts(ctx, req)
+}
+
+func (c *ProjectClient) SetPortVisibility(ctx context.Context, req *proto.SetPortVisibilityRequest) (*proto.SetPortVisibilityResponse, error) {
+	return c.Client.SetPortVisibility(ctx, req)
+}
+
+func (c *ProjectClient) UpdatePort(ctx context.Context, req *proto.UpdatePortRequest) (*proto.UpdatePortResponse, error) {
+	return c.Client.UpdatePort(ctx, req)
+}
+
+func (c *ProjectClient) IsPortPublic(ctx context.Context, req *proto.IsPortPublicRequest) (*proto.IsPortPublicResponse, error) {
+	return c.Client.IsPortPublic(ctx, req)
+}
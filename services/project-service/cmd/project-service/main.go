@@ -54,6 +54,9 @@ func main() {
 	authClient := proto.NewAuthServiceClient(authConn)
 
 	svc := service.New(gdb, rdb, authClient, cfg.AtlasBase, cfg.GatewayURL)
+	svc.SetAgentAuth(cfg.AgentJWKSURL, cfg.AgentAuthDisabled)
+	svc.SetAllowedOrigins(cfg.AllowedOrigins)
+	svc.SetFileDenylist(cfg.FileDenylist)
 
 	lis, err := net.Listen("tcp", ":"+cfg.GRPCPort)
 	if err != nil {
@@ -9,15 +9,51 @@ import (
 )
 
 type Project struct {
-	ID            string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	Name          string `gorm:"not null;size:100"`
-	UserID        string `gorm:"type:uuid;not null;index"`
-	RepoURL       string `gorm:"not null"`
-	AtlasID       string `gorm:"uniqueIndex;not null"`
-	Status        string `gorm:"not null;default:'STOPPED'"`
-	WebhookSecret string `gorm:"type:text"`
-	UpdatedAt     time.Time
-	CreatedAt     time.Time
+	ID             string `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name           string `gorm:"not null;size:100"`
+	UserID         string `gorm:"type:uuid;not null;index"`
+	RepoURL        string `gorm:"not null"`
+	AtlasID        string `gorm:"uniqueIndex;not null"`
+	Status         string `gorm:"not null;default:'STOPPED'"`
+	WebhookSecret  string `gorm:"type:text"`
+	AutosavePolicy string `gorm:"not null;default:'shadow'"`
+	DefaultBranch  string
+	// Clone strategy, see proto.CloneOptions. SparsePaths is comma separated.
+	CloneDepth       int
+	CloneFilter      string
+	SparsePaths      string `gorm:"type:text"`
+	PersistWorkspace bool
+	// Outcome of the agent's last shutdown push
+	LastSyncStatus  string
+	LastSyncBranch  string
+	LastSyncRef     string
+	LastSyncCommit  string
+	LastSyncMessage string `gorm:"type:text"`
+	LastSyncAt      *time.Time
+	// Latest clone progress reported by the agent while starting
+	CloneProgressPhase   string
+	CloneProgressPercent int
+	CloneProgressOverall int
+	CloneProgressMessage string `gorm:"type:text"`
+	CloneProgressAt      *time.Time
+	UpdatedAt            time.Time
+	CreatedAt            time.Time
+}
+
+// Port is a port a workspace serves on. Rows outlive sessions so a port
+// stays public, and keeps its label, across restarts.
+type Port struct {
+	ID        uint   `gorm:"primaryKey"`
+	ProjectID string `gorm:"type:uuid;not null;uniqueIndex:idx_ports_project_port"`
+	Port      int    `gorm:"not null;uniqueIndex:idx_ports_project_port"`
+	Label     string
+	Protocol  string `gorm:"not null;default:'http'"`
+	Public    bool   `gorm:"not null;default:false"`
+	// Whether something is listening on it in the running workspace, and what
+	Open      bool `gorm:"not null;default:false"`
+	Process   string
+	UpdatedAt time.Time
+	CreatedAt time.Time
 }
 
 func Connect(dsn string) (*gorm.DB, error) {
@@ -25,7 +61,7 @@ func Connect(dsn string) (*gorm.DB, error) {
 }
 
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(&Project{})
+	return db.AutoMigrate(&Project{}, &Port{})
 }
 
 func DSN(host string, port int, user, pass, dbname string) string {
@@ -5,6 +5,12 @@ import (
 	"gorm.io/gorm"
 )
 
+var lastSyncFields = []string{"LastSyncStatus", "LastSyncBranch", "LastSyncRef", "LastSyncCommit", "LastSyncMessage", "LastSyncAt"}
+
+var cloneOptionFields = []string{"CloneDepth", "CloneFilter", "SparsePaths", "PersistWorkspace"}
+
+var cloneProgressFields = []string{"CloneProgressPhase", "CloneProgressPercent", "CloneProgressOverall", "CloneProgressMessage", "CloneProgressAt"}
+
 func Migrations() []*gormigrate.Migration {
 	return []*gormigrate.Migration{
 		{
@@ -16,5 +22,104 @@ func Migrations() []*gormigrate.Migration {
 				return tx.Migrator().DropTable("projects")
 			},
 		},
+		{
+			ID: "20261016_add_project_autosave_policy",
+			Migrate: func(tx *gorm.DB) error {
+				if tx.Migrator().HasColumn(&Project{}, "AutosavePolicy") {
+					return nil
+				}
+				return tx.Migrator().AddColumn(&Project{}, "AutosavePolicy")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropColumn(&Project{}, "AutosavePolicy")
+			},
+		},
+		{
+			ID: "20261016_add_project_last_sync",
+			Migrate: func(tx *gorm.DB) error {
+				for _, field := range lastSyncFields {
+					if tx.Migrator().HasColumn(&Project{}, field) {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(&Project{}, field); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				for _, field := range lastSyncFields {
+					if err := tx.Migrator().DropColumn(&Project{}, field); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "20261016_add_project_default_branch",
+			Migrate: func(tx *gorm.DB) error {
+				if tx.Migrator().HasColumn(&Project{}, "DefaultBranch") {
+					return nil
+				}
+				return tx.Migrator().AddColumn(&Project{}, "DefaultBranch")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropColumn(&Project{}, "DefaultBranch")
+			},
+		},
+		{
+			ID: "20261016_add_project_clone_options",
+			Migrate: func(tx *gorm.DB) error {
+				for _, field := range cloneOptionFields {
+					if tx.Migrator().HasColumn(&Project{}, field) {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(&Project{}, field); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				for _, field := range cloneOptionFields {
+					if err := tx.Migrator().DropColumn(&Project{}, field); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "20261016_add_project_clone_progress",
+			Migrate: func(tx *gorm.DB) error {
+				for _, field := range cloneProgressFields {
+					if tx.Migrator().HasColumn(&Project{}, field) {
+						continue
+					}
+					if err := tx.Migrator().AddColumn(&Project{}, field); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				for _, field := range cloneProgressFields {
+					if err := tx.Migrator().DropColumn(&Project{}, field); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "20261016_create_ports_table",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&Port{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("ports")
+			},
+		},
 	}
 }
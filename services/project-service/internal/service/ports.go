@@ -0,0 +1,260 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Aadithya-J/code_nest/proto"
+	"github.com/Aadithya-J/code_nest/services/project-service/internal/db"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// agentPort is where the agent's own API listens. It is never shared
+// publicly: it exposes the whole workspace.
+const agentPort = 9000
+
+var portProtocols = map[string]bool{
+	"http":  true,
+	"https": true,
+}
+
+func validPort(port int32) bool {
+	return port > 0 && port <= 65535
+}
+
+func toPortInfo(p db.Port) *proto.PortInfo {
+	return &proto.PortInfo{
+		Port:      int32(p.Port),
+		Label:     p.Label,
+		Protocol:  p.Protocol,
+		Public:    p.Public,
+		Open:      p.Open,
+		Process:   p.Process,
+		UpdatedAt: p.UpdatedAt.Unix(),
+	}
+}
+
+// ownedProject loads a project and checks that userID owns it.
+func (s *Service) ownedProject(ctx context.Context, projectID, userID string) (*db.Project, error) {
+	var project db.Project
+	if err := s.db.WithContext(ctx).First(&project, "id = ?", projectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "project not found")
+		}
+		return nil, status.Errorf(codes.Internal, "fetch project: %v", err)
+	}
+	if project.UserID != userID {
+		return nil, status.Error(codes.PermissionDenied, "not owner")
+	}
+	return &project, nil
+}
+
+// findOrNewPort returns the stored port, or an unsaved one with defaults.
+func (s *Service) findOrNewPort(ctx context.Context, projectID string, port int) (db.Port, error) {
+	var p db.Port
+	err := s.db.WithContext(ctx).First(&p, "project_id = ? AND port = ?", projectID, port).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return db.Port{ProjectID: projectID, Port: port, Protocol: "http"}, nil
+	}
+	if err != nil {
+		return db.Port{}, status.Errorf(codes.Internal, "fetch port: %v", err)
+	}
+	return p, nil
+}
+
+// syncAtlasPort tells Atlas to forward an open port, with its visibility, or
+// to stop forwarding a closed one.
+func (s *Service) syncAtlasPort(ctx context.Context, atlasID string, p db.Port) error {
+	url := fmt.Sprintf("%s/sandboxes/%s/ports", s.atlasBase, atlasID)
+	var request *http.Request
+	if p.Open {
+		body, err := json.Marshal(map[string]interface{}{
+			"port":   p.Port,
+			"public": p.Public,
+		})
+		if err != nil {
+			return err
+		}
+		request, err = http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", "application/json")
+	} else {
+		var err error
+		request, err = http.NewRequestWithContext(ctx, http.MethodDelete, fmt.Sprintf("%s/%d", url, p.Port), nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	var resp *http.Response
+	err := s.cb.Call(func() error {
+		var err error
+		resp, err = s.httpCli.Do(request)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Atlas may already have dropped a port of a stopped sandbox
+	if resp.StatusCode >= 300 && !(resp.StatusCode == http.StatusNotFound && !p.Open) {
+		return fmt.Errorf("atlas error status: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ReportPort records a port opening or closing in a workspace, authenticated
+// by the workspace's callback token, and updates Atlas's forwarding.
+func (s *Service) ReportPort(ctx context.Context, req *proto.ReportPortRequest) (*proto.ReportPortResponse, error) {
+	if req.GetAtlasId() == "" || req.GetCallbackToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "atlas_id and callback_token required")
+	}
+	if !validPort(req.GetPort()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid port")
+	}
+	var project db.Project
+	if err := s.db.WithContext(ctx).First(&project, "atlas_id = ?", req.GetAtlasId()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "project not found")
+		}
+		return nil, status.Errorf(codes.Internal, "query project: %v", err)
+	}
+	if project.WebhookSecret == "" || project.WebhookSecret != req.GetCallbackToken() {
+		return nil, status.Error(codes.PermissionDenied, "invalid callback token")
+	}
+
+	p, err := s.findOrNewPort(ctx, project.ID, int(req.GetPort()))
+	if err != nil {
+		return nil, err
+	}
+	// The workspace config only fills in what the owner hasn't set
+	if p.ID == 0 && portProtocols[req.GetProtocol()] {
+		p.Protocol = req.GetProtocol()
+	}
+	if p.Label == "" {
+		p.Label = req.GetLabel()
+	}
+	p.Open = req.GetOpen()
+	p.Process = req.GetProcess()
+	if err := s.db.WithContext(ctx).Save(&p).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "save port: %v", err)
+	}
+	if err := s.syncAtlasPort(ctx, project.AtlasID, p); err != nil {
+		return nil, status.Errorf(codes.Internal, "atlas port update failed: %v", err)
+	}
+	return &proto.ReportPortResponse{Ok: true}, nil
+}
+
+// ListPorts returns a project's known ports, open ones first.
+func (s *Service) ListPorts(ctx context.Context, req *proto.ListPortsRequest) (*proto.ListPortsResponse, error) {
+	if req.GetProjectId() == "" || req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "project_id and user_id required")
+	}
+	project, err := s.ownedProject(ctx, req.GetProjectId(), req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	var ports []db.Port
+	if err := s.db.WithContext(ctx).Where("project_id = ?", project.ID).Order("open desc, port").Find(&ports).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "list ports: %v", err)
+	}
+	resp := &proto.ListPortsResponse{}
+	for _, p := range ports {
+		resp.Ports = append(resp.Ports, toPortInfo(p))
+	}
+	return resp, nil
+}
+
+// SetPortVisibility makes a port public, so anyone with the link can open
+// it, or private again. Ports can be shared before anything listens on them.
+func (s *Service) SetPortVisibility(ctx context.Context, req *proto.SetPortVisibilityRequest) (*proto.SetPortVisibilityResponse, error) {
+	if req.GetProjectId() == "" || req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "project_id and user_id required")
+	}
+	if !validPort(req.GetPort()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid port")
+	}
+	if req.GetPort() == agentPort && req.GetPublic() {
+		return nil, status.Error(codes.InvalidArgument, "the agent port cannot be made public")
+	}
+	project, err := s.ownedProject(ctx, req.GetProjectId(), req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.findOrNewPort(ctx, project.ID, int(req.GetPort()))
+	if err != nil {
+		return nil, err
+	}
+	p.Public = req.GetPublic()
+	if err := s.db.WithContext(ctx).Save(&p).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "save port: %v", err)
+	}
+	if p.Open && project.AtlasID != "" {
+		if err := s.syncAtlasPort(ctx, project.AtlasID, p); err != nil {
+			return nil, status.Errorf(codes.Internal, "atlas port update failed: %v", err)
+		}
+	}
+	return &proto.SetPortVisibilityResponse{Port: toPortInfo(p)}, nil
+}
+
+// UpdatePort sets a port's label and protocol.
+func (s *Service) UpdatePort(ctx context.Context, req *proto.UpdatePortRequest) (*proto.UpdatePortResponse, error) {
+	if req.GetProjectId() == "" || req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "project_id and user_id required")
+	}
+	if !validPort(req.GetPort()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid port")
+	}
+	if req.GetProtocol() != "" && !portProtocols[req.GetProtocol()] {
+		return nil, status.Error(codes.InvalidArgument, "protocol must be http or https")
+	}
+	if len(req.GetLabel()) > 100 {
+		return nil, status.Error(codes.InvalidArgument, "label too long")
+	}
+	project, err := s.ownedProject(ctx, req.GetProjectId(), req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.findOrNewPort(ctx, project.ID, int(req.GetPort()))
+	if err != nil {
+		return nil, err
+	}
+	p.Label = req.GetLabel()
+	if req.GetProtocol() != "" {
+		p.Protocol = req.GetProtocol()
+	}
+	if err := s.db.WithContext(ctx).Save(&p).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "save port: %v", err)
+	}
+	return &proto.UpdatePortResponse{Port: toPortInfo(p)}, nil
+}
+
+// IsPortPublic tells the gateway whether a workspace port may be served
+// without authentication.
+func (s *Service) IsPortPublic(ctx context.Context, req *proto.IsPortPublicRequest) (*proto.IsPortPublicResponse, error) {
+	if req.GetAtlasId() == "" || !validPort(req.GetPort()) {
+		return nil, status.Error(codes.InvalidArgument, "atlas_id and port required")
+	}
+	if req.GetPort() == agentPort {
+		return &proto.IsPortPublicResponse{Public: false}, nil
+	}
+	var public bool
+	err := s.db.WithContext(ctx).Model(&db.Port{}).
+		Select("ports.public").
+		Joins("JOIN projects ON projects.id = ports.project_id").
+		Where("projects.atlas_id = ? AND ports.port = ?", req.GetAtlasId(), req.GetPort()).
+		Limit(1).
+		Scan(&public).Error
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "query port: %v", err)
+	}
+	return &proto.IsPortPublicResponse{Public: public}, nil
+}
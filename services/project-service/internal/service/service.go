@@ -9,6 +9,8 @@ import (
 	"io"
 	"math/rand"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -94,6 +96,61 @@ type Service struct {
 	gateway   string
 	httpCli   *http.Client
 	cb        *CircuitBreaker
+
+	agentJWKSURL      string
+	agentAuthDisabled bool
+	allowedOrigins    string
+	fileDenylist      string
+}
+
+// Auto-save policies understood by the agent. shadow keeps uncommitted work
+// on a side branch so the user's branch history stays clean.
+const defaultAutosavePolicy = "shadow"
+
+var autosavePolicies = map[string]bool{
+	"off":      true,
+	"shadow":   true,
+	"snapshot": true,
+	"wip":      true,
+}
+
+// Shutdown push outcomes reported by the agent.
+var syncStatuses = map[string]bool{
+	"PUSHED":   true,
+	"RECOVERY": true,
+	"FAILED":   true,
+	"SKIPPED":  true,
+}
+
+// Clone phases reported by the agent while a workspace starts.
+var cloneProgressPhases = map[string]bool{
+	"starting":    true,
+	"counting":    true,
+	"compressing": true,
+	"receiving":   true,
+	"resolving":   true,
+	"checkout":    true,
+	"done":        true,
+	"error":       true,
+}
+
+// SetAgentAuth configures how started agents verify workspace tokens: the
+// JWKS they fetch signing keys from, or no verification at all.
+func (s *Service) SetAgentAuth(jwksURL string, disabled bool) {
+	s.agentJWKSURL = jwksURL
+	s.agentAuthDisabled = disabled
+}
+
+// SetAllowedOrigins sets the origins, in the gateway's ALLOWED_ORIGINS
+// format, that started agents accept browser requests from.
+func (s *Service) SetAllowedOrigins(origins string) {
+	s.allowedOrigins = origins
+}
+
+// SetFileDenylist sets the FILE_DENYLIST patterns started agents refuse
+// file access to. Empty leaves the agent's default list in place.
+func (s *Service) SetFileDenylist(patterns string) {
+	s.fileDenylist = patterns
 }
 
 // generateAtlasID creates a consistent Atlas ID for a project
@@ -149,14 +206,30 @@ func (s *Service) CreateProject(ctx context.Context, req *proto.CreateProjectReq
 	if !strings.HasPrefix(req.GetRepoUrl(), "https://github.com/") && !strings.HasPrefix(req.GetRepoUrl(), "git@github.com:") {
 		return nil, status.Error(codes.InvalidArgument, "repo_url must be a GitHub repository")
 	}
+	policy := req.GetAutosavePolicy()
+	if policy == "" {
+		policy = defaultAutosavePolicy
+	}
+	if !autosavePolicies[policy] {
+		return nil, status.Error(codes.InvalidArgument, "autosave_policy must be one of off, shadow, snapshot, wip")
+	}
+	if !validRef(req.GetDefaultBranch()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid default_branch")
+	}
+	if err := validateCloneOptions(req.GetClone()); err != nil {
+		return nil, err
+	}
 	id := uuid.New().String()
 	project := db.Project{
-		ID:      id,
-		Name:    req.GetName(),
-		UserID:  req.GetUserId(),
-		RepoURL: req.GetRepoUrl(),
-		Status:  "STOPPED",
-	}
+		ID:             id,
+		Name:           req.GetName(),
+		UserID:         req.GetUserId(),
+		RepoURL:        req.GetRepoUrl(),
+		Status:         "STOPPED",
+		AutosavePolicy: policy,
+		DefaultBranch:  req.GetDefaultBranch(),
+	}
+	applyCloneOptions(&project, req.GetClone())
 	// Precompute atlas id for consistency.
 	project.AtlasID = s.generateAtlasID(id)
 	if err := s.db.WithContext(ctx).Create(&project).Error; err != nil {
@@ -170,6 +243,16 @@ func (s *Service) StartWorkspace(ctx context.Context, req *proto.StartWorkspaceR
 	if req.GetProjectId() == "" || req.GetUserId() == "" {
 		return nil, status.Error(codes.InvalidArgument, "project_id and user_id required")
 	}
+	if !validRef(req.GetRef()) {
+		return nil, status.Error(codes.InvalidArgument, "invalid ref")
+	}
+	if req.GetPullRequest() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid pull_request")
+	}
+	// An agent without a key URL would reject every request
+	if s.agentJWKSURL == "" && !s.agentAuthDisabled {
+		return nil, status.Error(codes.FailedPrecondition, "AGENT_JWKS_URL is not configured")
+	}
 
 	lockKey := fmt.Sprintf("lock:project:%s", req.GetProjectId())
 	ok, err := s.rdb.SetNX(ctx, lockKey, "1", 30*time.Second).Result()
@@ -192,7 +275,9 @@ func (s *Service) StartWorkspace(ctx context.Context, req *proto.StartWorkspaceR
 		return nil, status.Error(codes.PermissionDenied, "not owner")
 	}
 
-	if project.Status == "RUNNING" || project.Status == "STARTING" {
+	// A workspace whose setup failed is still up; starting it again would
+	// only create a second sandbox
+	if project.Status == "RUNNING" || project.Status == "STARTING" || project.Status == "SETUP_FAILED" {
 		return &proto.StartWorkspaceResponse{
 			Ok:      true,
 			Status:  toStatusEnum(project.Status),
@@ -203,34 +288,82 @@ func (s *Service) StartWorkspace(ctx context.Context, req *proto.StartWorkspaceR
 	callbackToken := uuid.New().String()
 	project.WebhookSecret = callbackToken
 	project.Status = "STARTING"
+	// Progress from the previous start no longer applies
+	project.CloneProgressPhase, project.CloneProgressMessage, project.CloneProgressAt = "", "", nil
+	project.CloneProgressPercent, project.CloneProgressOverall = 0, 0
 	if project.AtlasID == "" {
 		project.AtlasID = s.generateAtlasID(project.ID)
 	}
 	if err := s.db.WithContext(ctx).Save(&project).Error; err != nil {
 		return nil, status.Errorf(codes.Internal, "save project: %v", err)
 	}
+	// The new sandbox reports its ports as they open
+	if err := s.db.WithContext(ctx).Model(&db.Port{}).Where("project_id = ?", project.ID).Update("open", false).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "reset ports: %v", err)
+	}
 
 	git, err := s.auth.GenerateRepoToken(ctx, &proto.GenerateRepoTokenRequest{UserId: req.GetUserId()})
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "get repo token: %v", err)
 	}
 
+	env := map[string]string{
+		"GIT_REPO":             project.RepoURL,
+		"GIT_TOKEN":            git.GetToken(),
+		"GIT_USER_NAME":        git.GetUsername(),
+		"AGENT_CALLBACK_URL":   s.gateway + "/api/internal/webhook",
+		"AGENT_CALLBACK_TOKEN": callbackToken,
+		"AGENT_SYNC_URL":       s.gateway + "/api/internal/sync",
+		"AGENT_PROGRESS_URL":   s.gateway + "/api/internal/progress",
+		"AGENT_PORTS_URL":      s.gateway + "/api/internal/ports",
+		"ATLAS_ID":             project.AtlasID,
+		"AUTOSAVE_POLICY":      project.AutosavePolicy,
+	}
+	// The agent checks out a pull request, else the requested ref, else the
+	// project's default branch
+	switch {
+	case req.GetPullRequest() > 0:
+		env["GIT_PR"] = strconv.Itoa(int(req.GetPullRequest()))
+	case req.GetRef() != "":
+		env["GIT_REF"] = req.GetRef()
+	case project.DefaultBranch != "":
+		env["GIT_REF"] = project.DefaultBranch
+	}
+	if project.CloneDepth > 0 {
+		env["GIT_CLONE_DEPTH"] = strconv.Itoa(project.CloneDepth)
+	}
+	if project.CloneFilter != "" {
+		env["GIT_CLONE_FILTER"] = project.CloneFilter
+	}
+	if project.SparsePaths != "" {
+		env["GIT_SPARSE_PATHS"] = project.SparsePaths
+	}
+	if project.PersistWorkspace {
+		env["GIT_PERSIST_WORKSPACE"] = "true"
+	}
+	if s.agentJWKSURL != "" {
+		env["AGENT_JWKS_URL"] = s.agentJWKSURL
+	}
+	if s.agentAuthDisabled {
+		env["AGENT_AUTH_DISABLED"] = "true"
+	}
+	if s.allowedOrigins != "" {
+		env["ALLOWED_ORIGINS"] = s.allowedOrigins
+	}
+	if s.fileDenylist != "" {
+		env["FILE_DENYLIST"] = s.fileDenylist
+	}
+
 	payload := map[string]interface{}{
 		"id":    project.AtlasID,
 		"image": "aadithya1/ide-agent:latest",
 		"auth_config": map[string]interface{}{
 			"enabled":    true,
 			"verify_url": s.gateway + "/auth/verify",
+			// Passed on to the sandbox; the agent checks the workspace token
+			"forward_headers": []string{"X-User-Id", "X-Workspace-Token"},
 		},
-		"env": map[string]string{
-			"GIT_REPO":             project.RepoURL,
-			"GIT_TOKEN":            git.GetToken(),
-			"GIT_USER_NAME":        git.GetUsername(),
-			"AGENT_CALLBACK_URL":   s.gateway + "/api/internal/webhook",
-			"AGENT_CALLBACK_TOKEN": callbackToken,
-			"ATLAS_ID":             project.AtlasID,
-			"ATLAS_BASE_URL":       s.atlasBase,
-		},
+		"env": env,
 	}
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -288,6 +421,9 @@ func (s *Service) StopWorkspace(ctx context.Context, req *proto.StopWorkspaceReq
 	s.db.WithContext(ctx).Model(&db.Project{}).
 		Where("id = ?", req.GetProjectId()).
 		Update("status", "STOPPED")
+	s.db.WithContext(ctx).Model(&db.Port{}).
+		Where("project_id = ?", req.GetProjectId()).
+		Update("open", false)
 
 	return &proto.StopWorkspaceResponse{Ok: true}, nil
 }
@@ -313,6 +449,8 @@ func (s *Service) WebhookUpdate(ctx context.Context, req *proto.WebhookUpdateReq
 		project.Status = "RUNNING"
 	case "ERROR":
 		project.Status = "ERROR"
+	case "SETUP_FAILED":
+		project.Status = "SETUP_FAILED"
 	default:
 		return nil, status.Error(codes.InvalidArgument, "invalid status")
 	}
@@ -343,6 +481,8 @@ func (s *Service) VerifyAndComplete(ctx context.Context, req *proto.VerifyAndCom
 		project.Status = "RUNNING"
 	case "ERROR":
 		project.Status = "ERROR"
+	case "SETUP_FAILED":
+		project.Status = "SETUP_FAILED"
 	default:
 		return nil, status.Error(codes.InvalidArgument, "invalid status")
 	}
@@ -367,6 +507,227 @@ func (s *Service) IsOwner(ctx context.Context, req *proto.IsOwnerRequest) (*prot
 	return &proto.IsOwnerResponse{IsOwner: project.UserID == req.GetUserId()}, nil
 }
 
+// SetAutosavePolicy changes how the agent saves uncommitted work. It takes
+// effect the next time the workspace starts.
+func (s *Service) SetAutosavePolicy(ctx context.Context, req *proto.SetAutosavePolicyRequest) (*proto.SetAutosavePolicyResponse, error) {
+	if req.GetProjectId() == "" || req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "project_id and user_id required")
+	}
+	if !autosavePolicies[req.GetPolicy()] {
+		return nil, status.Error(codes.InvalidArgument, "policy must be one of off, shadow, snapshot, wip")
+	}
+	var project db.Project
+	if err := s.db.WithContext(ctx).First(&project, "id = ?", req.GetProjectId()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "project not found")
+		}
+		return nil, status.Errorf(codes.Internal, "fetch project: %v", err)
+	}
+	if project.UserID != req.GetUserId() {
+		return nil, status.Error(codes.PermissionDenied, "not owner")
+	}
+	if err := s.db.WithContext(ctx).Model(&project).Update("autosave_policy", req.GetPolicy()).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "update project: %v", err)
+	}
+	return &proto.SetAutosavePolicyResponse{Ok: true, Policy: req.GetPolicy()}, nil
+}
+
+// ReportSync records where the agent pushed the workspace's commits on
+// shutdown. Like VerifyAndComplete it is authenticated by the callback token.
+func (s *Service) ReportSync(ctx context.Context, req *proto.ReportSyncRequest) (*proto.ReportSyncResponse, error) {
+	if req.GetAtlasId() == "" || req.GetCallbackToken() == "" || req.GetResult() == nil {
+		return nil, status.Error(codes.InvalidArgument, "atlas_id, callback_token and result required")
+	}
+	result := req.GetResult()
+	if !syncStatuses[result.GetStatus()] {
+		return nil, status.Error(codes.InvalidArgument, "invalid sync status")
+	}
+	var project db.Project
+	if err := s.db.WithContext(ctx).First(&project, "atlas_id = ?", req.GetAtlasId()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "project not found")
+		}
+		return nil, status.Errorf(codes.Internal, "query project: %v", err)
+	}
+	if project.WebhookSecret == "" || project.WebhookSecret != req.GetCallbackToken() {
+		return nil, status.Error(codes.PermissionDenied, "invalid callback token")
+	}
+	now := time.Now()
+	if err := s.db.WithContext(ctx).Model(&project).Updates(map[string]interface{}{
+		"last_sync_status":  result.GetStatus(),
+		"last_sync_branch":  result.GetBranch(),
+		"last_sync_ref":     result.GetRemoteRef(),
+		"last_sync_commit":  result.GetCommit(),
+		"last_sync_message": result.GetMessage(),
+		"last_sync_at":      &now,
+	}).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "update project: %v", err)
+	}
+	return &proto.ReportSyncResponse{Ok: true}, nil
+}
+
+// GetLastSync returns the last shutdown push outcome for a project.
+func (s *Service) GetLastSync(ctx context.Context, req *proto.GetLastSyncRequest) (*proto.GetLastSyncResponse, error) {
+	if req.GetProjectId() == "" || req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "project_id and user_id required")
+	}
+	var project db.Project
+	if err := s.db.WithContext(ctx).First(&project, "id = ?", req.GetProjectId()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "project not found")
+		}
+		return nil, status.Errorf(codes.Internal, "fetch project: %v", err)
+	}
+	if project.UserID != req.GetUserId() {
+		return nil, status.Error(codes.PermissionDenied, "not owner")
+	}
+	if project.LastSyncAt == nil {
+		return &proto.GetLastSyncResponse{}, nil
+	}
+	return &proto.GetLastSyncResponse{Result: &proto.SyncResult{
+		Status:     project.LastSyncStatus,
+		Branch:     project.LastSyncBranch,
+		RemoteRef:  project.LastSyncRef,
+		Commit:     project.LastSyncCommit,
+		Message:    project.LastSyncMessage,
+		ReportedAt: project.LastSyncAt.Unix(),
+	}}, nil
+}
+
+// SetCloneOptions changes the clone strategy used from the next start.
+func (s *Service) SetCloneOptions(ctx context.Context, req *proto.SetCloneOptionsRequest) (*proto.SetCloneOptionsResponse, error) {
+	if req.GetProjectId() == "" || req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "project_id and user_id required")
+	}
+	if err := validateCloneOptions(req.GetClone()); err != nil {
+		return nil, err
+	}
+	var project db.Project
+	if err := s.db.WithContext(ctx).First(&project, "id = ?", req.GetProjectId()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "project not found")
+		}
+		return nil, status.Errorf(codes.Internal, "fetch project: %v", err)
+	}
+	if project.UserID != req.GetUserId() {
+		return nil, status.Error(codes.PermissionDenied, "not owner")
+	}
+	applyCloneOptions(&project, req.GetClone())
+	if err := s.db.WithContext(ctx).Model(&project).Updates(map[string]interface{}{
+		"clone_depth":       project.CloneDepth,
+		"clone_filter":      project.CloneFilter,
+		"sparse_paths":      project.SparsePaths,
+		"persist_workspace": project.PersistWorkspace,
+	}).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "update project: %v", err)
+	}
+	return &proto.SetCloneOptionsResponse{Ok: true}, nil
+}
+
+// ReportProgress stores the latest clone progress sent by the agent,
+// authenticated by the workspace's callback token.
+func (s *Service) ReportProgress(ctx context.Context, req *proto.ReportProgressRequest) (*proto.ReportProgressResponse, error) {
+	if req.GetAtlasId() == "" || req.GetCallbackToken() == "" || req.GetProgress() == nil {
+		return nil, status.Error(codes.InvalidArgument, "atlas_id, callback_token and progress required")
+	}
+	progress := req.GetProgress()
+	if !cloneProgressPhases[progress.GetPhase()] {
+		return nil, status.Error(codes.InvalidArgument, "invalid clone phase")
+	}
+	if progress.GetPercent() < 0 || progress.GetPercent() > 100 || progress.GetOverall() < 0 || progress.GetOverall() > 100 {
+		return nil, status.Error(codes.InvalidArgument, "percent must be between 0 and 100")
+	}
+	var project db.Project
+	if err := s.db.WithContext(ctx).First(&project, "atlas_id = ?", req.GetAtlasId()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "project not found")
+		}
+		return nil, status.Errorf(codes.Internal, "query project: %v", err)
+	}
+	if project.WebhookSecret == "" || project.WebhookSecret != req.GetCallbackToken() {
+		return nil, status.Error(codes.PermissionDenied, "invalid callback token")
+	}
+	now := time.Now()
+	if err := s.db.WithContext(ctx).Model(&project).Updates(map[string]interface{}{
+		"clone_progress_phase":   progress.GetPhase(),
+		"clone_progress_percent": int(progress.GetPercent()),
+		"clone_progress_overall": int(progress.GetOverall()),
+		"clone_progress_message": progress.GetMessage(),
+		"clone_progress_at":      &now,
+	}).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "update project: %v", err)
+	}
+	return &proto.ReportProgressResponse{Ok: true}, nil
+}
+
+// GetCloneProgress returns the clone progress of the current start.
+func (s *Service) GetCloneProgress(ctx context.Context, req *proto.GetCloneProgressRequest) (*proto.GetCloneProgressResponse, error) {
+	if req.GetProjectId() == "" || req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "project_id and user_id required")
+	}
+	var project db.Project
+	if err := s.db.WithContext(ctx).First(&project, "id = ?", req.GetProjectId()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "project not found")
+		}
+		return nil, status.Errorf(codes.Internal, "fetch project: %v", err)
+	}
+	if project.UserID != req.GetUserId() {
+		return nil, status.Error(codes.PermissionDenied, "not owner")
+	}
+	if project.CloneProgressAt == nil {
+		return &proto.GetCloneProgressResponse{}, nil
+	}
+	return &proto.GetCloneProgressResponse{Progress: &proto.CloneProgress{
+		Phase:     project.CloneProgressPhase,
+		Percent:   int32(project.CloneProgressPercent),
+		Overall:   int32(project.CloneProgressOverall),
+		Message:   project.CloneProgressMessage,
+		UpdatedAt: project.CloneProgressAt.Unix(),
+	}}, nil
+}
+
+var cloneFilterRe = regexp.MustCompile(`^(blob:none|tree:0|blob:limit=[0-9]+[kmg]?)$`)
+
+// validateCloneOptions checks a clone strategy; nil means a plain clone.
+func validateCloneOptions(opts *proto.CloneOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.GetDepth() < 0 {
+		return status.Error(codes.InvalidArgument, "clone depth must not be negative")
+	}
+	if opts.GetFilter() != "" && !cloneFilterRe.MatchString(opts.GetFilter()) {
+		return status.Error(codes.InvalidArgument, "clone filter must be blob:none, tree:0 or blob:limit=<n>")
+	}
+	for _, p := range opts.GetSparsePaths() {
+		if p == "" || strings.HasPrefix(p, "/") || strings.HasPrefix(p, "-") || strings.Contains(p, "..") || strings.Contains(p, ",") {
+			return status.Errorf(codes.InvalidArgument, "invalid sparse path %q", p)
+		}
+	}
+	return nil
+}
+
+func applyCloneOptions(project *db.Project, opts *proto.CloneOptions) {
+	project.CloneDepth = int(opts.GetDepth())
+	project.CloneFilter = opts.GetFilter()
+	project.SparsePaths = strings.Join(opts.GetSparsePaths(), ",")
+	project.PersistWorkspace = opts.GetPersistWorkspace()
+}
+
+// validRef loosely checks a git branch, tag or commit name. The agent runs
+// git check-ref-format before using it; this just rejects obvious junk
+// early. Empty is valid and means "not set".
+func validRef(ref string) bool {
+	if ref == "" {
+		return true
+	}
+	if len(ref) > 255 || strings.HasPrefix(ref, "-") || strings.Contains(ref, "..") {
+		return false
+	}
+	return !strings.ContainsAny(ref, " ~^:?*[\\\t\n")
+}
+
 func randomSecret(n int) string {
 	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	res := make([]byte, n)
@@ -386,6 +747,8 @@ func toStatusEnum(status string) proto.ProjectStatus {
 		return proto.ProjectStatus_RUNNING
 	case "ERROR":
 		return proto.ProjectStatus_ERROR
+	case "SETUP_FAILED":
+		return proto.ProjectStatus_SETUP_FAILED
 	default:
 		return proto.ProjectStatus_PROJECT_STATUS_UNSPECIFIED
 	}
@@ -2,12 +2,17 @@ package service
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
@@ -16,23 +21,13 @@ import (
 )
 
 func TestService_CreateProject(t *testing.T) {
-	gormDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	require.NoError(t, err)
-
-	err = db.AutoMigrate(gormDB)
-	require.NoError(t, err)
-
-	// Create a mock Redis client
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
-	})
-
-	service := New(gormDB, redisClient, &mockAuthClient{}, "http://localhost:8080", "http://localhost:3000")
+	service, gormDB := newTestService(t, "http://localhost:8080")
 
 	req := &proto.CreateProjectRequest{
-		UserId:  uuid.New().String(),
-		Name:    "Test Project",
-		RepoUrl: "https://github.com/test/repo.git",
+		UserId:        uuid.New().String(),
+		Name:          "Test Project",
+		RepoUrl:       "https://github.com/test/repo.git",
+		DefaultBranch: "develop",
 	}
 
 	resp, err := service.CreateProject(context.Background(), req)
@@ -45,21 +40,60 @@ func TestService_CreateProject(t *testing.T) {
 	require.Equal(t, req.Name, project.Name)
 	require.Equal(t, req.RepoUrl, project.RepoURL)
 	require.Equal(t, "STOPPED", project.Status)
+	require.Equal(t, "shadow", project.AutosavePolicy)
+	require.Equal(t, "develop", project.DefaultBranch)
+
+	req.DefaultBranch = "--upload-pack=evil"
+	_, err = service.CreateProject(context.Background(), req)
+	require.Error(t, err)
 }
 
-func TestService_IsOwner(t *testing.T) {
-	gormDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+func TestService_SetAutosavePolicy(t *testing.T) {
+	service, gormDB := newTestService(t, "http://localhost:8080")
+
+	userID := uuid.New().String()
+	project := db.Project{
+		ID:             uuid.New().String(),
+		Name:           "Test Project",
+		UserID:         userID,
+		RepoURL:        "https://github.com/test/repo.git",
+		Status:         "STOPPED",
+		AtlasID:        "ws-" + uuid.New().String(),
+		AutosavePolicy: "shadow",
+	}
+	err := gormDB.Create(&project).Error
 	require.NoError(t, err)
 
-	err = db.AutoMigrate(gormDB)
+	resp, err := service.SetAutosavePolicy(context.Background(), &proto.SetAutosavePolicyRequest{
+		ProjectId: project.ID,
+		UserId:    userID,
+		Policy:    "wip",
+	})
 	require.NoError(t, err)
+	require.True(t, resp.Ok)
 
-	// Create a mock Redis client
-	redisClient := redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
+	var updated db.Project
+	err = gormDB.First(&updated, "id = ?", project.ID).Error
+	require.NoError(t, err)
+	require.Equal(t, "wip", updated.AutosavePolicy)
+
+	// Unknown policies and other users are rejected
+	_, err = service.SetAutosavePolicy(context.Background(), &proto.SetAutosavePolicyRequest{
+		ProjectId: project.ID,
+		UserId:    userID,
+		Policy:    "sometimes",
+	})
+	require.Error(t, err)
+	_, err = service.SetAutosavePolicy(context.Background(), &proto.SetAutosavePolicyRequest{
+		ProjectId: project.ID,
+		UserId:    uuid.New().String(),
+		Policy:    "off",
 	})
+	require.Error(t, err)
+}
 
-	service := New(gormDB, redisClient, &mockAuthClient{}, "http://localhost:8080", "http://localhost:3000")
+func TestService_IsOwner(t *testing.T) {
+	service, gormDB := newTestService(t, "http://localhost:8080")
 
 	userID := uuid.New().String()
 	project := db.Project{
@@ -70,7 +104,7 @@ func TestService_IsOwner(t *testing.T) {
 		Status:  "STOPPED",
 		AtlasID: "ws-" + uuid.New().String(),
 	}
-	err = gormDB.Create(&project).Error
+	err := gormDB.Create(&project).Error
 	require.NoError(t, err)
 
 	req := &proto.IsOwnerRequest{
@@ -92,9 +126,310 @@ func TestService_IsOwner(t *testing.T) {
 	require.False(t, resp.IsOwner)
 }
 
+func TestService_ReportSync(t *testing.T) {
+	service, gormDB := newTestService(t, "http://localhost:8080")
+
+	userID := uuid.New().String()
+	project := db.Project{
+		ID:            uuid.New().String(),
+		Name:          "Test Project",
+		UserID:        userID,
+		RepoURL:       "https://github.com/test/repo.git",
+		Status:        "RUNNING",
+		AtlasID:       "ws-" + uuid.New().String(),
+		WebhookSecret: "secret",
+	}
+	err := gormDB.Create(&project).Error
+	require.NoError(t, err)
+
+	// Nothing reported yet
+	last, err := service.GetLastSync(context.Background(), &proto.GetLastSyncRequest{ProjectId: project.ID, UserId: userID})
+	require.NoError(t, err)
+	require.Nil(t, last.Result)
+
+	_, err = service.ReportSync(context.Background(), &proto.ReportSyncRequest{
+		AtlasId:       project.AtlasID,
+		CallbackToken: "wrong",
+		Result:        &proto.SyncResult{Status: "PUSHED"},
+	})
+	require.Error(t, err)
+
+	_, err = service.ReportSync(context.Background(), &proto.ReportSyncRequest{
+		AtlasId:       project.AtlasID,
+		CallbackToken: "secret",
+		Result: &proto.SyncResult{
+			Status:    "RECOVERY",
+			Branch:    "main",
+			RemoteRef: "origin/codenest/recovery-" + project.AtlasID,
+			Commit:    "abc123",
+		},
+	})
+	require.NoError(t, err)
+
+	last, err = service.GetLastSync(context.Background(), &proto.GetLastSyncRequest{ProjectId: project.ID, UserId: userID})
+	require.NoError(t, err)
+	require.NotNil(t, last.Result)
+	require.Equal(t, "RECOVERY", last.Result.Status)
+	require.Equal(t, "origin/codenest/recovery-"+project.AtlasID, last.Result.RemoteRef)
+	require.NotZero(t, last.Result.ReportedAt)
+}
+
+func TestService_SetCloneOptions(t *testing.T) {
+	service, gormDB := newTestService(t, "http://localhost:8080")
+
+	userID := uuid.New().String()
+	project := db.Project{
+		ID:      uuid.New().String(),
+		Name:    "Test Project",
+		UserID:  userID,
+		RepoURL: "https://github.com/test/repo.git",
+		Status:  "STOPPED",
+		AtlasID: "ws-" + uuid.New().String(),
+	}
+	err := gormDB.Create(&project).Error
+	require.NoError(t, err)
+
+	_, err = service.SetCloneOptions(context.Background(), &proto.SetCloneOptionsRequest{
+		ProjectId: project.ID,
+		UserId:    userID,
+		Clone: &proto.CloneOptions{
+			Depth:            1,
+			Filter:           "blob:none",
+			SparsePaths:      []string{"services/api", "docs"},
+			PersistWorkspace: true,
+		},
+	})
+	require.NoError(t, err)
+
+	var updated db.Project
+	err = gormDB.First(&updated, "id = ?", project.ID).Error
+	require.NoError(t, err)
+	require.Equal(t, 1, updated.CloneDepth)
+	require.Equal(t, "blob:none", updated.CloneFilter)
+	require.Equal(t, "services/api,docs", updated.SparsePaths)
+	require.True(t, updated.PersistWorkspace)
+
+	_, err = service.SetCloneOptions(context.Background(), &proto.SetCloneOptionsRequest{
+		ProjectId: project.ID,
+		UserId:    userID,
+		Clone:     &proto.CloneOptions{Filter: "--upload-pack=evil"},
+	})
+	require.Error(t, err)
+	_, err = service.SetCloneOptions(context.Background(), &proto.SetCloneOptionsRequest{
+		ProjectId: project.ID,
+		UserId:    userID,
+		Clone:     &proto.CloneOptions{SparsePaths: []string{"../etc"}},
+	})
+	require.Error(t, err)
+}
+
+func TestService_StartWorkspace_NoJWKSURL(t *testing.T) {
+	service, _ := newTestService(t, "http://localhost:8080")
+
+	req := &proto.StartWorkspaceRequest{ProjectId: uuid.New().String(), UserId: uuid.New().String()}
+	_, err := service.StartWorkspace(context.Background(), req)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// With agent auth disabled the request gets past the check
+	service.SetAgentAuth("", true)
+	_, err = service.StartWorkspace(context.Background(), req)
+	require.NotEqual(t, codes.FailedPrecondition, status.Code(err))
+}
+
+// newTestService returns a Service backed by an in-memory sqlite database.
+// The models default their ids to Postgres' gen_random_uuid(), which sqlite
+// can't parse, so the parsed schema gets a sqlite expression instead before
+// migrating.
+func newTestService(t *testing.T, atlasBase string) (*Service, *gorm.DB) {
+	t.Helper()
+	gormDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	stmt := &gorm.Statement{DB: gormDB}
+	require.NoError(t, stmt.Parse(&db.Project{}))
+	stmt.Schema.LookUpField("ID").DefaultValue = "(lower(hex(randomblob(16))))"
+	require.NoError(t, db.AutoMigrate(gormDB))
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	return New(gormDB, redisClient, &mockAuthClient{}, atlasBase, "http://localhost:3000"), gormDB
+}
+
 // mockAuthClient implements AuthClient for testing
 type mockAuthClient struct{}
 
 func (m *mockAuthClient) GenerateRepoToken(ctx context.Context, in *proto.GenerateRepoTokenRequest, opts ...grpc.CallOption) (*proto.GenerateRepoTokenResponse, error) {
 	return &proto.GenerateRepoTokenResponse{Token: "mock-token"}, nil
 }
+
+func TestService_ReportProgress(t *testing.T) {
+	service, gormDB := newTestService(t, "http://localhost:8080")
+
+	userID := uuid.New().String()
+	project := db.Project{
+		ID:            uuid.New().String(),
+		Name:          "Test Project",
+		UserID:        userID,
+		RepoURL:       "https://github.com/test/repo.git",
+		Status:        "STARTING",
+		AtlasID:       "ws-" + uuid.New().String(),
+		WebhookSecret: "secret",
+	}
+	err := gormDB.Create(&project).Error
+	require.NoError(t, err)
+
+	// Nothing reported yet
+	progress, err := service.GetCloneProgress(context.Background(), &proto.GetCloneProgressRequest{ProjectId: project.ID, UserId: userID})
+	require.NoError(t, err)
+	require.Nil(t, progress.Progress)
+
+	_, err = service.ReportProgress(context.Background(), &proto.ReportProgressRequest{
+		AtlasId:       project.AtlasID,
+		CallbackToken: "wrong",
+		Progress:      &proto.CloneProgress{Phase: "receiving", Percent: 10, Overall: 17},
+	})
+	require.Error(t, err)
+
+	_, err = service.ReportProgress(context.Background(), &proto.ReportProgressRequest{
+		AtlasId:       project.AtlasID,
+		CallbackToken: "secret",
+		Progress:      &proto.CloneProgress{Phase: "unpacking", Percent: 10},
+	})
+	require.Error(t, err)
+
+	_, err = service.ReportProgress(context.Background(), &proto.ReportProgressRequest{
+		AtlasId:       project.AtlasID,
+		CallbackToken: "secret",
+		Progress:      &proto.CloneProgress{Phase: "receiving", Percent: 50, Overall: 45},
+	})
+	require.NoError(t, err)
+
+	progress, err = service.GetCloneProgress(context.Background(), &proto.GetCloneProgressRequest{ProjectId: project.ID, UserId: userID})
+	require.NoError(t, err)
+	require.NotNil(t, progress.Progress)
+	require.Equal(t, "receiving", progress.Progress.Phase)
+	require.EqualValues(t, 50, progress.Progress.Percent)
+	require.EqualValues(t, 45, progress.Progress.Overall)
+	require.NotZero(t, progress.Progress.UpdatedAt)
+
+	_, err = service.GetCloneProgress(context.Background(), &proto.GetCloneProgressRequest{ProjectId: project.ID, UserId: uuid.New().String()})
+	require.Error(t, err)
+}
+
+func TestService_VerifyAndComplete_SetupFailed(t *testing.T) {
+	service, gormDB := newTestService(t, "http://localhost:8080")
+
+	project := db.Project{
+		ID:            uuid.New().String(),
+		Name:          "Test Project",
+		UserID:        uuid.New().String(),
+		RepoURL:       "https://github.com/test/repo.git",
+		Status:        "STARTING",
+		AtlasID:       "ws-" + uuid.New().String(),
+		WebhookSecret: "secret",
+	}
+	err := gormDB.Create(&project).Error
+	require.NoError(t, err)
+
+	resp, err := service.VerifyAndComplete(context.Background(), &proto.VerifyAndCompleteRequest{
+		AtlasId:       project.AtlasID,
+		CallbackToken: "secret",
+		Status:        "SETUP_FAILED",
+	})
+	require.NoError(t, err)
+	require.Equal(t, proto.ProjectStatus_SETUP_FAILED, resp.Status)
+
+	var updated db.Project
+	err = gormDB.First(&updated, "id = ?", project.ID).Error
+	require.NoError(t, err)
+	require.Equal(t, "SETUP_FAILED", updated.Status)
+}
+
+func TestService_Ports(t *testing.T) {
+	// Record what Atlas is asked to forward
+	var atlasMu sync.Mutex
+	var atlasCalls []string
+	atlas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atlasMu.Lock()
+		atlasCalls = append(atlasCalls, r.Method+" "+r.URL.Path)
+		atlasMu.Unlock()
+	}))
+	defer atlas.Close()
+
+	service, gormDB := newTestService(t, atlas.URL)
+
+	userID := uuid.New().String()
+	project := db.Project{
+		ID:            uuid.New().String(),
+		Name:          "Test Project",
+		UserID:        userID,
+		RepoURL:       "https://github.com/test/repo.git",
+		Status:        "RUNNING",
+		AtlasID:       "ws-" + uuid.New().String(),
+		WebhookSecret: "secret",
+	}
+	err := gormDB.Create(&project).Error
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = service.ReportPort(ctx, &proto.ReportPortRequest{AtlasId: project.AtlasID, CallbackToken: "wrong", Port: 5173, Open: true})
+	require.Error(t, err)
+
+	_, err = service.ReportPort(ctx, &proto.ReportPortRequest{
+		AtlasId:       project.AtlasID,
+		CallbackToken: "secret",
+		Port:          5173,
+		Open:          true,
+		Process:       "node",
+		Label:         "Vite",
+	})
+	require.NoError(t, err)
+
+	ports, err := service.ListPorts(ctx, &proto.ListPortsRequest{ProjectId: project.ID, UserId: userID})
+	require.NoError(t, err)
+	require.Len(t, ports.Ports, 1)
+	require.Equal(t, "Vite", ports.Ports[0].Label)
+	require.Equal(t, "http", ports.Ports[0].Protocol)
+	require.True(t, ports.Ports[0].Open)
+	require.False(t, ports.Ports[0].Public)
+
+	public, err := service.IsPortPublic(ctx, &proto.IsPortPublicRequest{AtlasId: project.AtlasID, Port: 5173})
+	require.NoError(t, err)
+	require.False(t, public.Public)
+
+	// Only the owner can share
+	_, err = service.SetPortVisibility(ctx, &proto.SetPortVisibilityRequest{ProjectId: project.ID, UserId: uuid.New().String(), Port: 5173, Public: true})
+	require.Error(t, err)
+	_, err = service.SetPortVisibility(ctx, &proto.SetPortVisibilityRequest{ProjectId: project.ID, UserId: userID, Port: 9000, Public: true})
+	require.Error(t, err)
+
+	shared, err := service.SetPortVisibility(ctx, &proto.SetPortVisibilityRequest{ProjectId: project.ID, UserId: userID, Port: 5173, Public: true})
+	require.NoError(t, err)
+	require.True(t, shared.Port.Public)
+
+	public, err = service.IsPortPublic(ctx, &proto.IsPortPublicRequest{AtlasId: project.AtlasID, Port: 5173})
+	require.NoError(t, err)
+	require.True(t, public.Public)
+
+	updated, err := service.UpdatePort(ctx, &proto.UpdatePortRequest{ProjectId: project.ID, UserId: userID, Port: 5173, Label: "Preview", Protocol: "https"})
+	require.NoError(t, err)
+	require.Equal(t, "Preview", updated.Port.Label)
+	require.Equal(t, "https", updated.Port.Protocol)
+
+	// A restarted server keeps the owner's label and visibility
+	_, err = service.ReportPort(ctx, &proto.ReportPortRequest{AtlasId: project.AtlasID, CallbackToken: "secret", Port: 5173, Open: false})
+	require.NoError(t, err)
+	_, err = service.ReportPort(ctx, &proto.ReportPortRequest{AtlasId: project.AtlasID, CallbackToken: "secret", Port: 5173, Open: true, Label: "Vite"})
+	require.NoError(t, err)
+	ports, err = service.ListPorts(ctx, &proto.ListPortsRequest{ProjectId: project.ID, UserId: userID})
+	require.NoError(t, err)
+	require.Len(t, ports.Ports, 1)
+	require.Equal(t, "Preview", ports.Ports[0].Label)
+	require.True(t, ports.Ports[0].Public)
+
+	atlasMu.Lock()
+	defer atlasMu.Unlock()
+	base := "/sandboxes/" + project.AtlasID + "/ports"
+	require.Equal(t, []string{"POST " + base, "POST " + base, "DELETE " + base + "/5173", "POST " + base}, atlasCalls)
+}